@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -85,6 +86,7 @@ func (a *App) DeleteConfig(name string) error {
 }
 
 // StartSpeedTest 启动测速 (Sync or Async handled by Wails? Wails calls are async from JS, but blocking in Go)
+// mode: "auto" 测速后更新 DNS，"dryrun" 测速后只生成 DNS 变更计划，"manual" 仅测速
 // We should run this in a goroutine and emit events?
 // If we block config.go/task.go logic runs in current goroutine.
 // Better to return and run in background, but the user might want "Wait".
@@ -104,6 +106,9 @@ func (a *App) StartSpeedTest(configName string, mode string) error {
 	// main.go did: `flag.IntVar(&task.Routines...`
 	// So we must manually set task package globals.
 
+	// cdn 包通过 config.GetConfig() 读取配置，需同步为当前选择的配置
+	config.SetConfig(cfg)
+
 	// Init Cancel State
 	utils.ResetCancel()
 
@@ -199,15 +204,20 @@ func (a *App) StartSpeedTest(configName string, mode string) error {
 		runtime.EventsEmit(a.ctx, "result", speedData)
 
 		// Filter and Update DNS if Auto Mode
-		if mode == "auto" {
-			runtime.EventsEmit(a.ctx, "status", "Updating DNS...")
-
-			// Extract IPs
-			var ips []string
-			for _, data := range speedData {
-				ips = append(ips, data.PingData.IP.String())
+		if mode == "auto" || mode == "dryrun" {
+			domains := len(cfg.Cloudflare.Domains)
+			if testType == "IPV6" {
+				domains = len(cfg.Cloudflare.DomainIPv6s)
 			}
+			if domains == 0 && cfg.Cloudflare.LoadBalancer.Enabled() {
+				// 只发布到负载均衡地址池
+				a.publishPool(speedData, mode == "dryrun")
+				return
+			}
+
+			runtime.EventsEmit(a.ctx, "status", "Planning DNS changes...")
 
+			var plan *cdn.Plan
 			if testType == "IPV6" {
 				// Handle IPv6
 				if len(cfg.Cloudflare.DomainIPv6s) == 0 {
@@ -215,18 +225,17 @@ func (a *App) StartSpeedTest(configName string, mode string) error {
 					return
 				}
 
-				ipList, err := cdn.GetIPListForIPv6Domains(ips, cfg.Cloudflare.DomainIPv6s)
+				ipList, err := cdn.GetIPListForIPv6Domains(speedData, cfg.Cloudflare.DomainIPv6s)
 				if err != nil {
 					runtime.EventsEmit(a.ctx, "error", fmt.Sprintf("Get IPv6 List failed: %v", err))
 					return
 				}
 
-				if err := cdn.HandleDNSRecordsIPv6(ipList); err != nil {
-					runtime.EventsEmit(a.ctx, "error", fmt.Sprintf("Update IPv6 DNS failed: %v", err))
+				plan, err = cdn.PlanDNSRecordsIPv6(ipList)
+				if err != nil {
+					a.emitPublishError("Plan IPv6 DNS failed", err)
 					return
 				}
-				runtime.EventsEmit(a.ctx, "status", "IPv6 DNS Updated Successfully!")
-
 			} else {
 				// Handle IPv4
 				if len(cfg.Cloudflare.Domains) == 0 {
@@ -234,24 +243,101 @@ func (a *App) StartSpeedTest(configName string, mode string) error {
 					return
 				}
 
-				ipList, err := cdn.GetIPListForDomains(ips, cfg.Cloudflare.Domains)
+				ipList, err := cdn.GetIPListForDomains(speedData, cfg.Cloudflare.Domains)
 				if err != nil {
 					runtime.EventsEmit(a.ctx, "error", fmt.Sprintf("Get IP List failed: %v", err))
 					return
 				}
 
-				if err := cdn.HandleDNSRecords(ipList); err != nil {
-					runtime.EventsEmit(a.ctx, "error", fmt.Sprintf("Update DNS failed: %v", err))
+				plan, err = cdn.PlanDNSRecords(ipList)
+				if err != nil {
+					a.emitPublishError("Plan DNS failed", err)
 					return
 				}
-				runtime.EventsEmit(a.ctx, "status", "DNS Updated Successfully!")
 			}
+
+			// Emit plan for preview
+			runtime.EventsEmit(a.ctx, "plan", plan)
+			for _, line := range plan.Lines() {
+				runtime.EventsEmit(a.ctx, "log", line)
+			}
+
+			if mode == "dryrun" {
+				runtime.EventsEmit(a.ctx, "status", "Dry run finished, DNS not modified.")
+				a.publishPool(speedData, true)
+				return
+			}
+
+			runtime.EventsEmit(a.ctx, "status", "Updating DNS...")
+			err := cdn.ApplyPlan(plan)
+			a.emitVerification(plan)
+			if err != nil {
+				runtime.EventsEmit(a.ctx, "error", fmt.Sprintf("Update %s DNS failed: %v", testType, err))
+				return
+			}
+			runtime.EventsEmit(a.ctx, "status", fmt.Sprintf("%s DNS Updated Successfully!", testType))
+			a.publishPool(speedData, false)
 		}
 	}()
 
 	return nil
 }
 
+// emitVerification 将金丝雀观察结果和发布后的校验结果逐个域名发送到界面，通过为 status，失败为 error
+func (a *App) emitVerification(plan *cdn.Plan) {
+	if plan.Canary != nil {
+		if plan.Canary.Passed {
+			runtime.EventsEmit(a.ctx, "status", plan.Canary.String())
+		} else {
+			runtime.EventsEmit(a.ctx, "error", plan.Canary.String())
+		}
+	}
+	if plan.Verification == nil {
+		return
+	}
+	for _, domain := range plan.Verification.Domains {
+		if domain.Passed {
+			runtime.EventsEmit(a.ctx, "status", domain.String())
+		} else {
+			runtime.EventsEmit(a.ctx, "error", domain.String())
+		}
+	}
+}
+
+// emitPublishError 将发布失败的原因发送到界面，质量保护跳过发布时单独提示原因
+func (a *App) emitPublishError(failure string, err error) {
+	if errors.Is(err, cdn.ErrPublishSkipped) {
+		runtime.EventsEmit(a.ctx, "error", fmt.Sprintf("Publish skipped, no records modified: %v", err))
+		return
+	}
+	runtime.EventsEmit(a.ctx, "error", fmt.Sprintf("%s: %v", failure, err))
+}
+
+// publishPool 配置了负载均衡地址池时，将测速结果发布为地址池源站
+func (a *App) publishPool(speedData utils.DownloadSpeedSet, dryRun bool) {
+	if !config.GetConfig().Cloudflare.LoadBalancer.Enabled() {
+		return
+	}
+	runtime.EventsEmit(a.ctx, "status", "Planning load balancer pool changes...")
+	plan, err := cdn.PlanPool(speedData)
+	if err != nil {
+		a.emitPublishError("Plan load balancer pool failed", err)
+		return
+	}
+	for _, line := range plan.Changes {
+		runtime.EventsEmit(a.ctx, "log", line)
+	}
+	if dryRun {
+		runtime.EventsEmit(a.ctx, "status", "Dry run finished, load balancer pool not modified.")
+		return
+	}
+	if err := cdn.ApplyPoolPlan(plan); err != nil {
+		runtime.EventsEmit(a.ctx, "error", fmt.Sprintf("Update load balancer pool failed: %v", err))
+		return
+	}
+	runtime.EventsEmit(a.ctx, "status", "Load balancer pool updated successfully!")
+}
+
 // detectIPTypeFromFile helper
 func detectIPTypeFromFile(filename string) (bool, error) {
 	file, err := os.Open(filename)
@@ -276,6 +362,40 @@ func detectIPTypeFromFile(filename string) (bool, error) {
 	return false, nil
 }
 
+// ListSnapshots 列出指定配置的 DNS 变更前快照
+func (a *App) ListSnapshots(configName string) ([]cdn.Snapshot, error) {
+	cfg, err := config.LoadConfig(configName)
+	if err != nil {
+		return nil, fmt.Errorf("load config failed: %w", err)
+	}
+	// 使用独立的发布器，不替换正在运行的任务使用的全局配置
+	publisher, err := cdn.PublisherFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return publisher.ListSnapshots()
+}
+
+// RestoreSnapshot 将 DNS 记录恢复为指定快照
+func (a *App) RestoreSnapshot(configName string, id string) error {
+	cfg, err := config.LoadConfig(configName)
+	if err != nil {
+		return fmt.Errorf("load config failed: %w", err)
+	}
+	publisher, err := cdn.PublisherFor(cfg)
+	if err != nil {
+		return err
+	}
+
+	runtime.EventsEmit(a.ctx, "status", fmt.Sprintf("Restoring snapshot %s...", id))
+	if err := publisher.RestoreSnapshot(id); err != nil {
+		runtime.EventsEmit(a.ctx, "error", fmt.Sprintf("Restore snapshot failed: %v", err))
+		return err
+	}
+	runtime.EventsEmit(a.ctx, "status", fmt.Sprintf("Snapshot %s restored.", id))
+	return nil
+}
+
 // StopSpeedTest 停止测速
 func (a *App) StopSpeedTest() {
 	utils.SetCancel()
@@ -0,0 +1,331 @@
+package cdn
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"AutoCDN/config"
+)
+
+// 阿里云云解析 API 地址和版本
+const (
+	alidnsBaseURL    = "https://alidns.aliyuncs.com"
+	alidnsAPIVersion = "2015-01-09"
+)
+
+// 阿里云分页设置
+const (
+	alidnsDomainsPerPage = 100
+	alidnsRecordsPerPage = 500
+)
+
+// 阿里云免费版允许的最小 TTL，ttl: auto 时使用
+const alidnsDefaultTTL = 600
+
+// 统一线路名与阿里云线路代码的对应关系，未列出的线路按原样提交（如 oversea、edu）
+var alidnsLines = map[string]string{
+	"":   "default",
+	"电信": "telecom",
+	"联通": "unicom",
+	"移动": "mobile",
+}
+
+// AliDNSClient 阿里云云解析 DNS API 客户端
+type AliDNSClient struct {
+	retryPolicy
+	baseURL    string
+	cfg        config.AliDNSConfig
+	httpClient *http.Client
+}
+
+// NewAliDNSClient 创建阿里云云解析 DNS API 客户端
+// baseURL 为空时使用官方 API 地址，httpClient 为空时使用默认的 HTTP 客户端
+func NewAliDNSClient(baseURL string, cfg config.AliDNSConfig, httpClient *http.Client) *AliDNSClient {
+	if baseURL == "" {
+		baseURL = alidnsBaseURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &AliDNSClient{
+		retryPolicy: defaultRetryPolicy(),
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		cfg:         cfg,
+		httpClient:  httpClient,
+	}
+}
+
+// alidnsRecord 阿里云解析记录
+type alidnsRecord struct {
+	RecordID        string `json:"RecordId"`
+	RR              string `json:"RR"`
+	Type            string `json:"Type"`
+	Value           string `json:"Value"`
+	TTL             int    `json:"TTL"`
+	Line            string `json:"Line"`
+	Remark          string `json:"Remark"`
+	UpdateTimestamp int64  `json:"UpdateTimestamp"`
+}
+
+// alidnsError 阿里云错误响应
+type alidnsError struct {
+	Code    string `json:"Code"`
+	Message string `json:"Message"`
+}
+
+// alidnsClassify 根据阿里云错误码确定错误类别
+func alidnsClassify(statusCode int, code string) error {
+	switch {
+	case strings.HasPrefix(code, "InvalidAccessKeyId"), strings.HasPrefix(code, "Forbidden"),
+		code == "SignatureDoesNotMatch", code == "IncompleteSignature":
+		return ErrAuth
+	case strings.HasPrefix(code, "Throttling"):
+		return ErrRateLimited
+	case code == "DomainRecordNotBelongToUser", strings.HasPrefix(code, "InvalidDomainName"),
+		strings.HasPrefix(code, "InvalidRR.NoExist"), code == "DomainNotFound":
+		return ErrNotFound
+	}
+	return classify(statusCode, nil)
+}
+
+// percentEncode 按阿里云签名规则进行 URL 编码
+func percentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
+
+// alidnsSign 计算请求签名（HMAC-SHA1，签名版本 1.0）
+func alidnsSign(method string, params url.Values, secret string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, percentEncode(key)+"="+percentEncode(params.Get(key)))
+	}
+	stringToSign := method + "&" + percentEncode("/") + "&" + percentEncode(strings.Join(pairs, "&"))
+
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// nonce 生成签名随机数
+func nonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// call 调用阿里云 API，查询类请求在网络错误、限流和服务端错误时会重试
+func (c *AliDNSClient) call(action string, params url.Values, idempotent bool, result interface{}) error {
+	return c.retry(idempotent, func() error {
+		return c.send(action, params, result)
+	})
+}
+
+// send 签名并发送一次请求，每次重试都使用新的时间戳和随机数
+func (c *AliDNSClient) send(action string, params url.Values, result interface{}) error {
+	query := url.Values{}
+	for key, values := range params {
+		query[key] = values
+	}
+	query.Set("Action", action)
+	query.Set("Format", "JSON")
+	query.Set("Version", alidnsAPIVersion)
+	query.Set("AccessKeyId", c.cfg.AccessKeyID)
+	query.Set("SignatureMethod", "HMAC-SHA1")
+	query.Set("SignatureVersion", "1.0")
+	query.Set("SignatureNonce", nonce())
+	query.Set("Timestamp", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	query.Set("Signature", alidnsSign(http.MethodGet, query, c.cfg.AccessKeySecret))
+
+	resp, err := c.httpClient.Get(c.baseURL + "/?" + query.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr alidnsError
+		json.Unmarshal(data, &apiErr)
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Errors:     []APIMessage{{Message: fmt.Sprintf("%s: %s", apiErr.Code, apiErr.Message)}},
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			kind:       alidnsClassify(resp.StatusCode, apiErr.Code),
+		}
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("解析阿里云响应失败: %v", err)
+	}
+	return nil
+}
+
+// Name 返回服务商名称
+func (c *AliDNSClient) Name() string {
+	return ProviderAliDNS
+}
+
+// Capabilities 返回阿里云云解析支持的功能
+func (c *AliDNSClient) Capabilities() Capabilities {
+	return Capabilities{MultiValue: true, Lines: true, AutoTTL: alidnsDefaultTTL}
+}
+
+// ListZones 获取账号下的全部域名，Zone ID 即为域名
+func (c *AliDNSClient) ListZones() ([]Zone, error) {
+	var zones []Zone
+	for page := 1; ; page++ {
+		params := url.Values{}
+		params.Set("PageNumber", strconv.Itoa(page))
+		params.Set("PageSize", strconv.Itoa(alidnsDomainsPerPage))
+
+		var result struct {
+			TotalCount int `json:"TotalCount"`
+			Domains    struct {
+				Domain []struct {
+					DomainName string `json:"DomainName"`
+				} `json:"Domain"`
+			} `json:"Domains"`
+		}
+		if err := c.call("DescribeDomains", params, true, &result); err != nil {
+			return nil, fmt.Errorf("获取阿里云域名列表失败: %w", err)
+		}
+		for _, domain := range result.Domains.Domain {
+			zones = append(zones, Zone{ID: domain.DomainName, Name: domain.DomainName})
+		}
+
+		if len(result.Domains.Domain) == 0 || len(zones) >= result.TotalCount {
+			break
+		}
+	}
+	return zones, nil
+}
+
+// alidnsLine 将统一线路名转换为阿里云线路代码
+func alidnsLine(line string) string {
+	if code, ok := alidnsLines[line]; ok {
+		return code
+	}
+	return line
+}
+
+// unifiedLine 将阿里云线路代码转换为统一线路名
+func unifiedLine(code string) string {
+	for line, c := range alidnsLines {
+		if c == code {
+			return line
+		}
+	}
+	return code
+}
+
+// ListRecords 获取域名中符合筛选条件的记录（自动翻页）
+func (c *AliDNSClient) ListRecords(zoneID string, filter RecordFilter) ([]DNSRecord, error) {
+	var records []DNSRecord
+	for page := 1; ; page++ {
+		params := url.Values{}
+		params.Set("DomainName", zoneID)
+		params.Set("PageNumber", strconv.Itoa(page))
+		params.Set("PageSize", strconv.Itoa(alidnsRecordsPerPage))
+		if filter.Name != "" {
+			params.Set("RRKeyWord", subDomain(filter.Name, zoneID))
+		}
+		if filter.Type != "" {
+			params.Set("TypeKeyWord", filter.Type)
+		}
+
+		var result struct {
+			TotalCount    int `json:"TotalCount"`
+			DomainRecords struct {
+				Record []alidnsRecord `json:"Record"`
+			} `json:"DomainRecords"`
+		}
+		if err := c.call("DescribeDomainRecords", params, true, &result); err != nil {
+			return nil, fmt.Errorf("获取DNS记录失败: %w", err)
+		}
+
+		for _, r := range result.DomainRecords.Record {
+			record := DNSRecord{
+				ID:      r.RecordID,
+				Name:    fullName(r.RR, zoneID),
+				Type:    r.Type,
+				Content: r.Value,
+				TTL:     r.TTL,
+				Comment: r.Remark,
+				Line:    unifiedLine(r.Line),
+			}
+			if r.UpdateTimestamp > 0 {
+				record.ModifiedOn = time.UnixMilli(r.UpdateTimestamp)
+			}
+			// 关键字为模糊匹配，这里按完整记录名和类型精确筛选
+			if filter.Name != "" && !strings.EqualFold(record.Name, filter.Name) || filter.Type != "" && record.Type != filter.Type {
+				continue
+			}
+			records = append(records, record)
+		}
+
+		if len(result.DomainRecords.Record) == 0 || page*alidnsRecordsPerPage >= result.TotalCount {
+			break
+		}
+	}
+	return records, nil
+}
+
+// UpsertRecord 添加（ID 为空时）或修改解析记录
+func (c *AliDNSClient) UpsertRecord(zoneID string, record DNSRecord) error {
+	params := url.Values{}
+	params.Set("RR", subDomain(record.Name, zoneID))
+	params.Set("Type", record.Type)
+	params.Set("Value", record.Content)
+	params.Set("TTL", strconv.Itoa(record.TTL))
+	params.Set("Line", alidnsLine(record.Line))
+
+	if record.ID == "" {
+		params.Set("DomainName", zoneID)
+		if err := c.call("AddDomainRecord", params, false, nil); err != nil {
+			return fmt.Errorf("创建DNS记录失败: %w", err)
+		}
+		return nil
+	}
+	params.Set("RecordId", record.ID)
+	if err := c.call("UpdateDomainRecord", params, true, nil); err != nil {
+		return fmt.Errorf("更新DNS记录失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteRecord 删除解析记录
+func (c *AliDNSClient) DeleteRecord(zoneID string, record DNSRecord) error {
+	params := url.Values{}
+	params.Set("RecordId", record.ID)
+	if err := c.call("DeleteDomainRecord", params, true, nil); err != nil {
+		return fmt.Errorf("删除DNS记录失败: %w", err)
+	}
+	return nil
+}
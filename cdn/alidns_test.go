@@ -0,0 +1,211 @@
+package cdn
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"AutoCDN/config"
+)
+
+// 阿里云文档中的签名示例
+func TestAliDNSSign(t *testing.T) {
+	params := url.Values{}
+	params.Set("AccessKeyId", "testid")
+	params.Set("Action", "DescribeRegions")
+	params.Set("Format", "XML")
+	params.Set("SignatureMethod", "HMAC-SHA1")
+	params.Set("SignatureNonce", "3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf")
+	params.Set("SignatureVersion", "1.0")
+	params.Set("Timestamp", "2016-02-23T12:46:24Z")
+	params.Set("Version", "2014-05-26")
+
+	if got := alidnsSign("GET", params, "testsecret"); got != "OLeaidS1JvxuMvnyHOwuJ+uX5qY=" {
+		t.Fatalf("签名错误: %s", got)
+	}
+}
+
+// fakeAliDNS 简单的阿里云云解析 API 模拟服务，会校验请求签名
+type fakeAliDNS struct {
+	mu      sync.Mutex
+	secret  string
+	records []alidnsRecord // 只模拟 example.com 一个域名
+	nextID  int
+}
+
+func (f *fakeAliDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	fail := func(status int, code, message string) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(alidnsError{Code: code, Message: message})
+	}
+	signature := query.Get("Signature")
+	query.Del("Signature")
+	if query.Get("AccessKeyId") != "test-id" {
+		fail(http.StatusNotFound, "InvalidAccessKeyId.NotFound", "Specified access key is not found.")
+		return
+	}
+	if signature != alidnsSign(http.MethodGet, query, f.secret) {
+		fail(http.StatusBadRequest, "SignatureDoesNotMatch", "Specified signature is not matched with our calculation.")
+		return
+	}
+
+	reply := func(body interface{}) {
+		json.NewEncoder(w).Encode(body)
+	}
+	find := func(id string) int {
+		for i, record := range f.records {
+			if record.RecordID == id {
+				return i
+			}
+		}
+		return -1
+	}
+	ttl, _ := strconv.Atoi(query.Get("TTL"))
+
+	switch query.Get("Action") {
+	case "DescribeDomains":
+		reply(map[string]interface{}{
+			"TotalCount": 1,
+			"Domains":    map[string]interface{}{"Domain": []map[string]string{{"DomainName": "example.com"}}},
+		})
+	case "DescribeDomainRecords":
+		var matched []alidnsRecord
+		for _, record := range f.records {
+			if t := query.Get("TypeKeyWord"); t != "" && record.Type != t {
+				continue
+			}
+			matched = append(matched, record)
+		}
+		reply(map[string]interface{}{"TotalCount": len(matched), "DomainRecords": map[string]interface{}{"Record": matched}})
+	case "AddDomainRecord":
+		f.nextID++
+		record := alidnsRecord{
+			RecordID: strconv.Itoa(f.nextID),
+			RR:       query.Get("RR"),
+			Type:     query.Get("Type"),
+			Value:    query.Get("Value"),
+			TTL:      ttl,
+			Line:     query.Get("Line"),
+		}
+		f.records = append(f.records, record)
+		reply(map[string]string{"RecordId": record.RecordID})
+	case "UpdateDomainRecord":
+		i := find(query.Get("RecordId"))
+		if i < 0 {
+			fail(http.StatusBadRequest, "DomainRecordNotBelongToUser", "The DNS record does not exist.")
+			return
+		}
+		f.records[i].RR = query.Get("RR")
+		f.records[i].Value = query.Get("Value")
+		f.records[i].TTL = ttl
+		f.records[i].Line = query.Get("Line")
+		reply(map[string]string{"RecordId": f.records[i].RecordID})
+	case "DeleteDomainRecord":
+		i := find(query.Get("RecordId"))
+		if i < 0 {
+			fail(http.StatusBadRequest, "DomainRecordNotBelongToUser", "The DNS record does not exist.")
+			return
+		}
+		f.records = append(f.records[:i], f.records[i+1:]...)
+		reply(map[string]string{"RecordId": query.Get("RecordId")})
+	default:
+		fail(http.StatusBadRequest, "InvalidAction.NotFound", "Specified api is not found.")
+	}
+}
+
+// values 返回指定主机记录、类型和线路的记录值（已排序）
+func (f *fakeAliDNS) values(rr, recordType, line string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var values []string
+	for _, record := range f.records {
+		if record.RR == rr && record.Type == recordType && record.Line == line {
+			values = append(values, record.Value)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+func newTestAliDNS(t *testing.T) (*AliDNSClient, *fakeAliDNS) {
+	t.Helper()
+	fake := &fakeAliDNS{secret: "test-secret", nextID: 100}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := NewAliDNSClient(server.URL, config.AliDNSConfig{AccessKeyID: "test-id", AccessKeySecret: "test-secret"}, server.Client())
+	client.initialBackoff = time.Millisecond
+	return client, fake
+}
+
+func TestAliDNSPublish(t *testing.T) {
+	client, fake := newTestAliDNS(t)
+	fake.records = []alidnsRecord{
+		{RecordID: "1", RR: "cdn", Type: "A", Value: "9.9.9.9", TTL: 600, Line: "default"},
+		{RecordID: "2", RR: "cdn", Type: "A", Value: "8.8.8.8", TTL: 600, Line: "mobile"},
+		{RecordID: "3", RR: "cdn6", Type: "AAAA", Value: "2606:4700::9", TTL: 600, Line: "default"},
+		{RecordID: "4", RR: "_autocdn.cdn", Type: "TXT", Value: "autocdn-owner type=A", TTL: 600, Line: "default"},
+		{RecordID: "5", RR: "_autocdn.cdn6", Type: "TXT", Value: "autocdn-owner type=AAAA", TTL: 600, Line: "default"},
+	}
+
+	publisher := NewPublisher(client, config.CloudflareConfig{
+		Domains: []config.DomainConfig{
+			{Name: "cdn.example.com"},
+			{Name: "cdn.example.com", Line: "电信", TTL: 600},
+		},
+		DomainIPv6s: []config.DomainConfig{{Name: "cdn6.example.com", TTL: config.TTLAuto}},
+		SnapshotDir: t.TempDir(),
+	})
+	if err := publisher.HandleDNSRecords(speedSet("1.1.1.1", "2.2.2.2")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if err := publisher.HandleDNSRecordsIPv6(speedSet("2606:4700::1")); err != nil {
+		t.Fatalf("发布 IPv6 失败: %v", err)
+	}
+
+	if got := fake.values("cdn", "A", "default"); !reflect.DeepEqual(got, []string{"1.1.1.1"}) {
+		t.Fatalf("默认线路的记录为 %v", got)
+	}
+	if got := fake.values("cdn", "A", "telecom"); !reflect.DeepEqual(got, []string{"2.2.2.2"}) {
+		t.Fatalf("电信线路的记录为 %v", got)
+	}
+	if got := fake.values("cdn", "A", "mobile"); !reflect.DeepEqual(got, []string{"8.8.8.8"}) {
+		t.Fatalf("未配置的移动线路被修改: %v", got)
+	}
+	if got := fake.values("cdn6", "AAAA", "default"); !reflect.DeepEqual(got, []string{"2606:4700::1"}) {
+		t.Fatalf("AAAA 记录为 %v", got)
+	}
+
+	plan, err := publisher.PlanDNSRecords(speedSet("1.1.1.1", "2.2.2.2"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if plan.Changes() != 0 {
+		t.Fatalf("期望无需变更，实际:\n%v", plan.Lines())
+	}
+}
+
+func TestAliDNSErrors(t *testing.T) {
+	client, fake := newTestAliDNS(t)
+
+	if err := client.DeleteRecord("example.com", DNSRecord{ID: "missing"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("期望记录不存在错误，实际 %v", err)
+	}
+
+	fake.secret = "other"
+	if _, err := client.ListZones(); !errors.Is(err, ErrAuth) {
+		t.Fatalf("期望认证错误，实际 %v", err)
+	}
+}
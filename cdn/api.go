@@ -0,0 +1,306 @@
+package cdn
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"AutoCDN/config"
+)
+
+// Cloudflare API 地址
+const apiBaseURL = "https://api.cloudflare.com/client/v4"
+
+// 重试设置
+const (
+	defaultMaxAttempts    = 4                // 最多尝试次数（含首次请求）
+	defaultInitialBackoff = 1 * time.Second  // 首次重试前的等待时间，之后每次翻倍
+	maxBackoff            = 30 * time.Second // 单次等待时间上限
+)
+
+// retryPolicy 请求失败时的重试策略
+type retryPolicy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+}
+
+// defaultRetryPolicy 默认重试策略
+func defaultRetryPolicy() retryPolicy {
+	return retryPolicy{maxAttempts: defaultMaxAttempts, initialBackoff: defaultInitialBackoff}
+}
+
+// Client Cloudflare API 客户端
+type Client struct {
+	retryPolicy
+	baseURL    string
+	cf         config.CloudflareConfig // 认证信息以及域名、快照等发布设置
+	httpClient *http.Client
+}
+
+// NewClient 创建 Cloudflare API 客户端
+// baseURL 为空时使用官方 API 地址，httpClient 为空时使用默认的 HTTP 客户端
+func NewClient(baseURL string, cf config.CloudflareConfig, httpClient *http.Client) *Client {
+	if baseURL == "" {
+		baseURL = apiBaseURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Client{
+		retryPolicy: defaultRetryPolicy(),
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		cf:          cf,
+		httpClient:  httpClient,
+	}
+}
+
+// DefaultClient 使用全局配置创建客户端，包级函数均通过它访问 Cloudflare
+func DefaultClient() *Client {
+	return NewClient(apiBaseURL, config.GetConfig().Cloudflare, nil)
+}
+
+// 错误类别，可通过 errors.Is 判断
+var (
+	ErrAuth        = errors.New("认证失败")
+	ErrNotFound    = errors.New("资源不存在")
+	ErrRateLimited = errors.New("请求过于频繁")
+	ErrValidation  = errors.New("请求参数无效")
+	ErrServer      = errors.New("服务端错误")
+)
+
+// APIMessage Cloudflare 响应中的 errors / messages 条目
+type APIMessage struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// APIError DNS 服务商 API 返回的错误
+type APIError struct {
+	StatusCode int
+	Status     string
+	Errors     []APIMessage
+	Messages   []APIMessage
+	RetryAfter time.Duration // 限流时服务端要求的等待时间
+
+	kind error
+}
+
+// Error 返回包含错误码和信息的描述
+func (e *APIError) Error() string {
+	var details []string
+	for _, m := range e.Errors {
+		if m.Code == 0 {
+			details = append(details, m.Message)
+			continue
+		}
+		details = append(details, fmt.Sprintf("[%d] %s", m.Code, m.Message))
+	}
+	if len(details) == 0 {
+		return fmt.Sprintf("%v (%s)", e.kind, e.Status)
+	}
+	return fmt.Sprintf("%v (%s): %s", e.kind, e.Status, strings.Join(details, "; "))
+}
+
+// Unwrap 返回错误类别
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+// temporary 是否为可重试的临时错误
+func (e *APIError) temporary() bool {
+	return e.kind == ErrRateLimited || e.kind == ErrServer
+}
+
+// apiResponse Cloudflare API 响应信封
+type apiResponse struct {
+	Success    bool            `json:"success"`
+	Errors     []APIMessage    `json:"errors"`
+	Messages   []APIMessage    `json:"messages"`
+	Result     json.RawMessage `json:"result"`
+	ResultInfo struct {
+		Page       int `json:"page"`
+		TotalPages int `json:"total_pages"`
+	} `json:"result_info"`
+}
+
+// classify 根据状态码和错误码确定错误类别
+func classify(statusCode int, errs []APIMessage) error {
+	for _, m := range errs {
+		switch m.Code {
+		case 9103, 9106, 9109, 10000, 10001:
+			// 认证信息缺失、无效或权限不足
+			return ErrAuth
+		case 81044, 7003:
+			return ErrNotFound
+		case 971, 10429:
+			return ErrRateLimited
+		}
+	}
+
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrAuth
+	case statusCode == http.StatusNotFound:
+		return ErrNotFound
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode >= 500:
+		return ErrServer
+	}
+	return ErrValidation
+}
+
+// parseRetryAfter 解析 Retry-After 响应头（秒数或 HTTP 日期）
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// backoff 返回第 attempt 次重试前的等待时间，优先使用服务端要求的时间
+func backoff(initial time.Duration, attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		if retryAfter > maxBackoff {
+			return maxBackoff
+		}
+		return retryAfter
+	}
+	wait := initial << (attempt - 1)
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
+
+// setAuthHeaders 根据配置为请求设置 Cloudflare 认证头
+// 配置了 APIToken 或未填写 Email 时使用 API 令牌 (Bearer) 方式，否则使用全局 API 密钥方式
+func setAuthHeaders(req *http.Request, cf config.CloudflareConfig) {
+	switch cf.AuthMode() {
+	case config.AuthModeToken:
+		req.Header.Set("Authorization", "Bearer "+cf.Token())
+	default:
+		req.Header.Set("X-Auth-Key", cf.APIKey)
+		req.Header.Set("X-Auth-Email", cf.Email)
+	}
+}
+
+// doRequest 发送 Cloudflare API 请求并解析响应信封
+// 网络错误、限流和服务端错误会按指数退避重试（遵循 Retry-After）；Cloudflare 返回的错误以 *APIError 返回
+func (c *Client) doRequest(method, path string, body interface{}) (*apiResponse, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	var resp *apiResponse
+	err := c.retry(method != http.MethodPost, func() error {
+		var err error
+		resp, err = c.sendRequest(method, c.baseURL+path, payload)
+		return err
+	})
+	return resp, err
+}
+
+// retry 执行请求，失败时按指数退避重试
+// 非幂等请求（如创建记录）只有被限流（服务端明确未处理）时才重试，避免重复创建记录
+func (r retryPolicy) retry(idempotent bool, send func() error) error {
+	var lastErr error
+	for attempt := 1; attempt <= r.maxAttempts; attempt++ {
+		if attempt > 1 {
+			var retryAfter time.Duration
+			var apiErr *APIError
+			if errors.As(lastErr, &apiErr) {
+				retryAfter = apiErr.RetryAfter
+			}
+			wait := backoff(r.initialBackoff, attempt-1, retryAfter)
+			log.Printf("API 请求失败，%v 后重试 (%d/%d): %v", wait, attempt, r.maxAttempts, lastErr)
+			time.Sleep(wait)
+		}
+
+		lastErr = send()
+		if lastErr == nil {
+			return nil
+		}
+		if !retryable(idempotent, lastErr) {
+			return lastErr
+		}
+	}
+	return lastErr
+}
+
+// retryable 判断失败的请求是否可以重试
+func retryable(idempotent bool, err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return idempotent
+	}
+	if !idempotent {
+		return apiErr.kind == ErrRateLimited
+	}
+	return apiErr.temporary()
+}
+
+// sendRequest 发送一次请求
+func (c *Client) sendRequest(method, reqURL string, payload []byte) (*apiResponse, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, reqURL, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	setAuthHeaders(req, c.cf)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result apiResponse
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	decodeErr := json.Unmarshal(data, &result)
+
+	if resp.StatusCode >= 300 || decodeErr == nil && !result.Success {
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Errors:     result.Errors,
+			Messages:   result.Messages,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			kind:       classify(resp.StatusCode, result.Errors),
+		}
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("解析 Cloudflare 响应失败: %v", decodeErr)
+	}
+	for _, m := range result.Messages {
+		log.Printf("Cloudflare 提示: [%d] %s", m.Code, m.Message)
+	}
+	return &result, nil
+}
@@ -0,0 +1,160 @@
+package cdn
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"AutoCDN/utils"
+)
+
+// maxCanaryFailures 观察结果中保留的失败描述数量
+const maxCanaryFailures = 5
+
+// CanaryReport 金丝雀观察的结果
+type CanaryReport struct {
+	Domain     string   `json:"domain"`
+	IPs        []string `json:"ips"` // 探测的 IP
+	Probes     int      `json:"probes"`
+	Successes  int      `json:"successes"`
+	AvgLatency int64    `json:"avgLatencyMs"` // 成功请求的平均耗时
+	Passed     bool     `json:"passed"`
+	Failures   []string `json:"failures,omitempty"` // 最近几次失败的描述
+}
+
+// String 返回观察结果的可读描述
+func (r *CanaryReport) String() string {
+	result := "通过"
+	if !r.Passed {
+		result = "未通过"
+	}
+	line := fmt.Sprintf("金丝雀 %s 观察%s: 成功 %d/%d，平均耗时 %dms", r.Domain, result, r.Successes, r.Probes, r.AvgLatency)
+	if len(r.Failures) > 0 {
+		line += " (" + strings.Join(r.Failures, "; ") + ")"
+	}
+	return line
+}
+
+// applyCanary 分阶段执行计划：先执行金丝雀域名的变更并观察，健康时再执行其余域名和 HTTPS 记录的变更
+// 观察未通过时其余域名保持不变，按配置恢复金丝雀域名
+func (p *Publisher) applyCanary(plan *Plan) error {
+	name := p.cf.Canary.Domain
+	canary := &Plan{Type: plan.Type, current: plan.current, metrics: plan.metrics, claims: plan.claims, kept: plan.kept, staged: true}
+	rest := &Plan{Type: plan.Type, HTTPS: plan.HTTPS, current: plan.current, metrics: plan.metrics, claims: plan.claims, kept: plan.kept, staged: true}
+	var ips []string
+	for _, action := range plan.Actions {
+		if action.Name == name {
+			canary.Actions = append(canary.Actions, action)
+		} else {
+			rest.Actions = append(rest.Actions, action)
+		}
+		if action.Action != ActionDelete && action.Action != ActionNoop && !contains(ips, action.NewContent) {
+			ips = append(ips, action.NewContent)
+		}
+	}
+
+	// 金丝雀域名不在本次发布中，或其余域名没有地址记录变更时无需分阶段
+	if len(canary.Actions) == 0 || rest.actionChanges() == 0 {
+		if len(canary.Actions) == 0 {
+			log.Printf("金丝雀域名 %s 不在本次发布的 %s 记录中，直接发布全部域名", name, plan.Type)
+		}
+		plan.staged = true
+		return p.ApplyPlan(plan)
+	}
+
+	// 金丝雀域名已指向的 IP 同样需要探测
+	for _, action := range canary.Actions {
+		if action.Action != ActionDelete && !contains(ips, action.NewContent) {
+			ips = append(ips, action.NewContent)
+		}
+	}
+
+	log.Printf("金丝雀发布: 先更新 %s，观察 %d 秒", name, p.cf.Canary.SoakSeconds())
+	err := p.ApplyPlan(canary)
+	plan.Verification = canary.Verification
+	if err != nil {
+		return fmt.Errorf("金丝雀域名 %s 发布失败，其余域名未修改: %w", name, err)
+	}
+
+	plan.Canary = p.soakCanary(name, ips)
+	log.Print(plan.Canary.String())
+	if !plan.Canary.Passed {
+		if !p.cf.Canary.Revert || canary.SnapshotID == "" {
+			return fmt.Errorf("金丝雀 %s 观察未通过，其余域名未修改", name)
+		}
+		if err := p.RestoreSnapshot(canary.SnapshotID); err != nil {
+			return fmt.Errorf("金丝雀 %s 观察未通过，其余域名未修改，恢复金丝雀域名失败: %v", name, err)
+		}
+		return fmt.Errorf("金丝雀 %s 观察未通过，其余域名未修改，已恢复快照 %s", name, canary.SnapshotID)
+	}
+
+	err = p.ApplyPlan(rest)
+	if rest.Verification != nil {
+		if plan.Verification == nil {
+			plan.Verification = &VerifyReport{}
+		}
+		plan.Verification.Domains = append(plan.Verification.Domains, rest.Verification.Domains...)
+	}
+	return err
+}
+
+// soakCanary 在观察时长内按间隔以金丝雀域名探测各 IP，失败次数超过允许值时提前结束
+func (p *Publisher) soakCanary(name string, ips []string) *CanaryReport {
+	cfg := p.cf.Canary
+	rounds := cfg.SoakSeconds() / cfg.IntervalSeconds()
+	if rounds < 1 {
+		rounds = 1
+	}
+	allowed := rounds * len(ips) * (100 - cfg.MinSuccessRate()) / 100
+	expected := p.cf.Verify.ExpectedStatus()
+
+	report := &CanaryReport{Domain: name, IPs: ips, Passed: true}
+	var total time.Duration
+	fail := func(detail string) {
+		report.Failures = append(report.Failures, detail)
+		if len(report.Failures) > maxCanaryFailures {
+			report.Failures = report.Failures[1:]
+		}
+	}
+
+	for round := 0; round < rounds; round++ {
+		if round > 0 {
+			p.sleep(time.Duration(cfg.IntervalSeconds()) * time.Second)
+		}
+		if utils.CheckCanceled() {
+			report.Passed = false
+			fail("观察已取消")
+			return report
+		}
+
+		for _, ip := range ips {
+			report.Probes++
+			start := time.Now()
+			status, err := p.checkHTTP(name, ip)
+			elapsed := time.Since(start)
+			switch {
+			case err != nil:
+				fail(fmt.Sprintf("%s 请求失败: %v", ip, err))
+			case status != expected:
+				fail(fmt.Sprintf("%s HTTP %d", ip, status))
+			case cfg.MaxLatency > 0 && elapsed > time.Duration(cfg.MaxLatency)*time.Millisecond:
+				fail(fmt.Sprintf("%s 耗时 %dms", ip, elapsed.Milliseconds()))
+			default:
+				report.Successes++
+				total += elapsed
+			}
+		}
+		log.Printf("金丝雀 %s 第 %d/%d 轮探测: 成功 %d/%d", name, round+1, rounds, report.Successes, report.Probes)
+
+		if report.Probes-report.Successes > allowed {
+			report.Passed = false
+			break
+		}
+	}
+
+	if report.Successes > 0 {
+		report.AvgLatency = (total / time.Duration(report.Successes)).Milliseconds()
+	}
+	return report
+}
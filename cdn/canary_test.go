@@ -0,0 +1,70 @@
+package cdn
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"AutoCDN/config"
+)
+
+func TestApplyCanary(t *testing.T) {
+	pool, port := newVerifyServer(t)
+	publisher, server := newTestPublisher(t, config.CloudflareConfig{
+		Domains:       []config.DomainConfig{{Name: "example.com"}, {Name: "a.example.com"}},
+		RecordSetSize: 2,
+		Verify:        config.VerifyConfig{Path: "/health", Port: port},
+		Canary:        config.CanaryConfig{Domain: "example.com", Soak: 60, Interval: 30, Revert: true},
+	})
+	publisher.verifyRootCAs = pool
+	var slept []time.Duration
+	publisher.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	plan, err := publisher.PlanDNSRecords(speedSet("127.0.0.1"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if err := publisher.ApplyPlan(plan); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if plan.Canary == nil || !plan.Canary.Passed || plan.Canary.Probes != 2 || plan.Canary.Successes != 2 {
+		t.Fatalf("观察结果为 %+v", plan.Canary)
+	}
+	if !reflect.DeepEqual(slept, []time.Duration{30 * time.Second}) {
+		t.Fatalf("轮间等待为 %v", slept)
+	}
+	if got := server.Contents("zone1", "a.example.com", "A"); !reflect.DeepEqual(got, []string{"127.0.0.1"}) {
+		t.Fatalf("其余域名的记录为 %v", got)
+	}
+
+	// 新 IP 上没有服务：金丝雀恢复为原 IP，其余域名不变
+	plan, err = publisher.PlanDNSRecords(speedSet("127.0.0.2"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	err = publisher.ApplyPlan(plan)
+	if err == nil || !strings.Contains(err.Error(), "其余域名未修改，已恢复快照") {
+		t.Fatalf("期望观察未通过并恢复，实际 %v", err)
+	}
+	if plan.Canary.Passed || plan.Canary.Probes != 1 || !strings.Contains(plan.Canary.String(), "127.0.0.2 请求失败") {
+		t.Fatalf("观察结果为 %s", plan.Canary)
+	}
+	for _, name := range []string{"example.com", "a.example.com"} {
+		if got := server.Contents("zone1", name, "A"); !reflect.DeepEqual(got, []string{"127.0.0.1"}) {
+			t.Fatalf("%s 的记录为 %v", name, got)
+		}
+	}
+
+	// 其余域名将使用的 IP 同样经过探测
+	plan, err = publisher.PlanDNSRecords(speedSet("127.0.0.1", "127.0.0.2"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if err := publisher.ApplyPlan(plan); err == nil || len(plan.Canary.IPs) != 2 {
+		t.Fatalf("期望观察未通过，实际 %v (%+v)", err, plan.Canary)
+	}
+	if got := server.Contents("zone1", "a.example.com", "A"); !reflect.DeepEqual(got, []string{"127.0.0.1"}) {
+		t.Fatalf("其余域名的记录为 %v", got)
+	}
+}
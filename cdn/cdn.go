@@ -2,346 +2,298 @@ package cdn
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"strings"
+	"net/url"
+	"strconv"
+	"time"
 
 	"AutoCDN/config"
+	"AutoCDN/utils"
 )
 
-// GetRecordList 获取指定Zone的DNS记录列表
-func GetRecordList(zoneID string) (map[string]string, error) {
-	cfg := config.GetConfig()
-	log.Printf("DEBUG: GetRecordList called with zoneID: '%s' (Config ZoneID: '%s')", zoneID, cfg.Cloudflare.ZoneID)
-
-	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records", zoneID)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("X-Auth-Key", cfg.Cloudflare.APIKey)
-	req.Header.Set("X-Auth-Email", cfg.Cloudflare.Email)
-	req.Header.Set("Content-Type", "application/json")
+// 每页获取的记录数量（Cloudflare 允许的最大值为 5000，这里取常用的 100）
+const recordsPerPage = 100
+
+// DNSRecord Cloudflare DNS 记录
+type DNSRecord struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	Content    string    `json:"content"`
+	TTL        int       `json:"ttl"`
+	Proxied    bool      `json:"proxied"`
+	Comment    string    `json:"comment"`
+	Tags       []string  `json:"tags"`
+	ModifiedOn time.Time `json:"modified_on"`
+	Line       string    `json:"line,omitempty"` // 解析线路（DNSPod / 阿里云），默认线路为空
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+// RecordFilter 记录列表的服务端筛选条件，空字段表示不筛选
+type RecordFilter struct {
+	Name string // 完整记录名，如 cdn.example.com
+	Type string // 记录类型，如 A / AAAA
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("获取DNS记录失败: %s", resp.Status)
-	}
+// ListDNSRecords 获取指定Zone中符合筛选条件的全部DNS记录（自动翻页）
+func (c *Client) ListDNSRecords(zoneID string, filter RecordFilter) ([]DNSRecord, error) {
+	var records []DNSRecord
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(recordsPerPage))
+		if filter.Name != "" {
+			query.Set("name", filter.Name)
+		}
+		if filter.Type != "" {
+			query.Set("type", filter.Type)
+		}
 
-	var result struct {
-		Result []struct {
-			Name string `json:"name"`
-			ID   string `json:"id"`
-			Type string `json:"type"`
-		} `json:"result"`
-	}
+		resp, err := c.doRequest("GET", fmt.Sprintf("/zones/%s/dns_records?%s", zoneID, query.Encode()), nil)
+		if err != nil {
+			return nil, fmt.Errorf("获取DNS记录失败: %w", err)
+		}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
+		var result []DNSRecord
+		if err := json.Unmarshal(resp.Result, &result); err != nil {
+			return nil, err
+		}
+		records = append(records, result...)
 
-	records := make(map[string]string)
-	for _, record := range result.Result {
-		// 只处理A记录和AAAA记录
-		if record.Type == "A" || record.Type == "AAAA" {
-			records[record.Name] = record.ID
+		// 没有分页信息或已到最后一页时结束
+		if len(result) == 0 || page >= resp.ResultInfo.TotalPages {
+			break
 		}
 	}
 
 	return records, nil
 }
 
-// GetRecordListWithType 获取指定Zone的DNS记录列表（包含类型信息）
-func GetRecordListWithType(zoneID string) (map[string]map[string]string, error) {
-	cfg := config.GetConfig()
-
-	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records", zoneID)
-	req, err := http.NewRequest("GET", url, nil)
+// GetRecordList 获取指定Zone的全部A记录和AAAA记录
+func (c *Client) GetRecordList(zoneID string) ([]DNSRecord, error) {
+	records, err := c.ListDNSRecords(zoneID, RecordFilter{})
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("X-Auth-Key", cfg.Cloudflare.APIKey)
-	req.Header.Set("X-Auth-Email", cfg.Cloudflare.Email)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("获取DNS记录失败: %s", resp.Status)
-	}
-
-	var result struct {
-		Result []struct {
-			Name string `json:"name"`
-			ID   string `json:"id"`
-			Type string `json:"type"`
-		} `json:"result"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	records := make(map[string]map[string]string)
-	for _, record := range result.Result {
+	var filtered []DNSRecord
+	for _, record := range records {
 		// 只处理A记录和AAAA记录
 		if record.Type == "A" || record.Type == "AAAA" {
-			if records[record.Name] == nil {
-				records[record.Name] = make(map[string]string)
-			}
-			records[record.Name][record.Type] = record.ID
+			filtered = append(filtered, record)
 		}
 	}
+	return filtered, nil
+}
 
-	return records, nil
+// GetRecordListWithType 获取指定Zone中指定类型的DNS记录（服务端筛选）
+func (c *Client) GetRecordListWithType(zoneID, recordType string) ([]DNSRecord, error) {
+	return c.ListDNSRecords(zoneID, RecordFilter{Type: recordType})
 }
 
-// UpdateDNSRecords 更新指定域名的DNS解析记录
-func UpdateDNSRecords(newIP, domain, zoneID, recordID string) error {
-	cfg := config.GetConfig()
-
-	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records/%s", zoneID, recordID)
-	reqBody := fmt.Sprintf(`{
-		"type": "A",
-		"name": "%s",
-		"content": "%s",
-		"ttl": 3600,
-		"proxied": false
-	}`, domain, newIP)
-
-	req, err := http.NewRequest("PATCH", url, strings.NewReader(reqBody))
-	if err != nil {
-		return err
-	}
+// recordBody 创建或更新记录时提交的请求体
+type recordBody struct {
+	Type    string    `json:"type"`
+	Name    string    `json:"name"`
+	Content string    `json:"content,omitempty"`
+	Data    *svcbData `json:"data,omitempty"` // HTTPS / SVCB 记录以 data 提交，不使用 content
+	TTL     int       `json:"ttl"`
+	Proxied bool      `json:"proxied"`
+	Comment string    `json:"comment,omitempty"`
+	Tags    []string  `json:"tags,omitempty"`
+}
 
-	req.Header.Set("X-Auth-Key", cfg.Cloudflare.APIKey)
-	req.Header.Set("X-Auth-Email", cfg.Cloudflare.Email)
-	req.Header.Set("Content-Type", "application/json")
+// bodyFromRecord 根据记录生成请求体
+func bodyFromRecord(record DNSRecord) recordBody {
+	body := recordBody{
+		Type:    record.Type,
+		Name:    record.Name,
+		Content: record.Content,
+		TTL:     record.TTL,
+		Proxied: record.Proxied,
+		Comment: record.Comment,
+		Tags:    record.Tags,
+	}
+	if isSVCBType(record.Type) {
+		// 无法解析时仍以 content 提交，由 API 返回错误
+		if svcb, err := parseSVCB(record.Content); err == nil {
+			body.Content = ""
+			body.Data = svcb.data()
+		}
+	}
+	return body
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+// newRecordBody 根据域名配置生成请求体
+func newRecordBody(recordType, content string, domain config.DomainConfig) recordBody {
+	return recordBody{
+		Type:    recordType,
+		Name:    domain.Name,
+		Content: content,
+		TTL:     domain.RecordTTL(),
+		Proxied: domain.IsProxied(),
+		Comment: domain.Comment,
+		Tags:    domain.Tags,
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("更新DNS记录失败: %s", resp.Status)
+// UpdateDNSRecords 更新指定域名的DNS解析记录
+func (c *Client) UpdateDNSRecords(newIP string, domain config.DomainConfig, zoneID, recordID string) error {
+	path := fmt.Sprintf("/zones/%s/dns_records/%s", zoneID, recordID)
+	if _, err := c.doRequest("PATCH", path, newRecordBody("A", newIP, domain)); err != nil {
+		return fmt.Errorf("更新DNS记录失败: %w", err)
 	}
 	return nil
 }
 
 // UpdateDNSRecordsIPv6 更新指定域名的IPv6 DNS解析记录
-func UpdateDNSRecordsIPv6(newIP, domain, zoneID, recordID string) error {
-	cfg := config.GetConfig()
-
-	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records/%s", zoneID, recordID)
-	reqBody := fmt.Sprintf(`{
-		"type": "AAAA",
-		"name": "%s",
-		"content": "%s",
-		"ttl": 3600,
-		"proxied": false
-	}`, domain, newIP)
-
-	req, err := http.NewRequest("PATCH", url, strings.NewReader(reqBody))
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("X-Auth-Key", cfg.Cloudflare.APIKey)
-	req.Header.Set("X-Auth-Email", cfg.Cloudflare.Email)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("更新IPv6 DNS记录失败: %s", resp.Status)
+func (c *Client) UpdateDNSRecordsIPv6(newIP string, domain config.DomainConfig, zoneID, recordID string) error {
+	path := fmt.Sprintf("/zones/%s/dns_records/%s", zoneID, recordID)
+	if _, err := c.doRequest("PATCH", path, newRecordBody("AAAA", newIP, domain)); err != nil {
+		return fmt.Errorf("更新IPv6 DNS记录失败: %w", err)
 	}
 	return nil
 }
 
 // CreateDNSRecord 创建新的DNS记录
-func CreateDNSRecord(newIP, domain, zoneID string) error {
-	cfg := config.GetConfig()
-
-	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records", zoneID)
-	reqBody := fmt.Sprintf(`{
-		"type": "A",
-		"name": "%s",
-		"content": "%s",
-		"ttl": 3600,
-		"proxied": false
-	}`, domain, newIP)
-
-	req, err := http.NewRequest("POST", url, strings.NewReader(reqBody))
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("X-Auth-Key", cfg.Cloudflare.APIKey)
-	req.Header.Set("X-Auth-Email", cfg.Cloudflare.Email)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("创建DNS记录失败: %s", resp.Status)
+func (c *Client) CreateDNSRecord(newIP string, domain config.DomainConfig, zoneID string) error {
+	path := fmt.Sprintf("/zones/%s/dns_records", zoneID)
+	if _, err := c.doRequest("POST", path, newRecordBody("A", newIP, domain)); err != nil {
+		return fmt.Errorf("创建DNS记录失败: %w", err)
 	}
 	return nil
 }
 
 // CreateDNSRecordIPv6 创建新的IPv6 DNS记录
-func CreateDNSRecordIPv6(newIP, domain, zoneID string) error {
-	cfg := config.GetConfig()
-
-	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records", zoneID)
-	reqBody := fmt.Sprintf(`{
-		"type": "AAAA",
-		"name": "%s",
-		"content": "%s",
-		"ttl": 3600,
-		"proxied": false
-	}`, domain, newIP)
-
-	req, err := http.NewRequest("POST", url, strings.NewReader(reqBody))
-	if err != nil {
-		return err
+func (c *Client) CreateDNSRecordIPv6(newIP string, domain config.DomainConfig, zoneID string) error {
+	path := fmt.Sprintf("/zones/%s/dns_records", zoneID)
+	if _, err := c.doRequest("POST", path, newRecordBody("AAAA", newIP, domain)); err != nil {
+		return fmt.Errorf("创建IPv6 DNS记录失败: %w", err)
 	}
+	return nil
+}
 
-	req.Header.Set("X-Auth-Key", cfg.Cloudflare.APIKey)
-	req.Header.Set("X-Auth-Email", cfg.Cloudflare.Email)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("创建IPv6 DNS记录失败: %s", resp.Status)
+// DeleteDNSRecord 删除指定的DNS记录
+func (c *Client) DeleteDNSRecord(zoneID, recordID string) error {
+	path := fmt.Sprintf("/zones/%s/dns_records/%s", zoneID, recordID)
+	if _, err := c.doRequest("DELETE", path, nil); err != nil {
+		return fmt.Errorf("删除DNS记录失败: %w", err)
 	}
 	return nil
 }
 
-// HandleDNSRecords 处理DNS记录的更新或创建
-func HandleDNSRecords(ipList []string) error {
-	cfg := config.GetConfig()
+// Name 返回服务商名称
+func (c *Client) Name() string {
+	return ProviderCloudflare
+}
 
-	recordList, err := GetRecordList(cfg.Cloudflare.ZoneID)
-	if err != nil {
-		return fmt.Errorf("获取记录列表失败: %v", err)
-	}
+// Capabilities 返回 Cloudflare 支持的功能
+func (c *Client) Capabilities() Capabilities {
+	return Capabilities{MultiValue: true, Proxied: true, Comments: true, Tags: true, HTTPS: true}
+}
 
-	if len(ipList) == 0 {
-		return fmt.Errorf("没有可用的 IPv4 IP")
+// ListRecords 获取Zone中符合筛选条件的记录
+func (c *Client) ListRecords(zoneID string, filter RecordFilter) ([]DNSRecord, error) {
+	return c.ListDNSRecords(zoneID, filter)
+}
+
+// UpsertRecord 创建（ID 为空时）或更新记录
+func (c *Client) UpsertRecord(zoneID string, record DNSRecord) error {
+	body := bodyFromRecord(record)
+	if record.ID == "" {
+		if _, err := c.doRequest("POST", fmt.Sprintf("/zones/%s/dns_records", zoneID), body); err != nil {
+			return fmt.Errorf("创建DNS记录失败: %w", err)
+		}
+		return nil
+	}
+	if _, err := c.doRequest("PATCH", fmt.Sprintf("/zones/%s/dns_records/%s", zoneID, record.ID), body); err != nil {
+		return fmt.Errorf("更新DNS记录失败: %w", err)
 	}
+	return nil
+}
 
-	// 如果 IP 数量少于域名数量，循环复用 IP
-	log.Printf("更新 %d 个域名 (可用 IP: %d)", len(cfg.Cloudflare.Domains), len(ipList))
+// DeleteRecord 删除记录
+func (c *Client) DeleteRecord(zoneID string, record DNSRecord) error {
+	return c.DeleteDNSRecord(zoneID, record.ID)
+}
 
-	for i, domain := range cfg.Cloudflare.Domains {
-		// 使用取模运算循环分配 IP
-		newIP := ipList[i%len(ipList)]
+// batchRecord 批量接口中的修改项，在记录内容之外带有记录 ID
+type batchRecord struct {
+	ID string `json:"id"`
+	recordBody
+}
 
-		if recordID, exists := recordList[domain]; exists {
-			// 更新记录
-			if err := UpdateDNSRecords(newIP, domain, cfg.Cloudflare.ZoneID, recordID); err != nil {
-				log.Printf("更新记录失败 %s: %v", domain, err)
-				continue
-			}
-			log.Printf("成功更新记录: %s -> %s", domain, newIP)
-		} else {
-			// 创建记录
-			if err := CreateDNSRecord(newIP, domain, cfg.Cloudflare.ZoneID); err != nil {
-				log.Printf("创建记录失败 %s: %v", domain, err)
-				continue
+// ApplyBatch 通过 dns_records/batch 接口在一次请求中执行删除、修改和创建，Cloudflare 保证全部成功或全部不生效
+// 接口不存在或不允许时（404 / 405 / 501）返回 ErrBatchUnsupported
+func (c *Client) ApplyBatch(zoneID string, batch RecordBatch) error {
+	body := struct {
+		Deletes []map[string]string `json:"deletes,omitempty"`
+		Patches []batchRecord       `json:"patches,omitempty"`
+		Posts   []recordBody        `json:"posts,omitempty"`
+	}{}
+	for _, record := range batch.Deletes {
+		body.Deletes = append(body.Deletes, map[string]string{"id": record.ID})
+	}
+	for _, record := range batch.Updates {
+		body.Patches = append(body.Patches, batchRecord{ID: record.ID, recordBody: bodyFromRecord(record)})
+	}
+	for _, record := range batch.Creates {
+		body.Posts = append(body.Posts, bodyFromRecord(record))
+	}
+
+	if _, err := c.doRequest("POST", fmt.Sprintf("/zones/%s/dns_records/batch", zoneID), body); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			switch apiErr.StatusCode {
+			case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+				return fmt.Errorf("%w: %v", ErrBatchUnsupported, err)
 			}
-			log.Printf("成功创建记录: %s -> %s", domain, newIP)
 		}
+		return fmt.Errorf("批量提交DNS记录失败: %w", err)
 	}
 	return nil
 }
 
-// HandleDNSRecordsIPv6 处理IPv6 DNS记录的更新或创建
-func HandleDNSRecordsIPv6(ipList []string) error {
-	cfg := config.GetConfig()
+// HandleDNSRecords 处理DNS记录的更新或创建
+func (p *Publisher) HandleDNSRecords(ipList utils.DownloadSpeedSet) error {
+	// 如果 IP 数量少于域名数量，循环复用 IP
+	log.Printf("更新 %d 个域名 (可用 IP: %d)", len(p.cf.Domains), len(ipList))
 
-	recordList, err := GetRecordList(cfg.Cloudflare.ZoneID)
+	plan, err := p.PlanDNSRecords(ipList)
 	if err != nil {
-		return fmt.Errorf("获取记录列表失败: %v", err)
-	}
-
-	if len(ipList) == 0 {
-		return fmt.Errorf("没有可用的 IPv6 IP")
+		return err
 	}
+	plan.Print()
+	return p.ApplyPlan(plan)
+}
 
+// HandleDNSRecordsIPv6 处理IPv6 DNS记录的更新或创建
+func (p *Publisher) HandleDNSRecordsIPv6(ipList utils.DownloadSpeedSet) error {
 	// 如果 IP 数量少于域名数量，循环复用 IP
-	log.Printf("更新 %d 个 IPv6 域名 (可用 IP: %d)", len(cfg.Cloudflare.DomainIPv6s), len(ipList))
-
-	for i, domain := range cfg.Cloudflare.DomainIPv6s {
-		// 使用取模运算循环分配 IP
-		newIP := ipList[i%len(ipList)]
+	log.Printf("更新 %d 个 IPv6 域名 (可用 IP: %d)", len(p.cf.DomainIPv6s), len(ipList))
 
-		if recordID, exists := recordList[domain]; exists {
-			// 更新记录
-			if err := UpdateDNSRecordsIPv6(newIP, domain, cfg.Cloudflare.ZoneID, recordID); err != nil {
-				log.Printf("更新IPv6记录失败 %s: %v", domain, err)
-				continue
-			}
-			log.Printf("成功更新IPv6记录: %s -> %s", domain, newIP)
-		} else {
-			// 创建记录
-			if err := CreateDNSRecordIPv6(newIP, domain, cfg.Cloudflare.ZoneID); err != nil {
-				log.Printf("创建IPv6记录失败 %s: %v", domain, err)
-				continue
-			}
-			log.Printf("成功创建IPv6记录: %s -> %s", domain, newIP)
-		}
+	plan, err := p.PlanDNSRecordsIPv6(ipList)
+	if err != nil {
+		return err
 	}
-	return nil
+	plan.Print()
+	return p.ApplyPlan(plan)
 }
 
 // HandleAllDNSRecords 同时处理IPv4和IPv6 DNS记录的更新或创建
-func HandleAllDNSRecords(ipv4List []string, ipv6List []string) error {
-	cfg := config.GetConfig()
-
+func (p *Publisher) HandleAllDNSRecords(ipv4List utils.DownloadSpeedSet, ipv6List utils.DownloadSpeedSet) error {
 	// 处理IPv4域名
-	if len(cfg.Cloudflare.Domains) > 0 {
-		if err := HandleDNSRecords(ipv4List); err != nil {
+	if len(p.cf.Domains) > 0 {
+		if err := p.HandleDNSRecords(ipv4List); err != nil {
 			log.Printf("处理IPv4 DNS记录失败: %v", err)
 		}
 	}
 
 	// 处理IPv6域名
-	if len(cfg.Cloudflare.DomainIPv6s) > 0 {
-		if err := HandleDNSRecordsIPv6(ipv6List); err != nil {
+	if len(p.cf.DomainIPv6s) > 0 {
+		if err := p.HandleDNSRecordsIPv6(ipv6List); err != nil {
 			log.Printf("处理IPv6 DNS记录失败: %v", err)
 		}
 	}
@@ -350,7 +302,7 @@ func HandleAllDNSRecords(ipv4List []string, ipv6List []string) error {
 }
 
 // GetIPListForDomains 返回所有可用的 IP，不再截断或报错
-func GetIPListForDomains(speedData []string, domains []string) ([]string, error) {
+func GetIPListForDomains(speedData utils.DownloadSpeedSet, domains []config.DomainConfig) (utils.DownloadSpeedSet, error) {
 	if len(speedData) == 0 {
 		return nil, fmt.Errorf("没有可用的测速数据")
 	}
@@ -359,7 +311,7 @@ func GetIPListForDomains(speedData []string, domains []string) ([]string, error)
 }
 
 // GetIPListForIPv6Domains 返回所有可用的 IPv6 IP，不再截断或报错
-func GetIPListForIPv6Domains(speedData []string, domains []string) ([]string, error) {
+func GetIPListForIPv6Domains(speedData utils.DownloadSpeedSet, domains []config.DomainConfig) (utils.DownloadSpeedSet, error) {
 	if len(speedData) == 0 {
 		return nil, fmt.Errorf("没有可用的测速数据")
 	}
@@ -0,0 +1,462 @@
+package cdn
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"AutoCDN/cdn/cftest"
+	"AutoCDN/config"
+	"AutoCDN/utils"
+)
+
+// newTestClient 启动模拟服务并创建指向它的客户端
+func newTestClient(t *testing.T, cf config.CloudflareConfig) (*Client, *cftest.Server) {
+	t.Helper()
+	server := cftest.NewServer()
+	t.Cleanup(server.Close)
+	server.Token = "test-token"
+	server.AddZone("zone1", "example.com")
+
+	if cf.APIToken == "" && cf.APIKey == "" {
+		cf.APIToken = "test-token"
+	}
+	if cf.ZoneID == "" {
+		cf.ZoneID = "zone1"
+	}
+	if cf.SnapshotDir == "" {
+		cf.SnapshotDir = t.TempDir()
+	}
+	client := NewClient(server.URL, cf, server.Client())
+	client.initialBackoff = time.Millisecond
+	return client, server
+}
+
+// newTestPublisher 启动模拟服务并创建使用它的发布器
+func newTestPublisher(t *testing.T, cf config.CloudflareConfig) (*Publisher, *cftest.Server) {
+	t.Helper()
+	client, server := newTestClient(t, cf)
+	return NewPublisher(client, client.cf), server
+}
+
+// speedSet 按顺序生成测速结果
+func speedSet(ips ...string) utils.DownloadSpeedSet {
+	var set utils.DownloadSpeedSet
+	for i, ip := range ips {
+		set = append(set, utils.CloudflareIPData{
+			PingData: &utils.PingData{
+				IP:       &net.IPAddr{IP: net.ParseIP(ip)},
+				Sended:   4,
+				Received: 4,
+				Delay:    time.Duration(100+i*10) * time.Millisecond,
+			},
+			DownloadSpeed: float64(20-i) * 1024 * 1024,
+		})
+	}
+	return set
+}
+
+func TestCreateUpdateDelete(t *testing.T) {
+	client, server := newTestClient(t, config.CloudflareConfig{})
+	domain := config.DomainConfig{Name: "cdn.example.com", TTL: 120}
+
+	if err := client.CreateDNSRecord("1.1.1.1", domain, "zone1"); err != nil {
+		t.Fatalf("创建记录失败: %v", err)
+	}
+	records, err := client.GetRecordListWithType("zone1", "A")
+	if err != nil {
+		t.Fatalf("获取记录失败: %v", err)
+	}
+	if len(records) != 1 || records[0].Content != "1.1.1.1" || records[0].TTL != 120 {
+		t.Fatalf("记录不符合预期: %+v", records)
+	}
+
+	if err := client.UpdateDNSRecords("2.2.2.2", domain, "zone1", records[0].ID); err != nil {
+		t.Fatalf("更新记录失败: %v", err)
+	}
+	if got := server.Contents("zone1", "cdn.example.com", "A"); !reflect.DeepEqual(got, []string{"2.2.2.2"}) {
+		t.Fatalf("更新后的记录为 %v", got)
+	}
+
+	if err := client.DeleteDNSRecord("zone1", records[0].ID); err != nil {
+		t.Fatalf("删除记录失败: %v", err)
+	}
+	if got := server.Records("zone1"); len(got) != 0 {
+		t.Fatalf("删除后仍有记录: %+v", got)
+	}
+}
+
+func TestListDNSRecordsPagination(t *testing.T) {
+	client, server := newTestClient(t, config.CloudflareConfig{})
+	for i := 0; i < recordsPerPage+5; i++ {
+		server.AddRecord("zone1", cftest.Record{Name: "cdn.example.com", Type: "A", Content: net.IPv4(10, 0, byte(i/256), byte(i%256)).String()})
+	}
+	server.AddRecord("zone1", cftest.Record{Name: "cdn.example.com", Type: "AAAA", Content: "2606:4700::1"})
+	server.AddRecord("zone1", cftest.Record{Name: "example.com", Type: "TXT", Content: "hello"})
+
+	records, err := client.GetRecordListWithType("zone1", "A")
+	if err != nil {
+		t.Fatalf("获取记录失败: %v", err)
+	}
+	if len(records) != recordsPerPage+5 {
+		t.Fatalf("期望 %d 条 A 记录，实际 %d 条", recordsPerPage+5, len(records))
+	}
+
+	all, err := client.GetRecordList("zone1")
+	if err != nil {
+		t.Fatalf("获取记录失败: %v", err)
+	}
+	if len(all) != recordsPerPage+6 {
+		t.Fatalf("期望 %d 条 A/AAAA 记录，实际 %d 条", recordsPerPage+6, len(all))
+	}
+}
+
+func TestAuthHeaders(t *testing.T) {
+	client, server := newTestClient(t, config.CloudflareConfig{APIKey: "key", Email: "user@example.com"})
+	server.Token = ""
+	server.APIKey = "key"
+	server.Email = "user@example.com"
+	if _, err := client.GetRecordList("zone1"); err != nil {
+		t.Fatalf("全局 API 密钥认证失败: %v", err)
+	}
+
+	server.APIKey = "other"
+	_, err := client.GetRecordList("zone1")
+	if !errors.Is(err, ErrAuth) {
+		t.Fatalf("期望认证错误，实际 %v", err)
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	client, server := newTestClient(t, config.CloudflareConfig{})
+	domain := config.DomainConfig{Name: "cdn.example.com"}
+
+	err := client.UpdateDNSRecords("1.1.1.1", domain, "zone1", "missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("期望记录不存在错误，实际 %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Errors[0].Code != 81044 {
+		t.Fatalf("错误未包含 Cloudflare 错误信息: %#v", err)
+	}
+
+	server.Fail(cftest.Failure{Method: "POST", Status: http.StatusBadRequest, Code: 9005, Message: "Content for A record is invalid."})
+	if err := client.CreateDNSRecord("1.1.1.1", domain, "zone1"); !errors.Is(err, ErrValidation) {
+		t.Fatalf("期望参数错误，实际 %v", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	client, server := newTestClient(t, config.CloudflareConfig{})
+
+	// 限流和服务端错误后重试成功
+	server.Fail(
+		cftest.Failure{Status: http.StatusTooManyRequests, Code: 10429, Message: "rate limited", RetryAfter: "0"},
+		cftest.Failure{Status: http.StatusBadGateway, Code: 10002, Message: "bad gateway"},
+	)
+	if _, err := client.GetRecordList("zone1"); err != nil {
+		t.Fatalf("重试后仍然失败: %v", err)
+	}
+	if n := len(server.Requests()); n != 3 {
+		t.Fatalf("期望请求 3 次，实际 %d 次", n)
+	}
+
+	// 超过最大尝试次数后返回最后一次错误
+	for i := 0; i < defaultMaxAttempts; i++ {
+		server.Fail(cftest.Failure{Status: http.StatusInternalServerError, Code: 10002, Message: "internal error"})
+	}
+	if _, err := client.GetRecordList("zone1"); !errors.Is(err, ErrServer) {
+		t.Fatalf("期望服务端错误，实际 %v", err)
+	}
+
+	// POST 遇到服务端错误时不重试，避免重复创建
+	before := len(server.Requests())
+	server.Fail(cftest.Failure{Method: "POST", Status: http.StatusInternalServerError, Code: 10002, Message: "internal error"})
+	if err := client.CreateDNSRecord("1.1.1.1", config.DomainConfig{Name: "cdn.example.com"}, "zone1"); !errors.Is(err, ErrServer) {
+		t.Fatalf("期望服务端错误，实际 %v", err)
+	}
+	if n := len(server.Requests()) - before; n != 1 {
+		t.Fatalf("POST 请求了 %d 次", n)
+	}
+}
+
+func TestPlanAndApply(t *testing.T) {
+	publisher, server := newTestPublisher(t, config.CloudflareConfig{
+		Domains:       []config.DomainConfig{{Name: "a.example.com"}, {Name: "b.example.com"}},
+		RecordSetSize: 2,
+	})
+	server.AddRecord("zone1", cftest.Record{Name: "a.example.com", Type: "A", Content: "1.1.1.1", TTL: config.DefaultTTL, Comment: ownerMarker})
+	server.AddRecord("zone1", cftest.Record{Name: "a.example.com", Type: "A", Content: "9.9.9.9", TTL: config.DefaultTTL, Comment: ownerMarker})
+	server.AddRecord("zone1", cftest.Record{Name: "a.example.com", Type: "A", Content: "8.8.8.8", TTL: config.DefaultTTL, Comment: ownerMarker})
+
+	plan, err := publisher.PlanDNSRecords(speedSet("1.1.1.1", "2.2.2.2"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	// a: 保持 1.1.1.1、更新一条为 2.2.2.2、删除一条；b: 创建两条
+	if plan.Changes() != 4 {
+		t.Fatalf("期望 4 项变更，实际:\n%v", plan.Lines())
+	}
+
+	if err := publisher.ApplyPlan(plan); err != nil {
+		t.Fatalf("执行计划失败: %v", err)
+	}
+	for _, name := range []string{"a.example.com", "b.example.com"} {
+		if got := server.Contents("zone1", name, "A"); !reflect.DeepEqual(got, []string{"1.1.1.1", "2.2.2.2"}) {
+			t.Fatalf("%s 的记录为 %v", name, got)
+		}
+	}
+
+	// 再次生成计划时无需变更
+	plan, err = publisher.PlanDNSRecords(speedSet("1.1.1.1", "2.2.2.2"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if plan.Changes() != 0 {
+		t.Fatalf("期望无需变更，实际:\n%v", plan.Lines())
+	}
+}
+
+func TestRecordOptionsMatch(t *testing.T) {
+	proxied := true
+	publisher, server := newTestPublisher(t, config.CloudflareConfig{
+		Domains: []config.DomainConfig{
+			{Name: "a.example.com", Proxied: &proxied, Tags: []string{"team:cdn", "env:prod"}},
+			{Name: "b.example.com", TTL: 300, Proxied: &proxied},
+		},
+	})
+	// Cloudflare 代理记录的 TTL 总是自动，标签顺序与配置不同
+	server.AddRecord("zone1", cftest.Record{Name: "a.example.com", Type: "A", Content: "1.1.1.1", TTL: 1, Proxied: true, Comment: ownerMarker, Tags: []string{"env:prod", "team:cdn"}})
+	server.AddRecord("zone1", cftest.Record{Name: "b.example.com", Type: "A", Content: "1.1.1.1", TTL: 1, Proxied: true, Comment: ownerMarker})
+
+	plan, err := publisher.PlanDNSRecords(speedSet("1.1.1.1"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if plan.Changes() != 0 {
+		t.Fatalf("期望无需变更，实际:\n%v", plan.Lines())
+	}
+
+	// 标签变化时更新记录
+	publisher.cf.Domains[0].Tags = []string{"team:cdn"}
+	plan, err = publisher.PlanDNSRecords(speedSet("1.1.1.1"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if plan.Changes() != 1 || plan.Actions[0].Name != "a.example.com" || plan.Actions[0].Action != ActionUpdate {
+		t.Fatalf("期望更新 a 的标签，实际:\n%v", plan.Lines())
+	}
+	if err := publisher.ApplyPlan(plan); err != nil {
+		t.Fatalf("执行计划失败: %v", err)
+	}
+	for _, record := range server.Records("zone1") {
+		if record.Name == "a.example.com" && (record.TTL != 1 || !reflect.DeepEqual(record.Tags, []string{"team:cdn"})) {
+			t.Fatalf("a 的记录为 %+v", record)
+		}
+	}
+}
+
+// memorySets 以记录集为单位写入的内存服务商，记录提交的记录集
+type memorySets struct {
+	values    map[string][]string // 记录名 -> A 记录的值
+	submitted []RecordSet
+}
+
+func (m *memorySets) Name() string { return "memory" }
+
+func (m *memorySets) Capabilities() Capabilities {
+	return Capabilities{MultiValue: true, NameLookup: true}
+}
+
+func (m *memorySets) ListZones() ([]Zone, error) {
+	return []Zone{{ID: "zone1", Name: "example.com"}}, nil
+}
+
+func (m *memorySets) ListRecords(zoneID string, filter RecordFilter) ([]DNSRecord, error) {
+	var records []DNSRecord
+	if filter.Type != "" && filter.Type != "A" {
+		return nil, nil
+	}
+	for _, value := range m.values[filter.Name] {
+		records = append(records, DNSRecord{ID: value, Name: filter.Name, Type: "A", Content: value, TTL: 300})
+	}
+	return records, nil
+}
+
+func (m *memorySets) UpsertRecord(zoneID string, record DNSRecord) error {
+	return errors.New("只支持提交记录集")
+}
+
+func (m *memorySets) DeleteRecord(zoneID string, record DNSRecord) error {
+	return errors.New("只支持提交记录集")
+}
+
+func (m *memorySets) ApplyRecordSets(zoneID string, sets []RecordSet) error {
+	for _, set := range sets {
+		m.submitted = append(m.submitted, set)
+		m.values[set.Name] = set.Values
+	}
+	return nil
+}
+
+func TestRecordSetKeepsUnmanagedValues(t *testing.T) {
+	provider := &memorySets{values: map[string][]string{"a.example.com": {"9.9.9.9", "8.8.8.8"}}}
+	publisher := NewPublisher(provider, config.CloudflareConfig{
+		Domains:     []config.DomainConfig{{Name: "a.example.com", TTL: 300}},
+		Ownership:   config.OwnershipConfig{AllowUnmarked: true},
+		SnapshotDir: t.TempDir(),
+	})
+
+	// 单 IP 模式只维护第一条记录，其余同名记录不出现在计划中，提交记录集时保留
+	plan, err := publisher.PlanDNSRecords(speedSet("1.1.1.1"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].Action != ActionUpdate || plan.Actions[0].OldContent != "9.9.9.9" {
+		t.Fatalf("计划为:\n%v", plan.Lines())
+	}
+	if err := publisher.ApplyPlan(plan); err != nil {
+		t.Fatalf("执行计划失败: %v", err)
+	}
+	if len(provider.submitted) != 1 || !reflect.DeepEqual(provider.submitted[0].Values, []string{"1.1.1.1", "8.8.8.8"}) {
+		t.Fatalf("提交的记录集为 %+v", provider.submitted)
+	}
+}
+
+// writeRequests 返回服务收到的写请求（非 GET）
+func writeRequests(server *cftest.Server) []cftest.Request {
+	var writes []cftest.Request
+	for _, req := range server.Requests() {
+		if req.Method != http.MethodGet {
+			writes = append(writes, req)
+		}
+	}
+	return writes
+}
+
+func TestBatchApply(t *testing.T) {
+	cf := config.CloudflareConfig{
+		Domains:       []config.DomainConfig{{Name: "a.example.com"}, {Name: "b.example.com"}},
+		RecordSetSize: 2,
+	}
+	publisher, server := newTestPublisher(t, cf)
+	server.AddRecord("zone1", cftest.Record{Name: "a.example.com", Type: "A", Content: "9.9.9.9", TTL: config.DefaultTTL, Comment: ownerMarker})
+	server.AddRecord("zone1", cftest.Record{Name: "a.example.com", Type: "A", Content: "8.8.8.8", TTL: config.DefaultTTL, Comment: ownerMarker})
+	server.AddRecord("zone1", cftest.Record{Name: "a.example.com", Type: "A", Content: "7.7.7.7", TTL: config.DefaultTTL, Comment: ownerMarker})
+
+	// 批量提交失败时整个 Zone 的变更均不生效
+	server.Fail(cftest.Failure{Method: "POST", Path: "/zones/zone1/dns_records/batch", Status: http.StatusBadRequest, Code: 9005, Message: "Content for A record is invalid."})
+	if err := publisher.HandleDNSRecords(speedSet("1.1.1.1", "2.2.2.2")); err == nil {
+		t.Fatal("批量提交失败时应返回错误")
+	}
+	if got := server.Contents("zone1", "a.example.com", "A"); !reflect.DeepEqual(got, []string{"7.7.7.7", "8.8.8.8", "9.9.9.9"}) {
+		t.Fatalf("批量提交失败后 a 的记录被修改: %v", got)
+	}
+	if got := server.Contents("zone1", "b.example.com", "A"); len(got) != 0 {
+		t.Fatalf("批量提交失败后 b 的记录被创建: %v", got)
+	}
+
+	// 更新、删除和创建在一次请求中提交
+	before := len(writeRequests(server))
+	if err := publisher.HandleDNSRecords(speedSet("1.1.1.1", "2.2.2.2")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if writes := writeRequests(server)[before:]; len(writes) != 1 || writes[0].Path != "/zones/zone1/dns_records/batch" {
+		t.Fatalf("期望 1 次批量请求，实际 %+v", writes)
+	}
+	for _, name := range []string{"a.example.com", "b.example.com"} {
+		if got := server.Contents("zone1", name, "A"); !reflect.DeepEqual(got, []string{"1.1.1.1", "2.2.2.2"}) {
+			t.Fatalf("%s 的记录为 %v", name, got)
+		}
+	}
+
+	// 批量接口不可用时逐条修改
+	publisher, server = newTestPublisher(t, cf)
+	server.DisableBatch = true
+	if err := publisher.HandleDNSRecords(speedSet("1.1.1.1", "2.2.2.2")); err != nil {
+		t.Fatalf("逐条发布失败: %v", err)
+	}
+	if n := len(writeRequests(server)); n != 5 {
+		t.Fatalf("期望 1 次批量请求和 4 次逐条创建，实际 %d 次写请求", n)
+	}
+	if got := server.Contents("zone1", "b.example.com", "A"); !reflect.DeepEqual(got, []string{"1.1.1.1", "2.2.2.2"}) {
+		t.Fatalf("b 的记录为 %v", got)
+	}
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	publisher, server := newTestPublisher(t, config.CloudflareConfig{
+		Domains: []config.DomainConfig{{Name: "cdn.example.com"}},
+	})
+	server.AddRecord("zone1", cftest.Record{Name: "cdn.example.com", Type: "A", Content: "1.1.1.1", TTL: config.DefaultTTL, Comment: ownerMarker})
+
+	if err := publisher.HandleDNSRecords(speedSet("2.2.2.2")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	snapshots, err := publisher.ListSnapshots()
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("期望 1 个快照，实际 %d 个 (%v)", len(snapshots), err)
+	}
+
+	if err := publisher.RestoreSnapshot(snapshots[0].ID); err != nil {
+		t.Fatalf("恢复快照失败: %v", err)
+	}
+	if got := server.Contents("zone1", "cdn.example.com", "A"); !reflect.DeepEqual(got, []string{"1.1.1.1"}) {
+		t.Fatalf("恢复后的记录为 %v", got)
+	}
+}
+
+func TestMultiZone(t *testing.T) {
+	publisher, server := newTestPublisher(t, config.CloudflareConfig{
+		ZoneID:   "zone1",
+		ZoneName: "example.com",
+		Domains:  []config.DomainConfig{{Name: "cdn.example.com"}, {Name: "cdn.example.org"}},
+	})
+	server.AddZone("zone2", "example.org")
+
+	if err := publisher.HandleDNSRecords(speedSet("1.1.1.1")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if got := server.Contents("zone2", "cdn.example.org", "A"); !reflect.DeepEqual(got, []string{"1.1.1.1"}) {
+		t.Fatalf("example.org 中的记录为 %v", got)
+	}
+
+	publisher.cf.Domains = []config.DomainConfig{{Name: "cdn.example.net"}}
+	if _, err := publisher.PlanDNSRecords(speedSet("1.1.1.1")); err == nil {
+		t.Fatal("不属于任何 Zone 的域名应返回错误")
+	}
+	// 只填写 ZoneID 时同样按后缀匹配，其他 Zone 的域名不会写入配置的 Zone
+	publisher, server = newTestPublisher(t, config.CloudflareConfig{
+		Domains: []config.DomainConfig{{Name: "cdn.example.org"}},
+	})
+	server.AddZone("zone2", "example.org")
+	if err := publisher.HandleDNSRecords(speedSet("1.1.1.1")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if got := server.Contents("zone1", "cdn.example.org", "A"); len(got) != 0 {
+		t.Fatalf("example.org 的记录写入了配置的 Zone: %v", got)
+	}
+	if got := server.Contents("zone2", "cdn.example.org", "A"); !reflect.DeepEqual(got, []string{"1.1.1.1"}) {
+		t.Fatalf("example.org 中的记录为 %v", got)
+	}
+	publisher.cf.Domains = []config.DomainConfig{{Name: "cdn.example.net"}}
+	if _, err := publisher.PlanDNSRecords(speedSet("1.1.1.1")); err == nil {
+		t.Fatal("不属于任何 Zone 的域名应返回错误")
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	provider, err := NewProvider(cfg)
+	if err != nil || provider.Name() != ProviderCloudflare {
+		t.Fatalf("默认应使用 Cloudflare，实际 %v (%v)", provider, err)
+	}
+
+	cfg.Provider = "unknown"
+	if _, err := NewProvider(cfg); err == nil {
+		t.Fatal("未知的服务商应返回错误")
+	}
+}
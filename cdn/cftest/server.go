@@ -0,0 +1,598 @@
+// Package cftest 提供基于 httptest 的 Cloudflare DNS / 负载均衡 API 模拟服务，用于在无网络环境下测试 cdn 包
+package cftest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认每页数量
+const defaultPerPage = 20
+
+// Record 模拟服务中保存的 DNS 记录
+type Record struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Content    string      `json:"content"`
+	TTL        int         `json:"ttl"`
+	Proxied    bool        `json:"proxied"`
+	Comment    string      `json:"comment"`
+	Tags       []string    `json:"tags"`
+	Data       *RecordData `json:"data,omitempty"`
+	ModifiedOn time.Time   `json:"modified_on"`
+}
+
+// RecordData HTTPS / SVCB 记录的 data 字段，记录内容由 data 生成
+type RecordData struct {
+	Priority int    `json:"priority"`
+	Target   string `json:"target"`
+	Value    string `json:"value"`
+}
+
+// content 返回 data 对应的记录内容
+func (d *RecordData) content() string {
+	return strings.TrimSpace(fmt.Sprintf("%d %s %s", d.Priority, d.Target, d.Value))
+}
+
+// fillContent HTTPS / SVCB 记录只接受 data，内容由 data 生成；缺少 data 时内容为空，请求会被拒绝
+func fillContent(record *Record) {
+	if record.Type != "HTTPS" && record.Type != "SVCB" {
+		return
+	}
+	record.Content = ""
+	if record.Data != nil {
+		record.Content = record.Data.content()
+	}
+}
+
+// Zone 模拟服务中的 Zone
+type Zone struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Failure 注入的错误响应，匹配到的下一次请求直接返回该错误
+type Failure struct {
+	Method     string // 为空时匹配任意方法
+	Path       string // 路径前缀，为空时匹配任意路径
+	Status     int    // HTTP 状态码
+	Code       int    // Cloudflare 错误码
+	Message    string
+	RetryAfter string // Retry-After 响应头
+}
+
+// Request 服务收到的请求记录
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+}
+
+// Server 有状态的 Cloudflare DNS API 模拟服务
+type Server struct {
+	*httptest.Server
+
+	// 认证信息，Token 非空时要求 Bearer 认证，否则 APIKey 非空时要求 X-Auth-Key / X-Auth-Email
+	Token  string
+	APIKey string
+	Email  string
+
+	// DisableBatch 为 true 时 dns_records/batch 返回 405，模拟批量接口不可用
+	DisableBatch bool
+
+	mu       sync.Mutex
+	zones    []Zone
+	records  map[string][]*Record
+	pools    map[string]*Pool // 账号 ID/地址池 ID -> 地址池
+	failures []Failure
+	requests []Request
+	nextID   int
+}
+
+// NewServer 启动模拟服务，使用完毕后需调用 Close
+func NewServer() *Server {
+	s := &Server{records: make(map[string][]*Record), pools: make(map[string]*Pool)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// AddZone 添加 Zone
+func (s *Server) AddZone(id, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.zones = append(s.zones, Zone{ID: id, Name: name})
+}
+
+// AddRecord 向 Zone 添加记录并返回分配了 ID 的记录
+func (s *Server) AddRecord(zoneID string, record Record) Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record.ID == "" {
+		record.ID = s.newID()
+	}
+	if record.TTL == 0 {
+		record.TTL = 1
+	}
+	record.ModifiedOn = time.Now().UTC()
+	s.records[zoneID] = append(s.records[zoneID], &record)
+	return record
+}
+
+// Pool 负载均衡地址池，源站以原始字段保存，便于检查未知字段是否被保留
+type Pool struct {
+	ID      string                   `json:"id"`
+	Name    string                   `json:"name"`
+	Origins []map[string]interface{} `json:"origins"`
+}
+
+// AddPool 添加账号下的负载均衡地址池
+func (s *Server) AddPool(accountID string, pool Pool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pools[accountID+"/"+pool.ID] = &pool
+}
+
+// Pool 返回地址池的当前内容
+func (s *Server) Pool(accountID, poolID string) Pool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pool := *s.pools[accountID+"/"+poolID]
+	pool.Origins = append([]map[string]interface{}(nil), pool.Origins...)
+	return pool
+}
+
+// Records 返回 Zone 中当前的全部记录
+func (s *Server) Records(zoneID string) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records := make([]Record, 0, len(s.records[zoneID]))
+	for _, record := range s.records[zoneID] {
+		records = append(records, *record)
+	}
+	return records
+}
+
+// Contents 返回 Zone 中指定记录名和类型的记录内容（已排序）
+func (s *Server) Contents(zoneID, name, recordType string) []string {
+	var contents []string
+	for _, record := range s.Records(zoneID) {
+		if record.Name == name && record.Type == recordType {
+			contents = append(contents, record.Content)
+		}
+	}
+	sort.Strings(contents)
+	return contents
+}
+
+// Fail 注入错误，按注入顺序依次匹配，每个错误只生效一次
+func (s *Server) Fail(failures ...Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failures...)
+}
+
+// Requests 返回服务收到的全部请求
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// newID 生成记录 ID，调用方需持有锁
+func (s *Server) newID() string {
+	s.nextID++
+	return fmt.Sprintf("rec%04d", s.nextID)
+}
+
+// envelope Cloudflare API 响应信封
+type envelope struct {
+	Success    bool        `json:"success"`
+	Errors     []message   `json:"errors"`
+	Messages   []message   `json:"messages"`
+	Result     interface{} `json:"result"`
+	ResultInfo *resultInfo `json:"result_info,omitempty"`
+}
+
+type message struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type resultInfo struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	Count      int `json:"count"`
+	TotalCount int `json:"total_count"`
+	TotalPages int `json:"total_pages"`
+}
+
+// writeJSON 写出响应
+func writeJSON(w http.ResponseWriter, status int, body envelope) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError 写出错误响应
+func writeError(w http.ResponseWriter, status, code int, msg string) {
+	writeJSON(w, status, envelope{Errors: []message{{Code: code, Message: msg}}, Messages: []message{}})
+}
+
+// writeResult 写出成功响应
+func writeResult(w http.ResponseWriter, result interface{}, info *resultInfo) {
+	writeJSON(w, http.StatusOK, envelope{Success: true, Errors: []message{}, Messages: []message{}, Result: result, ResultInfo: info})
+}
+
+// takeFailure 取出与请求匹配的注入错误，调用方需持有锁
+func (s *Server) takeFailure(r *http.Request) (Failure, bool) {
+	for i, f := range s.failures {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if f.Path != "" && !strings.HasPrefix(r.URL.Path, f.Path) {
+			continue
+		}
+		s.failures = append(s.failures[:i], s.failures[i+1:]...)
+		return f, true
+	}
+	return Failure{}, false
+}
+
+// authorized 检查请求的认证头
+func (s *Server) authorized(r *http.Request) bool {
+	if s.Token != "" {
+		return r.Header.Get("Authorization") == "Bearer "+s.Token
+	}
+	if s.APIKey != "" {
+		return r.Header.Get("X-Auth-Key") == s.APIKey && r.Header.Get("X-Auth-Email") == s.Email
+	}
+	return true
+}
+
+// handle 分发请求
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery})
+
+	if f, ok := s.takeFailure(r); ok {
+		if f.RetryAfter != "" {
+			w.Header().Set("Retry-After", f.RetryAfter)
+		}
+		writeError(w, f.Status, f.Code, f.Message)
+		return
+	}
+	if !s.authorized(r) {
+		writeError(w, http.StatusForbidden, 10000, "Authentication error")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "zones" && r.Method == http.MethodGet:
+		s.listZones(w, r)
+	case len(parts) >= 3 && parts[0] == "zones" && parts[2] == "dns_records":
+		if !s.hasZone(parts[1]) {
+			writeError(w, http.StatusNotFound, 7003, "Could not route to /zones/"+parts[1])
+			return
+		}
+		switch {
+		case len(parts) == 3 && r.Method == http.MethodGet:
+			s.listRecords(w, r, parts[1])
+		case len(parts) == 3 && r.Method == http.MethodPost:
+			s.createRecord(w, r, parts[1])
+		case len(parts) == 4 && parts[3] == "batch" && r.Method == http.MethodPost && !s.DisableBatch:
+			s.batch(w, r, parts[1])
+		case len(parts) == 4 && (r.Method == http.MethodPatch || r.Method == http.MethodPut):
+			s.updateRecord(w, r, parts[1], parts[3])
+		case len(parts) == 4 && r.Method == http.MethodDelete:
+			s.deleteRecord(w, parts[1], parts[3])
+		default:
+			writeError(w, http.StatusMethodNotAllowed, 10405, "Method not allowed")
+		}
+	case len(parts) == 5 && parts[0] == "accounts" && parts[2] == "load_balancers" && parts[3] == "pools":
+		s.pool(w, r, parts[1]+"/"+parts[4])
+	default:
+		writeError(w, http.StatusNotFound, 7000, "No route for that URI")
+	}
+}
+
+// pool GET / PATCH /accounts/{account}/load_balancers/pools/{pool}，PATCH 会替换全部源站
+func (s *Server) pool(w http.ResponseWriter, r *http.Request, key string) {
+	pool, ok := s.pools[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, 1003, "pool not found")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeResult(w, pool, nil)
+	case http.MethodPatch:
+		var update struct {
+			Origins []map[string]interface{} `json:"origins"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			writeError(w, http.StatusBadRequest, 1002, "invalid pool")
+			return
+		}
+		for _, origin := range update.Origins {
+			if weight, _ := origin["weight"].(float64); weight < 0 || weight > 1 {
+				writeError(w, http.StatusBadRequest, 1002, "origin weight must be between 0 and 1")
+				return
+			}
+		}
+		if update.Origins != nil {
+			pool.Origins = update.Origins
+		}
+		writeResult(w, pool, nil)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, 10405, "Method not allowed")
+	}
+}
+
+// hasZone 判断 Zone 是否存在，调用方需持有锁
+func (s *Server) hasZone(zoneID string) bool {
+	for _, zone := range s.zones {
+		if zone.ID == zoneID {
+			return true
+		}
+	}
+	return false
+}
+
+// paginate 按 page / per_page 参数分页
+func paginate(r *http.Request, total int) (start, end int, info *resultInfo) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage < 1 {
+		perPage = defaultPerPage
+	}
+
+	start = (page - 1) * perPage
+	if start > total {
+		start = total
+	}
+	end = start + perPage
+	if end > total {
+		end = total
+	}
+	totalPages := (total + perPage - 1) / perPage
+	return start, end, &resultInfo{Page: page, PerPage: perPage, Count: end - start, TotalCount: total, TotalPages: totalPages}
+}
+
+// listZones GET /zones
+func (s *Server) listZones(w http.ResponseWriter, r *http.Request) {
+	start, end, info := paginate(r, len(s.zones))
+	writeResult(w, s.zones[start:end], info)
+}
+
+// listRecords GET /zones/{zone}/dns_records，支持 name / type 筛选
+func (s *Server) listRecords(w http.ResponseWriter, r *http.Request, zoneID string) {
+	name := r.URL.Query().Get("name")
+	recordType := r.URL.Query().Get("type")
+
+	matched := []*Record{}
+	for _, record := range s.records[zoneID] {
+		if name != "" && record.Name != name || recordType != "" && record.Type != recordType {
+			continue
+		}
+		matched = append(matched, record)
+	}
+	start, end, info := paginate(r, len(matched))
+	writeResult(w, matched[start:end], info)
+}
+
+// decodeRecord 解析请求体中的记录
+func decodeRecord(r *http.Request) (Record, map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+		return Record{}, nil, err
+	}
+	data, _ := json.Marshal(fields)
+	var record Record
+	err := json.Unmarshal(data, &record)
+	return record, fields, err
+}
+
+// createRecord POST /zones/{zone}/dns_records
+func (s *Server) createRecord(w http.ResponseWriter, r *http.Request, zoneID string) {
+	record, _, err := decodeRecord(r)
+	fillContent(&record)
+	if err != nil || record.Name == "" || record.Type == "" || record.Content == "" {
+		writeError(w, http.StatusBadRequest, 9005, "Invalid DNS record")
+		return
+	}
+	for _, existing := range s.records[zoneID] {
+		if existing.Name == record.Name && existing.Type == record.Type && existing.Content == record.Content {
+			writeError(w, http.StatusBadRequest, 81057, "An identical record already exists.")
+			return
+		}
+	}
+	record.ID = s.newID()
+	if record.TTL == 0 {
+		record.TTL = 1
+	}
+	record.ModifiedOn = time.Now().UTC()
+	s.records[zoneID] = append(s.records[zoneID], &record)
+	writeResult(w, record, nil)
+}
+
+// updateRecord PATCH / PUT /zones/{zone}/dns_records/{id}，PATCH 只修改请求中出现的字段
+func (s *Server) updateRecord(w http.ResponseWriter, r *http.Request, zoneID, recordID string) {
+	var target *Record
+	for _, record := range s.records[zoneID] {
+		if record.ID == recordID {
+			target = record
+		}
+	}
+	if target == nil {
+		writeError(w, http.StatusNotFound, 81044, "Record does not exist.")
+		return
+	}
+
+	update, fields, err := decodeRecord(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, 9005, "Invalid DNS record")
+		return
+	}
+	if r.Method == http.MethodPut {
+		update.ID = target.ID
+		fillContent(&update)
+		*target = update
+	} else {
+		applyFields(target, update, fields)
+	}
+	target.ModifiedOn = time.Now().UTC()
+	writeResult(w, target, nil)
+}
+
+// applyFields 将 PATCH 请求中出现的字段写入记录
+func applyFields(target *Record, update Record, fields map[string]json.RawMessage) {
+	if _, ok := fields["name"]; ok {
+		target.Name = update.Name
+	}
+	if _, ok := fields["type"]; ok {
+		target.Type = update.Type
+	}
+	if _, ok := fields["content"]; ok {
+		target.Content = update.Content
+	}
+	if _, ok := fields["ttl"]; ok {
+		target.TTL = update.TTL
+	}
+	if _, ok := fields["proxied"]; ok {
+		target.Proxied = update.Proxied
+	}
+	if _, ok := fields["comment"]; ok {
+		target.Comment = update.Comment
+	}
+	if _, ok := fields["tags"]; ok {
+		target.Tags = update.Tags
+	}
+	if _, ok := fields["data"]; ok {
+		target.Data = update.Data
+		fillContent(target)
+	}
+}
+
+// deleteRecord DELETE /zones/{zone}/dns_records/{id}
+func (s *Server) deleteRecord(w http.ResponseWriter, zoneID, recordID string) {
+	records := s.records[zoneID]
+	for i, record := range records {
+		if record.ID == recordID {
+			s.records[zoneID] = append(records[:i], records[i+1:]...)
+			writeResult(w, map[string]string{"id": recordID}, nil)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, 81044, "Record does not exist.")
+}
+
+// batchRequest dns_records/batch 请求体
+type batchRequest struct {
+	Deletes []struct {
+		ID string `json:"id"`
+	} `json:"deletes"`
+	Patches []json.RawMessage `json:"patches"`
+	Puts    []json.RawMessage `json:"puts"`
+	Posts   []json.RawMessage `json:"posts"`
+}
+
+// batch POST /zones/{zone}/dns_records/batch，按 deletes、patches、puts、posts 的顺序执行
+// 任一项失败时整个批次不生效
+func (s *Server) batch(w http.ResponseWriter, r *http.Request, zoneID string) {
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, 9005, "Invalid batch request")
+		return
+	}
+
+	// 在副本上执行，全部成功后才替换
+	records := make([]*Record, 0, len(s.records[zoneID]))
+	for _, record := range s.records[zoneID] {
+		copied := *record
+		records = append(records, &copied)
+	}
+	find := func(id string) int {
+		for i, record := range records {
+			if record.ID == id {
+				return i
+			}
+		}
+		return -1
+	}
+	nextID := s.nextID
+	result := map[string][]Record{"deletes": {}, "patches": {}, "puts": {}, "posts": {}}
+	now := time.Now().UTC()
+
+	for _, del := range req.Deletes {
+		i := find(del.ID)
+		if i < 0 {
+			writeError(w, http.StatusBadRequest, 81044, "Record does not exist.")
+			return
+		}
+		result["deletes"] = append(result["deletes"], *records[i])
+		records = append(records[:i], records[i+1:]...)
+	}
+	for key, items := range map[string][]json.RawMessage{"patches": req.Patches, "puts": req.Puts} {
+		for _, item := range items {
+			var fields map[string]json.RawMessage
+			var update Record
+			if json.Unmarshal(item, &fields) != nil || json.Unmarshal(item, &update) != nil {
+				writeError(w, http.StatusBadRequest, 9005, "Invalid DNS record")
+				return
+			}
+			i := find(update.ID)
+			if i < 0 {
+				writeError(w, http.StatusBadRequest, 81044, "Record does not exist.")
+				return
+			}
+			if key == "puts" {
+				fillContent(&update)
+				*records[i] = update
+			} else {
+				applyFields(records[i], update, fields)
+			}
+			records[i].ModifiedOn = now
+			result[key] = append(result[key], *records[i])
+		}
+	}
+	for _, item := range req.Posts {
+		var record Record
+		err := json.Unmarshal(item, &record)
+		fillContent(&record)
+		if err != nil || record.Name == "" || record.Type == "" || record.Content == "" {
+			writeError(w, http.StatusBadRequest, 9005, "Invalid DNS record")
+			return
+		}
+		for _, existing := range records {
+			if existing.Name == record.Name && existing.Type == record.Type && existing.Content == record.Content {
+				writeError(w, http.StatusBadRequest, 81057, "An identical record already exists.")
+				return
+			}
+		}
+		nextID++
+		record.ID = fmt.Sprintf("rec%04d", nextID)
+		if record.TTL == 0 {
+			record.TTL = 1
+		}
+		record.ModifiedOn = now
+		records = append(records, &record)
+		result["posts"] = append(result["posts"], record)
+	}
+
+	s.records[zoneID] = records
+	s.nextID = nextID
+	writeResult(w, result, nil)
+}
@@ -0,0 +1,168 @@
+package cdn
+
+import (
+	"AutoCDN/config"
+	"AutoCDN/utils"
+)
+
+// 以下包级函数使用全局配置创建的默认客户端或发布器，供命令行和图形界面调用
+
+// ListDNSRecords 获取Zone中符合条件的全部DNS记录
+func ListDNSRecords(zoneID string, filter RecordFilter) ([]DNSRecord, error) {
+	return DefaultClient().ListDNSRecords(zoneID, filter)
+}
+
+// GetRecordList 获取Zone中的A和AAAA记录
+func GetRecordList(zoneID string) ([]DNSRecord, error) {
+	return DefaultClient().GetRecordList(zoneID)
+}
+
+// GetRecordListWithType 获取Zone中指定类型的记录
+func GetRecordListWithType(zoneID, recordType string) ([]DNSRecord, error) {
+	return DefaultClient().GetRecordListWithType(zoneID, recordType)
+}
+
+// UpdateDNSRecords 更新A记录
+func UpdateDNSRecords(newIP string, domain config.DomainConfig, zoneID, recordID string) error {
+	return DefaultClient().UpdateDNSRecords(newIP, domain, zoneID, recordID)
+}
+
+// UpdateDNSRecordsIPv6 更新AAAA记录
+func UpdateDNSRecordsIPv6(newIP string, domain config.DomainConfig, zoneID, recordID string) error {
+	return DefaultClient().UpdateDNSRecordsIPv6(newIP, domain, zoneID, recordID)
+}
+
+// CreateDNSRecord 创建A记录
+func CreateDNSRecord(newIP string, domain config.DomainConfig, zoneID string) error {
+	return DefaultClient().CreateDNSRecord(newIP, domain, zoneID)
+}
+
+// CreateDNSRecordIPv6 创建AAAA记录
+func CreateDNSRecordIPv6(newIP string, domain config.DomainConfig, zoneID string) error {
+	return DefaultClient().CreateDNSRecordIPv6(newIP, domain, zoneID)
+}
+
+// DeleteDNSRecord 删除DNS记录
+func DeleteDNSRecord(zoneID, recordID string) error {
+	return DefaultClient().DeleteDNSRecord(zoneID, recordID)
+}
+
+// HandleDNSRecords 将IPv4测速结果发布到配置的域名
+func HandleDNSRecords(ipList utils.DownloadSpeedSet) error {
+	publisher, err := DefaultPublisher()
+	if err != nil {
+		return err
+	}
+	return publisher.HandleDNSRecords(ipList)
+}
+
+// HandleDNSRecordsIPv6 将IPv6测速结果发布到配置的域名
+func HandleDNSRecordsIPv6(ipList utils.DownloadSpeedSet) error {
+	publisher, err := DefaultPublisher()
+	if err != nil {
+		return err
+	}
+	return publisher.HandleDNSRecordsIPv6(ipList)
+}
+
+// HandleAllDNSRecords 同时发布IPv4和IPv6测速结果
+func HandleAllDNSRecords(ipv4List utils.DownloadSpeedSet, ipv6List utils.DownloadSpeedSet) error {
+	publisher, err := DefaultPublisher()
+	if err != nil {
+		return err
+	}
+	return publisher.HandleAllDNSRecords(ipv4List, ipv6List)
+}
+
+// PlanDNSRecords 计算IPv4域名的变更计划
+func PlanDNSRecords(results utils.DownloadSpeedSet) (*Plan, error) {
+	publisher, err := DefaultPublisher()
+	if err != nil {
+		return nil, err
+	}
+	return publisher.PlanDNSRecords(results)
+}
+
+// PlanDNSRecordsIPv6 计算IPv6域名的变更计划
+func PlanDNSRecordsIPv6(results utils.DownloadSpeedSet) (*Plan, error) {
+	publisher, err := DefaultPublisher()
+	if err != nil {
+		return nil, err
+	}
+	return publisher.PlanDNSRecordsIPv6(results)
+}
+
+// ApplyPlan 按计划执行变更
+func ApplyPlan(plan *Plan) error {
+	publisher, err := DefaultPublisher()
+	if err != nil {
+		return err
+	}
+	return publisher.ApplyPlan(plan)
+}
+
+// PlanPool 计算将测速结果发布到配置的负载均衡地址池所需的变更
+// 发布前的质量保护同样生效
+func PlanPool(results utils.DownloadSpeedSet) (*PoolPlan, error) {
+	return DefaultClient().PlanPool(config.GetConfig().Cloudflare.LoadBalancer, results)
+}
+
+// ApplyPoolPlan 按计划更新负载均衡地址池
+func ApplyPoolPlan(plan *PoolPlan) error {
+	return DefaultClient().ApplyPoolPlan(plan)
+}
+
+// PublishPool 将测速结果发布到配置的负载均衡地址池
+func PublishPool(results utils.DownloadSpeedSet) error {
+	plan, err := PlanPool(results)
+	if err != nil {
+		return err
+	}
+	plan.Print()
+	return ApplyPoolPlan(plan)
+}
+
+// ListZones 获取配置的 DNS 服务商中当前凭据可访问的全部Zone
+func ListZones() ([]Zone, error) {
+	publisher, err := DefaultPublisher()
+	if err != nil {
+		return nil, err
+	}
+	return publisher.Provider().ListZones()
+}
+
+// LoadSnapshot 读取指定快照
+func LoadSnapshot(id string) (*Snapshot, error) {
+	publisher, err := DefaultPublisher()
+	if err != nil {
+		return nil, err
+	}
+	return publisher.LoadSnapshot(id)
+}
+
+// ListSnapshots 列出所有快照，最新的在前
+func ListSnapshots() ([]Snapshot, error) {
+	publisher, err := DefaultPublisher()
+	if err != nil {
+		return nil, err
+	}
+	return publisher.ListSnapshots()
+}
+
+// RestoreSnapshot 将快照中的记录恢复为快照时的内容
+func RestoreSnapshot(id string) error {
+	publisher, err := DefaultPublisher()
+	if err != nil {
+		return err
+	}
+	return publisher.RestoreSnapshot(id)
+}
+
+// AdoptRecords 接管配置中域名的现有记录，names 为空时接管全部配置的域名
+func AdoptRecords(names []string) ([]string, error) {
+	publisher, err := DefaultPublisher()
+	if err != nil {
+		return nil, err
+	}
+	return publisher.AdoptRecords(names)
+}
@@ -0,0 +1,429 @@
+package cdn
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DNS 报文编解码（RFC 1035 / RFC 2136）和 TSIG 签名（RFC 8945），只实现动态更新和查询所需的部分
+
+// DNS 记录类型和类
+const (
+	dnsTypeA    uint16 = 1
+	dnsTypeSOA  uint16 = 6
+	dnsTypeTXT  uint16 = 16
+	dnsTypeAAAA uint16 = 28
+	dnsTypeTSIG uint16 = 250
+	dnsTypeANY  uint16 = 255
+
+	dnsClassIN   uint16 = 1
+	dnsClassNONE uint16 = 254
+	dnsClassANY  uint16 = 255
+)
+
+// DNS 操作码
+const (
+	dnsOpcodeQuery  = 0
+	dnsOpcodeUpdate = 5
+)
+
+// DNS 响应码和 TSIG 错误码
+const (
+	dnsRcodeSuccess  = 0
+	dnsRcodeFormErr  = 1
+	dnsRcodeServFail = 2
+	dnsRcodeNXDomain = 3
+	dnsRcodeNotImp   = 4
+	dnsRcodeRefused  = 5
+	dnsRcodeYXDomain = 6
+	dnsRcodeYXRRSet  = 7
+	dnsRcodeNXRRSet  = 8
+	dnsRcodeNotAuth  = 9
+	dnsRcodeNotZone  = 10
+	dnsRcodeBadSig   = 16
+	dnsRcodeBadKey   = 17
+	dnsRcodeBadTime  = 18
+)
+
+var dnsRcodeNames = map[int]string{
+	dnsRcodeSuccess:  "NOERROR",
+	dnsRcodeFormErr:  "FORMERR",
+	dnsRcodeServFail: "SERVFAIL",
+	dnsRcodeNXDomain: "NXDOMAIN",
+	dnsRcodeNotImp:   "NOTIMP",
+	dnsRcodeRefused:  "REFUSED",
+	dnsRcodeYXDomain: "YXDOMAIN",
+	dnsRcodeYXRRSet:  "YXRRSET",
+	dnsRcodeNXRRSet:  "NXRRSET",
+	dnsRcodeNotAuth:  "NOTAUTH",
+	dnsRcodeNotZone:  "NOTZONE",
+	dnsRcodeBadSig:   "BADSIG",
+	dnsRcodeBadKey:   "BADKEY",
+	dnsRcodeBadTime:  "BADTIME",
+}
+
+// dnsRcodeName 返回响应码名称
+func dnsRcodeName(rcode int) string {
+	if name, ok := dnsRcodeNames[rcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("RCODE%d", rcode)
+}
+
+// dnsQuestion 问题段条目，更新报文中为 Zone 段
+type dnsQuestion struct {
+	Name  string
+	Type  uint16
+	Class uint16
+}
+
+// dnsRR 资源记录，Data 为未解析的 RDATA
+type dnsRR struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	TTL   uint32
+	Data  []byte
+}
+
+// dnsMessage DNS 报文
+// 更新报文中 Question 为 Zone 段，Answer 为前提条件段，Authority 为更新段
+type dnsMessage struct {
+	ID         uint16
+	Response   bool
+	Opcode     int
+	Truncated  bool
+	Rcode      int
+	Question   []dnsQuestion
+	Answer     []dnsRR
+	Authority  []dnsRR
+	Additional []dnsRR
+
+	// 解析得到的 TSIG 记录（不在 Additional 中），tsigOffset 为其在报文中的起始位置
+	tsig        *tsigRecord
+	tsigKeyName string
+	tsigOffset  int
+}
+
+// packName 将域名编码为不压缩的线路格式
+func packName(buf []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if label == "" || len(label) > 63 {
+				return nil, fmt.Errorf("无效的域名: %s", name)
+			}
+			buf = append(buf, byte(len(label)))
+			buf = append(buf, label...)
+		}
+	}
+	return append(buf, 0), nil
+}
+
+// unpackName 从报文的 off 处解析域名（支持压缩指针），返回域名和之后的位置
+func unpackName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	for hops := 0; ; hops++ {
+		if off >= len(msg) || hops > 127 {
+			return "", 0, fmt.Errorf("域名格式错误")
+		}
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.Join(labels, "."), end, nil
+		case length&0xC0 == 0xC0:
+			if off+1 >= len(msg) {
+				return "", 0, fmt.Errorf("域名格式错误")
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3FFF)
+		case length&0xC0 == 0:
+			if off+1+length > len(msg) {
+				return "", 0, fmt.Errorf("域名格式错误")
+			}
+			labels = append(labels, string(msg[off+1:off+1+length]))
+			off += 1 + length
+		default:
+			return "", 0, fmt.Errorf("不支持的域名标签类型")
+		}
+	}
+}
+
+// packRR 编码资源记录
+func packRR(buf []byte, rr dnsRR) ([]byte, error) {
+	buf, err := packName(buf, rr.Name)
+	if err != nil {
+		return nil, err
+	}
+	buf = binary.BigEndian.AppendUint16(buf, rr.Type)
+	buf = binary.BigEndian.AppendUint16(buf, rr.Class)
+	buf = binary.BigEndian.AppendUint32(buf, rr.TTL)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(rr.Data)))
+	return append(buf, rr.Data...), nil
+}
+
+// pack 编码报文（不含 TSIG 记录）
+func (m *dnsMessage) pack() ([]byte, error) {
+	flags := uint16(m.Opcode&0xF)<<11 | uint16(m.Rcode&0xF)
+	if m.Response {
+		flags |= 1 << 15
+	}
+	if m.Truncated {
+		flags |= 1 << 9
+	}
+
+	buf := make([]byte, 0, 512)
+	buf = binary.BigEndian.AppendUint16(buf, m.ID)
+	buf = binary.BigEndian.AppendUint16(buf, flags)
+	for _, n := range []int{len(m.Question), len(m.Answer), len(m.Authority), len(m.Additional)} {
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	}
+
+	var err error
+	for _, q := range m.Question {
+		if buf, err = packName(buf, q.Name); err != nil {
+			return nil, err
+		}
+		buf = binary.BigEndian.AppendUint16(buf, q.Type)
+		buf = binary.BigEndian.AppendUint16(buf, q.Class)
+	}
+	for _, section := range [][]dnsRR{m.Answer, m.Authority, m.Additional} {
+		for _, rr := range section {
+			if buf, err = packRR(buf, rr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return buf, nil
+}
+
+// unpackDNSMessage 解析报文，末尾的 TSIG 记录单独保存
+func unpackDNSMessage(data []byte) (*dnsMessage, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("DNS 报文过短")
+	}
+	flags := binary.BigEndian.Uint16(data[2:])
+	m := &dnsMessage{
+		ID:        binary.BigEndian.Uint16(data),
+		Response:  flags&(1<<15) != 0,
+		Opcode:    int(flags>>11) & 0xF,
+		Truncated: flags&(1<<9) != 0,
+		Rcode:     int(flags & 0xF),
+	}
+	counts := make([]int, 4)
+	for i := range counts {
+		counts[i] = int(binary.BigEndian.Uint16(data[4+2*i:]))
+	}
+
+	off := 12
+	for i := 0; i < counts[0]; i++ {
+		name, next, err := unpackName(data, off)
+		if err != nil {
+			return nil, err
+		}
+		if next+4 > len(data) {
+			return nil, fmt.Errorf("DNS 报文被截断")
+		}
+		m.Question = append(m.Question, dnsQuestion{
+			Name:  name,
+			Type:  binary.BigEndian.Uint16(data[next:]),
+			Class: binary.BigEndian.Uint16(data[next+2:]),
+		})
+		off = next + 4
+	}
+
+	sections := []*[]dnsRR{&m.Answer, &m.Authority, &m.Additional}
+	for s, section := range sections {
+		for i := 0; i < counts[s+1]; i++ {
+			start := off
+			name, next, err := unpackName(data, off)
+			if err != nil {
+				return nil, err
+			}
+			if next+10 > len(data) {
+				return nil, fmt.Errorf("DNS 报文被截断")
+			}
+			length := int(binary.BigEndian.Uint16(data[next+8:]))
+			if next+10+length > len(data) {
+				return nil, fmt.Errorf("DNS 报文被截断")
+			}
+			rr := dnsRR{
+				Name:  name,
+				Type:  binary.BigEndian.Uint16(data[next:]),
+				Class: binary.BigEndian.Uint16(data[next+2:]),
+				TTL:   binary.BigEndian.Uint32(data[next+4:]),
+				Data:  append([]byte(nil), data[next+10:next+10+length]...),
+			}
+			off = next + 10 + length
+
+			// TSIG 必须是附加段的最后一条记录
+			if s == 2 && i == counts[3]-1 && rr.Type == dnsTypeTSIG {
+				tsig, err := parseTSIG(rr.Data)
+				if err != nil {
+					return nil, err
+				}
+				m.tsig = &tsig
+				m.tsigKeyName = name
+				m.tsigOffset = start
+				continue
+			}
+			*section = append(*section, rr)
+		}
+	}
+	return m, nil
+}
+
+// TSIG 签名算法（RFC 8945），目前只支持 HMAC-SHA256
+const (
+	tsigAlgorithm = "hmac-sha256"
+	tsigFudge     = 300
+)
+
+// tsigRecord TSIG 记录的 RDATA
+type tsigRecord struct {
+	Algorithm  string
+	TimeSigned uint64 // 48 位 Unix 时间
+	Fudge      uint16
+	MAC        []byte
+	OriginalID uint16
+	Error      uint16
+	Other      []byte
+}
+
+// pack 编码 TSIG RDATA
+func (t tsigRecord) pack() []byte {
+	buf, _ := packName(nil, t.Algorithm)
+	buf = appendUint48(buf, t.TimeSigned)
+	buf = binary.BigEndian.AppendUint16(buf, t.Fudge)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(t.MAC)))
+	buf = append(buf, t.MAC...)
+	buf = binary.BigEndian.AppendUint16(buf, t.OriginalID)
+	buf = binary.BigEndian.AppendUint16(buf, t.Error)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(t.Other)))
+	return append(buf, t.Other...)
+}
+
+// parseTSIG 解析 TSIG RDATA，其中的算法名不允许压缩
+func parseTSIG(data []byte) (tsigRecord, error) {
+	var t tsigRecord
+	algorithm, off, err := unpackName(data, 0)
+	if err != nil {
+		return t, err
+	}
+	if off+10 > len(data) {
+		return t, fmt.Errorf("TSIG 记录格式错误")
+	}
+	t.Algorithm = algorithm
+	t.TimeSigned = uint64(binary.BigEndian.Uint16(data[off:]))<<32 | uint64(binary.BigEndian.Uint32(data[off+2:]))
+	t.Fudge = binary.BigEndian.Uint16(data[off+6:])
+	macSize := int(binary.BigEndian.Uint16(data[off+8:]))
+	off += 10
+	if off+macSize+6 > len(data) {
+		return t, fmt.Errorf("TSIG 记录格式错误")
+	}
+	t.MAC = append([]byte(nil), data[off:off+macSize]...)
+	off += macSize
+	t.OriginalID = binary.BigEndian.Uint16(data[off:])
+	t.Error = binary.BigEndian.Uint16(data[off+2:])
+	otherLen := int(binary.BigEndian.Uint16(data[off+4:]))
+	off += 6
+	if off+otherLen > len(data) {
+		return t, fmt.Errorf("TSIG 记录格式错误")
+	}
+	t.Other = append([]byte(nil), data[off:off+otherLen]...)
+	return t, nil
+}
+
+// appendUint48 以大端序追加 48 位整数
+func appendUint48(buf []byte, v uint64) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(v>>32))
+	return binary.BigEndian.AppendUint32(buf, uint32(v))
+}
+
+// tsigKey TSIG 密钥
+type tsigKey struct {
+	Name   string
+	Secret []byte
+}
+
+// mac 计算 MAC：请求 MAC（仅响应）+ 报文（不含 TSIG）+ TSIG 变量
+func (k tsigKey) mac(requestMAC, msg []byte, t tsigRecord) []byte {
+	h := hmac.New(sha256.New, k.Secret)
+	if requestMAC != nil {
+		h.Write(binary.BigEndian.AppendUint16(nil, uint16(len(requestMAC))))
+		h.Write(requestMAC)
+	}
+	h.Write(msg)
+
+	// TSIG 变量中的名称使用小写的规范格式
+	vars, _ := packName(nil, strings.ToLower(k.Name))
+	vars = binary.BigEndian.AppendUint16(vars, dnsClassANY)
+	vars = binary.BigEndian.AppendUint32(vars, 0)
+	vars, _ = packName(vars, strings.ToLower(t.Algorithm))
+	vars = appendUint48(vars, t.TimeSigned)
+	vars = binary.BigEndian.AppendUint16(vars, t.Fudge)
+	vars = binary.BigEndian.AppendUint16(vars, t.Error)
+	vars = binary.BigEndian.AppendUint16(vars, uint16(len(t.Other)))
+	vars = append(vars, t.Other...)
+	h.Write(vars)
+	return h.Sum(nil)
+}
+
+// sign 为已编码的报文追加 TSIG 记录，返回签名后的报文和 MAC
+// 签名响应时 requestMAC 为对应请求的 MAC
+func (k tsigKey) sign(msg, requestMAC []byte, now time.Time) ([]byte, []byte) {
+	t := tsigRecord{
+		Algorithm:  tsigAlgorithm,
+		TimeSigned: uint64(now.Unix()),
+		Fudge:      tsigFudge,
+		OriginalID: binary.BigEndian.Uint16(msg),
+	}
+	t.MAC = k.mac(requestMAC, msg, t)
+
+	signed := append([]byte(nil), msg...)
+	signed, _ = packRR(signed, dnsRR{Name: k.Name, Type: dnsTypeTSIG, Class: dnsClassANY, Data: t.pack()})
+	binary.BigEndian.PutUint16(signed[10:], binary.BigEndian.Uint16(signed[10:])+1)
+	return signed, t.MAC
+}
+
+// verify 校验报文的 TSIG 签名，data 为收到的原始报文，m 为其解析结果
+// 校验响应时 requestMAC 为请求的 MAC；返回值为对方报告或本地检查发现的 TSIG 错误码
+func (k tsigKey) verify(data []byte, m *dnsMessage, requestMAC []byte, now time.Time) (int, error) {
+	if m.tsig == nil {
+		return 0, fmt.Errorf("报文缺少 TSIG 签名")
+	}
+	t := *m.tsig
+	if !strings.EqualFold(strings.TrimSuffix(m.tsigKeyName, "."), strings.TrimSuffix(k.Name, ".")) {
+		return dnsRcodeBadKey, fmt.Errorf("TSIG 密钥名不匹配: %s", m.tsigKeyName)
+	}
+	if !strings.EqualFold(t.Algorithm, tsigAlgorithm) {
+		return dnsRcodeBadKey, fmt.Errorf("不支持的 TSIG 算法: %s", t.Algorithm)
+	}
+	if t.Error != 0 {
+		return int(t.Error), fmt.Errorf("TSIG 错误: %s", dnsRcodeName(int(t.Error)))
+	}
+
+	// 还原签名时的报文：去掉 TSIG 记录，附加记录数减一，恢复原始 ID
+	msg := append([]byte(nil), data[:m.tsigOffset]...)
+	binary.BigEndian.PutUint16(msg, t.OriginalID)
+	binary.BigEndian.PutUint16(msg[10:], binary.BigEndian.Uint16(msg[10:])-1)
+	if !hmac.Equal(t.MAC, k.mac(requestMAC, msg, t)) {
+		return dnsRcodeBadSig, fmt.Errorf("TSIG 签名校验失败")
+	}
+
+	signedAt := time.Unix(int64(t.TimeSigned), 0)
+	if diff := now.Sub(signedAt); diff > time.Duration(t.Fudge)*time.Second || -diff > time.Duration(t.Fudge)*time.Second {
+		return dnsRcodeBadTime, fmt.Errorf("TSIG 签名时间超出允许范围: %v", signedAt)
+	}
+	return 0, nil
+}
@@ -0,0 +1,324 @@
+package cdn
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"AutoCDN/config"
+)
+
+// DNSPod API 地址
+const dnspodBaseURL = "https://dnsapi.cn"
+
+// DNSPod 分页设置
+const (
+	dnspodDomainsPerPage = 100
+	dnspodRecordsPerPage = 500
+)
+
+// DNSPod 默认线路名
+const dnspodDefaultLine = "默认"
+
+// DNSPod 免费套餐允许的最小 TTL，ttl: auto 时使用
+const dnspodDefaultTTL = 600
+
+// DNSPod 返回的时间为北京时间
+var dnspodTimeZone = time.FixedZone("CST", 8*3600)
+
+// DNSPodClient DNSPod API 客户端
+type DNSPodClient struct {
+	retryPolicy
+	baseURL    string
+	cfg        config.DNSPodConfig
+	httpClient *http.Client
+}
+
+// NewDNSPodClient 创建 DNSPod API 客户端
+// baseURL 为空时使用官方 API 地址，httpClient 为空时使用默认的 HTTP 客户端
+func NewDNSPodClient(baseURL string, cfg config.DNSPodConfig, httpClient *http.Client) *DNSPodClient {
+	if baseURL == "" {
+		baseURL = dnspodBaseURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &DNSPodClient{
+		retryPolicy: defaultRetryPolicy(),
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		cfg:         cfg,
+		httpClient:  httpClient,
+	}
+}
+
+// dnspodValue DNSPod 响应中有时为字符串、有时为数字的字段
+type dnspodValue string
+
+// UnmarshalJSON 同时接受字符串和数字
+func (v *dnspodValue) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = dnspodValue(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*v = dnspodValue(n.String())
+	return nil
+}
+
+// int 转换为整数，无法转换时返回 0
+func (v dnspodValue) int() int {
+	n, _ := strconv.Atoi(string(v))
+	return n
+}
+
+// dnspodStatus DNSPod 响应中的状态
+type dnspodStatus struct {
+	Code    dnspodValue `json:"code"`
+	Message string      `json:"message"`
+}
+
+// dnspodRecord DNSPod 记录
+type dnspodRecord struct {
+	ID        dnspodValue `json:"id"`
+	Name      string      `json:"name"`
+	Type      string      `json:"type"`
+	Value     string      `json:"value"`
+	TTL       dnspodValue `json:"ttl"`
+	Line      string      `json:"line"`
+	Remark    string      `json:"remark"`
+	UpdatedOn string      `json:"updated_on"`
+}
+
+// dnspodClassify 根据 DNSPod 状态码确定错误类别
+func dnspodClassify(code int) error {
+	switch code {
+	case -1, -7, -8, 85:
+		// 登录失败、账号被封禁、登录失败次数过多、异地登录
+		return ErrAuth
+	case 6, 8, 15:
+		// 域名 ID 错误、记录 ID 错误、域名被禁止操作
+		return ErrNotFound
+	case -3:
+		// 调用过于频繁
+		return ErrRateLimited
+	case -99:
+		// 系统维护
+		return ErrServer
+	}
+	return ErrValidation
+}
+
+// call 调用 DNSPod API，result 为 nil 时只检查状态
+// 查询类请求在网络错误、限流和服务端错误时会重试
+func (c *DNSPodClient) call(action string, params url.Values, idempotent bool, result interface{}) error {
+	params.Set("login_token", c.cfg.LoginToken())
+	params.Set("format", "json")
+	params.Set("lang", "cn")
+	params.Set("error_on_empty", "no")
+
+	return c.retry(idempotent, func() error {
+		return c.send(action, params, result)
+	})
+}
+
+// send 发送一次请求
+func (c *DNSPodClient) send(action string, params url.Values, result interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+"/"+action, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// DNSPod 要求请求带有包含程序名的 User-Agent
+	req.Header.Set("User-Agent", "AutoCDN/1.0")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return &APIError{StatusCode: resp.StatusCode, Status: resp.Status, kind: classify(resp.StatusCode, nil)}
+	}
+
+	var envelope struct {
+		Status dnspodStatus `json:"status"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("解析 DNSPod 响应失败: %v", err)
+	}
+	// 10 为记录列表为空
+	if code := envelope.Status.Code.int(); code != 1 && code != 10 {
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Errors:     []APIMessage{{Code: code, Message: envelope.Status.Message}},
+			kind:       dnspodClassify(code),
+		}
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
+
+// Name 返回服务商名称
+func (c *DNSPodClient) Name() string {
+	return ProviderDNSPod
+}
+
+// Capabilities 返回 DNSPod 支持的功能
+func (c *DNSPodClient) Capabilities() Capabilities {
+	return Capabilities{MultiValue: true, Lines: true, AutoTTL: dnspodDefaultTTL}
+}
+
+// ListZones 获取账号下的全部域名，Zone ID 即为域名
+func (c *DNSPodClient) ListZones() ([]Zone, error) {
+	var zones []Zone
+	for offset := 0; ; offset += dnspodDomainsPerPage {
+		params := url.Values{}
+		params.Set("offset", strconv.Itoa(offset))
+		params.Set("length", strconv.Itoa(dnspodDomainsPerPage))
+
+		var result struct {
+			Info struct {
+				DomainTotal dnspodValue `json:"domain_total"`
+			} `json:"info"`
+			Domains []struct {
+				Name string `json:"name"`
+			} `json:"domains"`
+		}
+		if err := c.call("Domain.List", params, true, &result); err != nil {
+			return nil, fmt.Errorf("获取DNSPod域名列表失败: %w", err)
+		}
+		for _, domain := range result.Domains {
+			zones = append(zones, Zone{ID: domain.Name, Name: domain.Name})
+		}
+
+		if len(result.Domains) == 0 || len(zones) >= result.Info.DomainTotal.int() {
+			break
+		}
+	}
+	return zones, nil
+}
+
+// subDomain 将完整记录名转换为 DNSPod 使用的主机记录
+func subDomain(name, zone string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	zone = strings.ToLower(zone)
+	if name == zone {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+zone)
+}
+
+// fullName 将主机记录转换为完整记录名
+func fullName(sub, zone string) string {
+	if sub == "@" || sub == "" {
+		return zone
+	}
+	return sub + "." + zone
+}
+
+// ListRecords 获取域名中符合筛选条件的记录（自动翻页），默认线路的记录 Line 为空
+func (c *DNSPodClient) ListRecords(zoneID string, filter RecordFilter) ([]DNSRecord, error) {
+	var records []DNSRecord
+	for offset := 0; ; offset += dnspodRecordsPerPage {
+		params := url.Values{}
+		params.Set("domain", zoneID)
+		params.Set("offset", strconv.Itoa(offset))
+		params.Set("length", strconv.Itoa(dnspodRecordsPerPage))
+		if filter.Name != "" {
+			params.Set("sub_domain", subDomain(filter.Name, zoneID))
+		}
+		if filter.Type != "" {
+			params.Set("record_type", filter.Type)
+		}
+
+		var result struct {
+			Info struct {
+				RecordTotal dnspodValue `json:"record_total"`
+			} `json:"info"`
+			Records []dnspodRecord `json:"records"`
+		}
+		if err := c.call("Record.List", params, true, &result); err != nil {
+			return nil, fmt.Errorf("获取DNS记录失败: %w", err)
+		}
+
+		for _, r := range result.Records {
+			record := DNSRecord{
+				ID:      string(r.ID),
+				Name:    fullName(r.Name, zoneID),
+				Type:    r.Type,
+				Content: r.Value,
+				TTL:     r.TTL.int(),
+				Comment: r.Remark,
+				Line:    r.Line,
+			}
+			if record.Line == dnspodDefaultLine {
+				record.Line = ""
+			}
+			if t, err := time.ParseInLocation("2006-01-02 15:04:05", r.UpdatedOn, dnspodTimeZone); err == nil {
+				record.ModifiedOn = t
+			}
+			records = append(records, record)
+		}
+
+		if len(result.Records) == 0 || len(records) >= result.Info.RecordTotal.int() {
+			break
+		}
+	}
+	return records, nil
+}
+
+// UpsertRecord 创建（ID 为空时）或修改记录
+func (c *DNSPodClient) UpsertRecord(zoneID string, record DNSRecord) error {
+	line := record.Line
+	if line == "" {
+		line = dnspodDefaultLine
+	}
+
+	params := url.Values{}
+	params.Set("domain", zoneID)
+	params.Set("sub_domain", subDomain(record.Name, zoneID))
+	params.Set("record_type", record.Type)
+	params.Set("record_line", line)
+	params.Set("value", record.Content)
+	params.Set("ttl", strconv.Itoa(record.TTL))
+
+	if record.ID == "" {
+		if err := c.call("Record.Create", params, false, nil); err != nil {
+			return fmt.Errorf("创建DNS记录失败: %w", err)
+		}
+		return nil
+	}
+	params.Set("record_id", record.ID)
+	if err := c.call("Record.Modify", params, true, nil); err != nil {
+		return fmt.Errorf("更新DNS记录失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteRecord 删除记录
+func (c *DNSPodClient) DeleteRecord(zoneID string, record DNSRecord) error {
+	params := url.Values{}
+	params.Set("domain", zoneID)
+	params.Set("record_id", record.ID)
+	if err := c.call("Record.Remove", params, true, nil); err != nil {
+		return fmt.Errorf("删除DNS记录失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package cdn
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"AutoCDN/config"
+)
+
+// fakeDNSPod 简单的 DNSPod API 模拟服务
+type fakeDNSPod struct {
+	mu      sync.Mutex
+	token   string
+	domains []string
+	records map[string][]map[string]string // 域名 -> 记录
+	nextID  int
+}
+
+func (f *fakeDNSPod) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r.ParseForm()
+
+	reply := func(code, message string, extra map[string]interface{}) {
+		body := map[string]interface{}{"status": map[string]string{"code": code, "message": message}}
+		for k, v := range extra {
+			body[k] = v
+		}
+		json.NewEncoder(w).Encode(body)
+	}
+	if r.Form.Get("login_token") != f.token {
+		reply("-1", "登录失败，请检查账号和密码", nil)
+		return
+	}
+
+	domain := r.Form.Get("domain")
+	switch r.URL.Path {
+	case "/Domain.List":
+		var domains []map[string]interface{}
+		for i, name := range f.domains {
+			domains = append(domains, map[string]interface{}{"id": i + 1, "name": name})
+		}
+		reply("1", "Action completed successful", map[string]interface{}{
+			"domains": domains,
+			"info":    map[string]interface{}{"domain_total": len(f.domains)},
+		})
+	case "/Record.List":
+		var matched []map[string]string
+		for _, record := range f.records[domain] {
+			if sub := r.Form.Get("sub_domain"); sub != "" && record["name"] != sub {
+				continue
+			}
+			if t := r.Form.Get("record_type"); t != "" && record["type"] != t {
+				continue
+			}
+			matched = append(matched, record)
+		}
+		offset, _ := strconv.Atoi(r.Form.Get("offset"))
+		length, _ := strconv.Atoi(r.Form.Get("length"))
+		end := offset + length
+		if end > len(matched) {
+			end = len(matched)
+		}
+		if offset > end {
+			offset = end
+		}
+		reply("1", "Action completed successful", map[string]interface{}{
+			"records": matched[offset:end],
+			"info":    map[string]interface{}{"record_total": strconv.Itoa(len(matched))},
+		})
+	case "/Record.Create":
+		f.nextID++
+		record := map[string]string{
+			"id":         strconv.Itoa(f.nextID),
+			"name":       r.Form.Get("sub_domain"),
+			"type":       r.Form.Get("record_type"),
+			"value":      r.Form.Get("value"),
+			"ttl":        r.Form.Get("ttl"),
+			"line":       r.Form.Get("record_line"),
+			"updated_on": time.Now().Format("2006-01-02 15:04:05"),
+		}
+		f.records[domain] = append(f.records[domain], record)
+		reply("1", "Action completed successful", map[string]interface{}{"record": map[string]string{"id": record["id"]}})
+	case "/Record.Modify":
+		for _, record := range f.records[domain] {
+			if record["id"] == r.Form.Get("record_id") {
+				record["name"] = r.Form.Get("sub_domain")
+				record["value"] = r.Form.Get("value")
+				record["ttl"] = r.Form.Get("ttl")
+				record["line"] = r.Form.Get("record_line")
+				reply("1", "Action completed successful", nil)
+				return
+			}
+		}
+		reply("8", "记录ID错误", nil)
+	case "/Record.Remove":
+		records := f.records[domain]
+		for i, record := range records {
+			if record["id"] == r.Form.Get("record_id") {
+				f.records[domain] = append(records[:i], records[i+1:]...)
+				reply("1", "Action completed successful", nil)
+				return
+			}
+		}
+		reply("8", "记录ID错误", nil)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// values 返回指定主机记录和线路的记录值（已排序）
+func (f *fakeDNSPod) values(domain, sub, line string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var values []string
+	for _, record := range f.records[domain] {
+		if record["name"] == sub && record["line"] == line {
+			values = append(values, record["value"])
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+func newTestDNSPod(t *testing.T) (*DNSPodClient, *fakeDNSPod) {
+	t.Helper()
+	fake := &fakeDNSPod{token: "12345,secret", domains: []string{"example.com"}, records: make(map[string][]map[string]string)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := NewDNSPodClient(server.URL, config.DNSPodConfig{ID: "12345", Token: "secret"}, server.Client())
+	client.initialBackoff = time.Millisecond
+	return client, fake
+}
+
+func TestDNSPodPublishLines(t *testing.T) {
+	client, fake := newTestDNSPod(t)
+	fake.records["example.com"] = []map[string]string{
+		{"id": "100", "name": "cdn", "type": "A", "value": "9.9.9.9", "ttl": "600", "line": "默认"},
+		{"id": "101", "name": "cdn", "type": "A", "value": "8.8.8.8", "ttl": "600", "line": "电信"},
+		{"id": "102", "name": "www", "type": "A", "value": "7.7.7.7", "ttl": "600", "line": "默认"},
+		{"id": "103", "name": "_autocdn.cdn", "type": "TXT", "value": "autocdn-owner type=A", "ttl": "600", "line": "默认"},
+	}
+	fake.nextID = 200
+
+	publisher := NewPublisher(client, config.CloudflareConfig{
+		Domains: []config.DomainConfig{
+			{Name: "cdn.example.com", TTL: config.TTLAuto},
+			{Name: "cdn.example.com", TTL: 600, Line: "unicom"},
+		},
+		SnapshotDir: t.TempDir(),
+	})
+	if err := publisher.HandleDNSRecords(speedSet("1.1.1.1", "2.2.2.2")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+
+	if got := fake.values("example.com", "cdn", "默认"); !reflect.DeepEqual(got, []string{"1.1.1.1"}) {
+		t.Fatalf("默认线路的记录为 %v", got)
+	}
+	if got := fake.values("example.com", "cdn", "联通"); !reflect.DeepEqual(got, []string{"2.2.2.2"}) {
+		t.Fatalf("联通线路的记录为 %v", got)
+	}
+	// 未配置的线路和其他主机记录保持不变
+	if got := fake.values("example.com", "cdn", "电信"); !reflect.DeepEqual(got, []string{"8.8.8.8"}) {
+		t.Fatalf("电信线路的记录为 %v", got)
+	}
+	if got := fake.values("example.com", "www", "默认"); !reflect.DeepEqual(got, []string{"7.7.7.7"}) {
+		t.Fatalf("www 的记录为 %v", got)
+	}
+
+	// ttl: auto 按 DNSPod 默认 TTL 处理，再次发布时无需变更
+	plan, err := publisher.PlanDNSRecords(speedSet("1.1.1.1", "2.2.2.2"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if plan.Changes() != 0 {
+		t.Fatalf("期望无需变更，实际:\n%v", plan.Lines())
+	}
+
+	// 回滚后恢复各线路的原始记录
+	snapshots, err := publisher.ListSnapshots()
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("期望 1 个快照，实际 %d 个 (%v)", len(snapshots), err)
+	}
+	if err := publisher.RestoreSnapshot(snapshots[0].ID); err != nil {
+		t.Fatalf("恢复快照失败: %v", err)
+	}
+	if got := fake.values("example.com", "cdn", "默认"); !reflect.DeepEqual(got, []string{"9.9.9.9"}) {
+		t.Fatalf("恢复后默认线路的记录为 %v", got)
+	}
+	if got := fake.values("example.com", "cdn", "联通"); len(got) != 0 {
+		t.Fatalf("恢复后联通线路仍有记录 %v", got)
+	}
+}
+
+func TestDNSPodErrors(t *testing.T) {
+	client, fake := newTestDNSPod(t)
+
+	if err := client.DeleteRecord("example.com", DNSRecord{ID: "missing"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("期望记录不存在错误，实际 %v", err)
+	}
+
+	fake.token = "other"
+	if _, err := client.ListZones(); !errors.Is(err, ErrAuth) {
+		t.Fatalf("期望认证错误，实际 %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package cdn
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"AutoCDN/config"
+	"AutoCDN/utils"
+)
+
+// ErrPublishSkipped 发布前的质量保护未通过，本次没有修改任何记录
+var ErrPublishSkipped = errors.New("跳过发布")
+
+// cloudflareRanges Cloudflare 官方地址段，见 https://www.cloudflare.com/ips/
+var cloudflareRanges = []string{
+	"103.21.244.0/22",
+	"103.22.200.0/22",
+	"103.31.4.0/22",
+	"104.16.0.0/13",
+	"104.24.0.0/14",
+	"108.162.192.0/18",
+	"131.0.72.0/22",
+	"141.101.64.0/18",
+	"162.158.0.0/15",
+	"172.64.0.0/13",
+	"173.245.48.0/20",
+	"188.114.96.0/20",
+	"190.93.240.0/20",
+	"197.234.240.0/22",
+	"198.41.128.0/17",
+	"2400:cb00::/32",
+	"2405:8100::/32",
+	"2405:b500::/32",
+	"2606:4700::/32",
+	"2803:f800::/32",
+	"2a06:98c0::/29",
+	"2c0f:f248::/32",
+}
+
+// skipPublish 返回带原因的跳过发布错误
+func skipPublish(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrPublishSkipped, fmt.Sprintf(format, args...))
+}
+
+// parseRanges 解析官方地址段，未配置时使用内置的 Cloudflare 地址段
+func parseRanges(ranges []string) ([]*net.IPNet, error) {
+	if len(ranges) == 0 {
+		ranges = cloudflareRanges
+	}
+	nets := make([]*net.IPNet, 0, len(ranges))
+	for _, cidr := range ranges {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("无效的地址段 %s: %v", cidr, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// checkGuard 按延迟和速度限制筛选测速结果
+// 合格 IP 少于要求的数量，或有合格 IP 不属于官方地址段时返回 ErrPublishSkipped
+func checkGuard(g config.GuardConfig, results utils.DownloadSpeedSet) (utils.DownloadSpeedSet, error) {
+	var qualified utils.DownloadSpeedSet
+	var rejected []string
+	for _, data := range results {
+		ip := data.PingData.IP.String()
+		speed := data.DownloadSpeed / 1024 / 1024
+		switch {
+		case g.MaxDelay > 0 && data.Delay > time.Duration(g.MaxDelay)*time.Millisecond:
+			rejected = append(rejected, fmt.Sprintf("%s 延迟 %dms", ip, data.Delay.Milliseconds()))
+		case g.MinSpeed > 0 && speed < g.MinSpeed:
+			rejected = append(rejected, fmt.Sprintf("%s 速度 %.2f MB/s", ip, speed))
+		default:
+			qualified = append(qualified, data)
+		}
+	}
+
+	if len(qualified) < g.MinIPCount() {
+		return nil, skipPublish("合格 IP 只有 %d 个，要求至少 %d 个 (延迟上限 %dms，速度下限 %.2f MB/s)",
+			len(qualified), g.MinIPCount(), g.MaxDelay, g.MinSpeed)
+	}
+	if len(rejected) > 0 {
+		log.Printf("%d 个 IP 未达到发布要求，不参与发布: %s", len(rejected), strings.Join(rejected, "; "))
+	}
+
+	if g.CheckRanges {
+		nets, err := parseRanges(g.Ranges)
+		if err != nil {
+			return nil, err
+		}
+		var outside []string
+		for _, data := range qualified {
+			if !inRanges(nets, data.PingData.IP.IP) {
+				outside = append(outside, data.PingData.IP.String())
+			}
+		}
+		if len(outside) > 0 {
+			return nil, skipPublish("%d 个 IP 不属于 CDN 官方地址段: %s", len(outside), strings.Join(outside, ", "))
+		}
+	}
+	return qualified, nil
+}
+
+// inRanges 判断 IP 是否属于任一地址段
+func inRanges(nets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// averageMetrics 返回有测速数据的 IP 的平均延迟和平均速度 (MB/s)，以及参与统计的数量
+func averageMetrics(ips []string, metrics map[string]utils.CloudflareIPData) (time.Duration, float64, int) {
+	var delay time.Duration
+	var speed float64
+	count := 0
+	for _, ip := range ips {
+		data, ok := metrics[ip]
+		if !ok {
+			continue
+		}
+		delay += data.Delay
+		speed += data.DownloadSpeed / 1024 / 1024
+		count++
+	}
+	if count == 0 {
+		return 0, 0, 0
+	}
+	return delay / time.Duration(count), speed / float64(count), count
+}
+
+// checkDegradation 对比计划发布的 IP 与当前已发布的 IP，平均延迟或速度变差超过允许的幅度时返回 ErrPublishSkipped
+// 当前 IP 不在本次测速结果中时使用元数据记录中保存的测速数据，都没有时不检查
+func (p *Publisher) checkDegradation(plan *Plan, domains []config.DomainConfig, zoneOf map[string]string) error {
+	limit := p.cf.Guard.MaxDegradation
+	if limit <= 0 || plan.actionChanges() == 0 {
+		return nil
+	}
+
+	var wanted []string
+	for _, action := range plan.Actions {
+		if action.Action != ActionDelete && !contains(wanted, action.NewContent) {
+			wanted = append(wanted, action.NewContent)
+		}
+	}
+
+	baseline := make(map[string]utils.CloudflareIPData)
+	var current []string
+	for _, domain := range domains {
+		var missing bool
+		for _, ip := range recordContents(filterLine(plan.current[domain.Name], domain.Line)) {
+			if contains(current, ip) {
+				continue
+			}
+			current = append(current, ip)
+			if data, ok := plan.metrics[ip]; ok {
+				baseline[ip] = data
+			} else {
+				missing = true
+			}
+		}
+		if missing && p.cf.Metadata.Enabled {
+			for ip, data := range p.publishedMetrics(zoneOf[domain.Name], domain.Name, plan.Type) {
+				if _, ok := baseline[ip]; !ok && contains(current, ip) {
+					baseline[ip] = data
+				}
+			}
+		}
+	}
+
+	curDelay, curSpeed, n := averageMetrics(current, baseline)
+	if n == 0 {
+		log.Printf("当前发布的 %s 记录没有测速数据，跳过劣化检查", plan.Type)
+		return nil
+	}
+	newDelay, newSpeed, _ := averageMetrics(wanted, plan.metrics)
+
+	if curDelay > 0 && float64(newDelay) > float64(curDelay)*(1+limit/100) {
+		return skipPublish("新 IP 平均延迟 %dms 比当前 %dms 高 %.0f%%，超过允许的 %.0f%%",
+			newDelay.Milliseconds(), curDelay.Milliseconds(), (float64(newDelay)/float64(curDelay)-1)*100, limit)
+	}
+	if curSpeed > 0 && newSpeed < curSpeed*(1-limit/100) {
+		return skipPublish("新 IP 平均速度 %.2f MB/s 比当前 %.2f MB/s 低 %.0f%%，超过允许的 %.0f%%",
+			newSpeed, curSpeed, (1-newSpeed/curSpeed)*100, limit)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package cdn
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"AutoCDN/config"
+)
+
+func TestCheckGuard(t *testing.T) {
+	results := speedSet("104.16.0.1", "104.16.0.2", "104.16.0.3")
+
+	// 延迟上限筛掉后两个 IP
+	got, err := checkGuard(config.GuardConfig{MaxDelay: 105}, results)
+	if err != nil || len(got) != 1 || got[0].PingData.IP.String() != "104.16.0.1" {
+		t.Fatalf("筛选结果为 %v (%v)", got, err)
+	}
+	// 速度下限筛掉最后一个 IP
+	if got, err := checkGuard(config.GuardConfig{MinSpeed: 19}, results); err != nil || len(got) != 2 {
+		t.Fatalf("筛选结果为 %v (%v)", got, err)
+	}
+
+	_, err = checkGuard(config.GuardConfig{MaxDelay: 105, MinIPs: 2}, results)
+	if !errors.Is(err, ErrPublishSkipped) || !strings.Contains(err.Error(), "合格 IP 只有 1 个，要求至少 2 个") {
+		t.Fatalf("期望跳过发布，实际 %v", err)
+	}
+
+	// 地址段检查：默认使用 Cloudflare 地址段，也可以自定义
+	_, err = checkGuard(config.GuardConfig{CheckRanges: true}, speedSet("104.16.0.1", "1.1.1.1", "2606:4700::1"))
+	if !errors.Is(err, ErrPublishSkipped) || !strings.Contains(err.Error(), "1 个 IP 不属于 CDN 官方地址段: 1.1.1.1") {
+		t.Fatalf("期望跳过发布，实际 %v", err)
+	}
+	if _, err := checkGuard(config.GuardConfig{CheckRanges: true, Ranges: []string{"1.1.1.0/24"}}, speedSet("1.1.1.1")); err != nil {
+		t.Fatalf("自定义地址段检查失败: %v", err)
+	}
+	if _, err := checkGuard(config.GuardConfig{CheckRanges: true, Ranges: []string{"bad"}}, speedSet("1.1.1.1")); err == nil || errors.Is(err, ErrPublishSkipped) {
+		t.Fatalf("期望地址段无效的错误，实际 %v", err)
+	}
+}
+
+func TestPublishGuardDegradation(t *testing.T) {
+	publisher, server := newTestPublisher(t, config.CloudflareConfig{
+		Domains:  []config.DomainConfig{{Name: "a.example.com"}},
+		Metadata: config.MetadataConfig{Enabled: true},
+		Guard:    config.GuardConfig{MaxDegradation: 20, CheckRanges: true},
+	})
+	if err := publisher.HandleDNSRecords(speedSet("104.16.0.1")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+
+	// 当前 IP 不在本次结果中，使用元数据记录中的延迟 100ms 作为基准
+	results := speedSet("104.16.0.2")
+	results[0].Delay = 200 * time.Millisecond
+	err := publisher.HandleDNSRecords(results)
+	if !errors.Is(err, ErrPublishSkipped) || !strings.Contains(err.Error(), "平均延迟 200ms 比当前 100ms 高 100%") {
+		t.Fatalf("期望跳过发布，实际 %v", err)
+	}
+	if got := server.Contents("zone1", "a.example.com", "A"); !reflect.DeepEqual(got, []string{"104.16.0.1"}) {
+		t.Fatalf("跳过发布后记录为 %v", got)
+	}
+
+	// 速度下降超过允许幅度
+	results = speedSet("104.16.0.2")
+	results[0].DownloadSpeed = 10 * 1024 * 1024
+	if err := publisher.HandleDNSRecords(results); !errors.Is(err, ErrPublishSkipped) || !strings.Contains(err.Error(), "平均速度 10.00 MB/s") {
+		t.Fatalf("期望跳过发布，实际 %v", err)
+	}
+
+	// 变差幅度在允许范围内时正常发布
+	results = speedSet("104.16.0.2")
+	results[0].Delay = 110 * time.Millisecond
+	if err := publisher.HandleDNSRecords(results); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if got := server.Contents("zone1", "a.example.com", "A"); !reflect.DeepEqual(got, []string{"104.16.0.2"}) {
+		t.Fatalf("发布后记录为 %v", got)
+	}
+}
@@ -0,0 +1,273 @@
+package cdn
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// svcParamKeys SvcParamKey 名称与编号 (RFC 9460)，输出时参数按编号排序
+var svcParamKeys = map[string]int{
+	"mandatory":       0,
+	"alpn":            1,
+	"no-default-alpn": 2,
+	"port":            3,
+	"ipv4hint":        4,
+	"ech":             5,
+	"ipv6hint":        6,
+}
+
+// svcParamNumber 返回参数的编号，未知名称排在最后
+func svcParamNumber(key string) int {
+	if n, ok := svcParamKeys[key]; ok {
+		return n
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(key, "key")); err == nil && strings.HasPrefix(key, "key") {
+		return n
+	}
+	return 1 << 16
+}
+
+// svcParam SVCB 记录中的一个参数，如 alpn="h3,h2"；no-default-alpn 等参数没有值
+type svcParam struct {
+	Key      string
+	Value    string
+	HasValue bool
+}
+
+// svcbRecord HTTPS / SVCB 记录的内容
+type svcbRecord struct {
+	Priority int
+	Target   string
+	Params   []svcParam
+}
+
+// svcbData Cloudflare 创建 HTTPS / SVCB 记录时使用的 data 字段
+type svcbData struct {
+	Priority int    `json:"priority"`
+	Target   string `json:"target"`
+	Value    string `json:"value"`
+}
+
+// splitSVCBFields 按空白拆分记录内容，引号内的空白和反斜杠转义的字符不拆分
+func splitSVCBFields(content string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inField, quoted, escaped := false, false, false
+	for _, r := range content {
+		switch {
+		case escaped:
+			field.WriteRune('\\')
+			field.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped, inField = true, true
+		case r == '"':
+			quoted, inField = !quoted, true
+			field.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if quoted || escaped {
+		return nil, fmt.Errorf("记录内容的引号或转义不完整: %s", content)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+// parseSVCB 解析展示格式的 HTTPS / SVCB 记录内容，如 1 . alpn="h3,h2" ipv4hint="104.16.1.1"
+func parseSVCB(content string) (*svcbRecord, error) {
+	fields, err := splitSVCBFields(content)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("无效的 HTTPS 记录内容: %s", content)
+	}
+	priority, err := strconv.Atoi(fields[0])
+	if err != nil || priority < 0 || priority > 65535 {
+		return nil, fmt.Errorf("无效的 HTTPS 记录优先级: %s", fields[0])
+	}
+
+	record := &svcbRecord{Priority: priority, Target: fields[1]}
+	for _, field := range fields[2:] {
+		key, value, hasValue := strings.Cut(field, "=")
+		if key == "" {
+			return nil, fmt.Errorf("无效的 HTTPS 记录参数: %s", field)
+		}
+		if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+			value = value[1 : len(value)-1]
+		}
+		record.Params = append(record.Params, svcParam{Key: strings.ToLower(key), Value: value, HasValue: hasValue})
+	}
+	record.sortParams()
+	return record, nil
+}
+
+// sortParams 按编号排序参数
+func (r *svcbRecord) sortParams() {
+	sort.SliceStable(r.Params, func(i, j int) bool {
+		return svcParamNumber(r.Params[i].Key) < svcParamNumber(r.Params[j].Key)
+	})
+}
+
+// set 设置参数的值，参数不存在时按编号顺序插入
+func (r *svcbRecord) set(key, value string) {
+	for i, param := range r.Params {
+		if param.Key == key {
+			r.Params[i] = svcParam{Key: key, Value: value, HasValue: true}
+			return
+		}
+	}
+	r.Params = append(r.Params, svcParam{Key: key, Value: value, HasValue: true})
+	r.sortParams()
+}
+
+// remove 删除参数
+func (r *svcbRecord) remove(key string) {
+	for i, param := range r.Params {
+		if param.Key == key {
+			r.Params = append(r.Params[:i], r.Params[i+1:]...)
+			return
+		}
+	}
+}
+
+// value 返回参数部分的展示格式，参数值统一加引号，值中的转义原样保留
+func (r *svcbRecord) value() string {
+	parts := make([]string, 0, len(r.Params))
+	for _, param := range r.Params {
+		if !param.HasValue {
+			parts = append(parts, param.Key)
+			continue
+		}
+		parts = append(parts, param.Key+`="`+param.Value+`"`)
+	}
+	return strings.Join(parts, " ")
+}
+
+// String 返回记录内容的展示格式，相同含义的记录输出相同
+func (r *svcbRecord) String() string {
+	if len(r.Params) == 0 {
+		return fmt.Sprintf("%d %s", r.Priority, r.Target)
+	}
+	return fmt.Sprintf("%d %s %s", r.Priority, r.Target, r.value())
+}
+
+// data 返回 Cloudflare API 的 data 字段
+func (r *svcbRecord) data() *svcbData {
+	return &svcbData{Priority: r.Priority, Target: r.Target, Value: r.value()}
+}
+
+// isSVCBType 判断记录类型是否为 HTTPS / SVCB
+func isSVCBType(recordType string) bool {
+	return recordType == "HTTPS" || recordType == "SVCB"
+}
+
+// buildHTTPSPlan 根据 A / AAAA 记录的计划计算各域名 HTTPS 记录的变更
+// 本次地址族的 hint 改为计划执行后该域名指向的 IP，另一地址族的 hint 和其他参数保持不变
+// 每个域名只维护第一条服务模式（优先级大于 0）的记录，只有别名模式记录的域名跳过
+func (p *Publisher) buildHTTPSPlan(address *Plan, zoneOf map[string]string) (*Plan, error) {
+	hintKey := "ipv4hint"
+	if address.Type == "AAAA" {
+		hintKey = "ipv6hint"
+	}
+
+	var names []string
+	hints := make(map[string][]string)
+	actionOf := make(map[string]PlanAction)
+	for _, action := range address.Actions {
+		if _, ok := actionOf[action.Name]; !ok {
+			names = append(names, action.Name)
+			actionOf[action.Name] = action
+		}
+		if action.Action != ActionDelete && !contains(hints[action.Name], action.NewContent) {
+			hints[action.Name] = append(hints[action.Name], action.NewContent)
+		}
+	}
+
+	groups, err := p.listRecordGroups("HTTPS", zoneOf)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := p.cf.HTTPSRecord
+	plan := &Plan{Type: "HTTPS", current: groups}
+	for _, name := range names {
+		// HTTPS 记录不能开启代理
+		domain := actionOf[name].domain
+		domain.Proxied = nil
+
+		var current *DNSRecord
+		var record *svcbRecord
+		for i, existing := range groups[name] {
+			if parsed, err := parseSVCB(existing.Content); err == nil && parsed.Priority > 0 {
+				current, record = &groups[name][i], parsed
+				break
+			}
+		}
+		if current == nil && len(groups[name]) > 0 {
+			log.Printf("%s 只有别名模式或无法解析的 HTTPS 记录，跳过", name)
+			continue
+		}
+		if current != nil {
+			reason, err := p.checkOwnership(actionOf[name].ZoneID, "HTTPS", domain, []DNSRecord{*current})
+			if err != nil {
+				return nil, err
+			}
+			if reason != "" {
+				log.Print(reason)
+				plan.Skipped = append(plan.Skipped, reason)
+				continue
+			}
+		}
+
+		action := PlanAction{ZoneID: actionOf[name].ZoneID, Name: name, Type: "HTTPS", domain: domain}
+		original := ""
+		if current == nil {
+			record = &svcbRecord{Priority: cfg.RecordPriority(), Target: cfg.RecordTarget()}
+			action.Action = ActionCreate
+		} else {
+			original = record.String()
+			action.RecordID = current.ID
+			action.OldContent = current.Content
+		}
+
+		if len(cfg.ALPN) > 0 {
+			record.set("alpn", strings.Join(cfg.ALPN, ","))
+		}
+		if len(hints[name]) > 0 {
+			record.set(hintKey, strings.Join(hints[name], ","))
+		} else {
+			record.remove(hintKey)
+		}
+		action.NewContent = record.String()
+
+		if current != nil {
+			// 按规范化后的内容比较，避免服务商返回的格式差异造成无效更新
+			normalized := *current
+			normalized.Content = original
+			if recordMatches(normalized, action.NewContent, domain) {
+				action.Action = ActionNoop
+				action.NewContent = current.Content
+			} else {
+				action.Action = ActionUpdate
+			}
+		}
+		plan.Actions = append(plan.Actions, action)
+	}
+	return plan, nil
+}
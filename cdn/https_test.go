@@ -0,0 +1,90 @@
+package cdn
+
+import (
+	"reflect"
+	"testing"
+
+	"AutoCDN/cdn/cftest"
+	"AutoCDN/config"
+)
+
+func TestParseSVCB(t *testing.T) {
+	record, err := parseSVCB(`1 .  ipv6hint=2606:4700::1 no-default-alpn alpn="h3,h2" key65000="a b" ipv4hint="1.1.1.1,2.2.2.2"`)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	want := `1 . alpn="h3,h2" no-default-alpn ipv4hint="1.1.1.1,2.2.2.2" ipv6hint="2606:4700::1" key65000="a b"`
+	if got := record.String(); got != want {
+		t.Fatalf("规范化后为 %s，期望 %s", got, want)
+	}
+	if data := record.data(); data.Priority != 1 || data.Target != "." || data.Value != want[4:] {
+		t.Fatalf("data 为 %+v", data)
+	}
+
+	for _, content := range []string{"", "1", "x .", `1 . alpn="h2`, "1 . =h2"} {
+		if _, err := parseSVCB(content); err == nil {
+			t.Fatalf("期望 %q 解析失败", content)
+		}
+	}
+}
+
+func TestPublishHTTPSRecords(t *testing.T) {
+	proxied := true
+	publisher, server := newTestPublisher(t, config.CloudflareConfig{
+		Domains:     []config.DomainConfig{{Name: "a.example.com", TTL: 300}, {Name: "b.example.com", Proxied: &proxied}, {Name: "c.example.com"}},
+		HTTPSRecord: config.HTTPSRecordConfig{Enabled: true, ALPN: []string{"h3", "h2"}},
+	})
+	// a 已有记录，ipv6hint 和 ech 需要保留；c 只有别名模式记录，不修改
+	server.AddRecord("zone1", cftest.Record{Name: "a.example.com", Type: "A", Content: "9.9.9.9", TTL: 300, Comment: ownerMarker})
+	server.AddRecord("zone1", cftest.Record{Name: "a.example.com", Type: "HTTPS", Content: `1 . alpn="h2" ipv6hint="2606:4700::1" ech="AEX+"`, TTL: 300, Comment: ownerMarker})
+	server.AddRecord("zone1", cftest.Record{Name: "c.example.com", Type: "HTTPS", Content: "0 cdn.example.net.", TTL: 1})
+
+	if err := publisher.HandleDNSRecords(speedSet("1.1.1.1", "2.2.2.2")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"a.example.com": `1 . alpn="h3,h2" ipv4hint="1.1.1.1" ech="AEX+" ipv6hint="2606:4700::1"`,
+		"b.example.com": `1 . alpn="h3,h2" ipv4hint="2.2.2.2"`,
+		"c.example.com": "0 cdn.example.net.",
+	} {
+		if got := server.Contents("zone1", name, "HTTPS"); !reflect.DeepEqual(got, []string{want}) {
+			t.Fatalf("%s 的 HTTPS 记录为 %v，期望 %s", name, got, want)
+		}
+	}
+	for _, record := range server.Records("zone1") {
+		if record.Type == "HTTPS" && record.Name == "b.example.com" && record.Proxied {
+			t.Fatal("HTTPS 记录不应开启代理")
+		}
+	}
+
+	// 再次计划时无需变更
+	plan, err := publisher.PlanDNSRecords(speedSet("1.1.1.1", "2.2.2.2"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if plan.Changes() != 0 {
+		t.Fatalf("期望无需变更，实际:\n%v", plan.Lines())
+	}
+
+	// 地址记录和 HTTPS 记录分别保存快照，恢复 HTTPS 快照后 a 回到原内容，b 被删除
+	snapshots, err := publisher.ListSnapshots()
+	if err != nil || len(snapshots) != 2 {
+		t.Fatalf("期望 2 个快照，实际 %d 个 (%v)", len(snapshots), err)
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.Type != "HTTPS" {
+			continue
+		}
+		if err := publisher.RestoreSnapshot(snapshot.ID); err != nil {
+			t.Fatalf("恢复快照失败: %v", err)
+		}
+	}
+	// 恢复时按 data 提交，参数为规范化后的顺序
+	if got := server.Contents("zone1", "a.example.com", "HTTPS"); !reflect.DeepEqual(got, []string{`1 . alpn="h2" ech="AEX+" ipv6hint="2606:4700::1"`}) {
+		t.Fatalf("恢复后 a 的 HTTPS 记录为 %v", got)
+	}
+	if got := server.Contents("zone1", "b.example.com", "HTTPS"); len(got) != 0 {
+		t.Fatalf("恢复后 b 的 HTTPS 记录为 %v", got)
+	}
+}
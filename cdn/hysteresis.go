@@ -0,0 +1,92 @@
+package cdn
+
+import (
+	"log"
+
+	"AutoCDN/config"
+	"AutoCDN/utils"
+)
+
+// indexResults 按 IP 索引测速结果
+func indexResults(results utils.DownloadSpeedSet) map[string]utils.CloudflareIPData {
+	index := make(map[string]utils.CloudflareIPData, len(results))
+	for _, data := range results {
+		index[data.PingData.IP.String()] = data
+	}
+	return index
+}
+
+// recordContents 返回记录的内容列表
+func recordContents(records []DNSRecord) []string {
+	contents := make([]string, 0, len(records))
+	for _, record := range records {
+		contents = append(contents, record.Content)
+	}
+	return contents
+}
+
+// beats 判断候选 IP 是否在所有已设置的指标上领先当前 IP 至少对应的幅度
+// 两个幅度都为 0 时，只要当前 IP 仍在结果中就不替换
+func beats(candidate, current utils.CloudflareIPData, h config.HysteresisConfig) bool {
+	if h.DelayMargin <= 0 && h.SpeedMargin <= 0 {
+		return false
+	}
+	if h.DelayMargin > 0 {
+		improvement := current.Delay - candidate.Delay
+		if improvement.Milliseconds() < int64(h.DelayMargin) {
+			return false
+		}
+	}
+	if h.SpeedMargin > 0 {
+		improvement := (candidate.DownloadSpeed - current.DownloadSpeed) / 1024 / 1024
+		if improvement < h.SpeedMargin {
+			return false
+		}
+	}
+	return true
+}
+
+// keepCurrentIPs 对目标 IP 列表应用防抖
+// 目标中尚未发布的候选 IP，如果不能明显胜过某个仍在本次结果中的当前 IP，则由该当前 IP 代替
+func keepCurrentIPs(name string, current, wanted []string, metrics map[string]utils.CloudflareIPData, h config.HysteresisConfig) []string {
+	result := append([]string(nil), wanted...)
+
+	inResult := make(map[string]bool, len(result))
+	for _, ip := range result {
+		inResult[ip] = true
+	}
+	published := make(map[string]bool, len(current))
+	for _, ip := range current {
+		published[ip] = true
+	}
+
+	for i, ip := range result {
+		if published[ip] {
+			continue
+		}
+		candidate, ok := metrics[ip]
+		if !ok {
+			continue
+		}
+		for _, cur := range current {
+			curData, tested := metrics[cur]
+			if !tested || inResult[cur] {
+				continue
+			}
+			if !beats(candidate, curData, h) {
+				log.Printf("防抖: %s 保持 %s (候选 %s 优势不足)", name, cur, ip)
+				delete(inResult, ip)
+				inResult[cur] = true
+				result[i] = cur
+				break
+			}
+		}
+	}
+
+	for _, cur := range current {
+		if _, tested := metrics[cur]; !tested && !inResult[cur] {
+			log.Printf("防抖: %s 当前 IP %s 不在本次测速结果中，允许替换", name, cur)
+		}
+	}
+	return result
+}
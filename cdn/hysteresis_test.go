@@ -0,0 +1,124 @@
+package cdn
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"AutoCDN/config"
+	"AutoCDN/utils"
+)
+
+// ipMetrics 生成指定延迟 (ms) 和下载速度 (MB/s) 的测速结果
+func ipMetrics(ip string, delay int, speed float64) utils.CloudflareIPData {
+	return utils.CloudflareIPData{
+		PingData: &utils.PingData{
+			IP:       &net.IPAddr{IP: net.ParseIP(ip)},
+			Sended:   4,
+			Received: 4,
+			Delay:    time.Duration(delay) * time.Millisecond,
+		},
+		DownloadSpeed: speed * 1024 * 1024,
+	}
+}
+
+func TestKeepCurrentIPs(t *testing.T) {
+	margins := config.HysteresisConfig{Enabled: true, DelayMargin: 20, SpeedMargin: 2}
+	tests := []struct {
+		name    string
+		h       config.HysteresisConfig
+		current []string
+		wanted  []string
+		metrics []utils.CloudflareIPData
+		want    []string
+	}{
+		{
+			name:    "优势在幅度内时保持当前 IP",
+			h:       margins,
+			current: []string{"1.1.1.1"},
+			wanted:  []string{"2.2.2.2"},
+			metrics: []utils.CloudflareIPData{ipMetrics("2.2.2.2", 100, 20), ipMetrics("1.1.1.1", 110, 19)},
+			want:    []string{"1.1.1.1"},
+		},
+		{
+			name:    "延迟和速度都超过幅度时替换",
+			h:       margins,
+			current: []string{"1.1.1.1"},
+			wanted:  []string{"2.2.2.2"},
+			metrics: []utils.CloudflareIPData{ipMetrics("2.2.2.2", 80, 21), ipMetrics("1.1.1.1", 110, 18)},
+			want:    []string{"2.2.2.2"},
+		},
+		{
+			name:    "只有延迟超过幅度时保持当前 IP",
+			h:       margins,
+			current: []string{"1.1.1.1"},
+			wanted:  []string{"2.2.2.2"},
+			metrics: []utils.CloudflareIPData{ipMetrics("2.2.2.2", 80, 20), ipMetrics("1.1.1.1", 110, 19)},
+			want:    []string{"1.1.1.1"},
+		},
+		{
+			name:    "只设置延迟幅度时不比较速度",
+			h:       config.HysteresisConfig{Enabled: true, DelayMargin: 20},
+			current: []string{"1.1.1.1"},
+			wanted:  []string{"2.2.2.2"},
+			metrics: []utils.CloudflareIPData{ipMetrics("2.2.2.2", 80, 10), ipMetrics("1.1.1.1", 110, 19)},
+			want:    []string{"2.2.2.2"},
+		},
+		{
+			name:    "两个幅度都为 0 时当前 IP 仍在结果中就不替换",
+			h:       config.HysteresisConfig{Enabled: true},
+			current: []string{"1.1.1.1"},
+			wanted:  []string{"2.2.2.2"},
+			metrics: []utils.CloudflareIPData{ipMetrics("2.2.2.2", 50, 30), ipMetrics("1.1.1.1", 200, 5)},
+			want:    []string{"1.1.1.1"},
+		},
+		{
+			name:    "当前 IP 不在测速结果中时替换",
+			h:       margins,
+			current: []string{"1.1.1.1"},
+			wanted:  []string{"2.2.2.2"},
+			metrics: []utils.CloudflareIPData{ipMetrics("2.2.2.2", 100, 20)},
+			want:    []string{"2.2.2.2"},
+		},
+		{
+			name:    "当前 IP 已在目标中时不变",
+			h:       margins,
+			current: []string{"1.1.1.1"},
+			wanted:  []string{"1.1.1.1", "2.2.2.2"},
+			metrics: []utils.CloudflareIPData{ipMetrics("1.1.1.1", 100, 20), ipMetrics("2.2.2.2", 101, 19)},
+			want:    []string{"1.1.1.1", "2.2.2.2"},
+		},
+		{
+			name:    "记录集保持多个当前 IP",
+			h:       margins,
+			current: []string{"1.1.1.1", "3.3.3.3"},
+			wanted:  []string{"2.2.2.2", "4.4.4.4"},
+			metrics: []utils.CloudflareIPData{
+				ipMetrics("2.2.2.2", 100, 20), ipMetrics("4.4.4.4", 101, 19),
+				ipMetrics("1.1.1.1", 105, 19), ipMetrics("3.3.3.3", 110, 18),
+			},
+			want: []string{"1.1.1.1", "3.3.3.3"},
+		},
+		{
+			name:    "记录集只替换优势足够的 IP",
+			h:       config.HysteresisConfig{Enabled: true, DelayMargin: 20},
+			current: []string{"1.1.1.1", "3.3.3.3"},
+			wanted:  []string{"2.2.2.2", "4.4.4.4"},
+			metrics: []utils.CloudflareIPData{
+				ipMetrics("2.2.2.2", 100, 20), ipMetrics("4.4.4.4", 101, 19),
+				ipMetrics("1.1.1.1", 200, 10), ipMetrics("3.3.3.3", 105, 18),
+			},
+			want: []string{"3.3.3.3", "4.4.4.4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := indexResults(tt.metrics)
+			if got := keepCurrentIPs("cdn.example.com", tt.current, tt.wanted, metrics, tt.h); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("期望 %v，实际 %v", tt.want, got)
+			}
+		})
+	}
+}
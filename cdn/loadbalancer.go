@@ -0,0 +1,248 @@
+package cdn
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"AutoCDN/config"
+	"AutoCDN/utils"
+)
+
+// poolOriginPrefix AutoCDN 管理的源站名称前缀
+const poolOriginPrefix = "autocdn-"
+
+// PoolOrigin 负载均衡地址池中的源站
+// 未列出的字段（如 header、port、virtual_network_id）原样保留，更新时一并提交
+type PoolOrigin struct {
+	Name    string  `json:"name"`
+	Address string  `json:"address"`
+	Enabled bool    `json:"enabled"`
+	Weight  float64 `json:"weight"`
+
+	raw map[string]json.RawMessage
+}
+
+// UnmarshalJSON 解析源站并保留全部原始字段
+func (o *PoolOrigin) UnmarshalJSON(data []byte) error {
+	type plain PoolOrigin
+	if err := json.Unmarshal(data, (*plain)(o)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &o.raw)
+}
+
+// MarshalJSON 在原始字段的基础上写入修改后的字段
+func (o PoolOrigin) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]interface{}, len(o.raw)+4)
+	for key, value := range o.raw {
+		fields[key] = value
+	}
+	fields["name"] = o.Name
+	fields["address"] = o.Address
+	fields["enabled"] = o.Enabled
+	fields["weight"] = o.Weight
+	return json.Marshal(fields)
+}
+
+// Pool 负载均衡地址池
+type Pool struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Origins []PoolOrigin `json:"origins"`
+}
+
+// PoolPlan 地址池源站的变更计划
+type PoolPlan struct {
+	AccountID string
+	Pool      *Pool
+	Origins   []PoolOrigin // 变更后的全部源站
+	Changes   []string     // 变更描述，为空表示无需变更
+}
+
+// Print 在命令行中打印计划
+func (p *PoolPlan) Print() {
+	fmt.Printf("负载均衡地址池变更计划 (%s): %d 项变更\n", p.Pool.Name, len(p.Changes))
+	for _, line := range p.Changes {
+		fmt.Println("  " + line)
+	}
+}
+
+// GetPool 获取负载均衡地址池
+func (c *Client) GetPool(accountID, poolID string) (*Pool, error) {
+	resp, err := c.doRequest("GET", fmt.Sprintf("/accounts/%s/load_balancers/pools/%s", accountID, poolID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取负载均衡地址池失败: %w", err)
+	}
+	var pool Pool
+	if err := json.Unmarshal(resp.Result, &pool); err != nil {
+		return nil, err
+	}
+	return &pool, nil
+}
+
+// UpdatePoolOrigins 替换地址池的全部源站
+func (c *Client) UpdatePoolOrigins(accountID, poolID string, origins []PoolOrigin) error {
+	body := map[string]interface{}{"origins": origins}
+	if _, err := c.doRequest("PATCH", fmt.Sprintf("/accounts/%s/load_balancers/pools/%s", accountID, poolID), body); err != nil {
+		return fmt.Errorf("更新负载均衡地址池失败: %w", err)
+	}
+	return nil
+}
+
+// PlanPool 读取地址池并计算将测速结果前 N 个 IP 发布为源站所需的变更
+// 发布前的质量保护同样生效，不满足时返回 ErrPublishSkipped
+func (c *Client) PlanPool(lb config.LoadBalancerConfig, results utils.DownloadSpeedSet) (*PoolPlan, error) {
+	if len(results) == 0 {
+		return nil, fmt.Errorf("没有可用的测速数据")
+	}
+	results, err := checkGuard(c.cf.Guard, results)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := c.GetPool(lb.AccountID, lb.PoolID)
+	if err != nil {
+		return nil, err
+	}
+	origins, changes := planPoolOrigins(pool.Origins, results, lb.OriginCount())
+	return &PoolPlan{AccountID: lb.AccountID, Pool: pool, Origins: origins, Changes: changes}, nil
+}
+
+// ApplyPoolPlan 按计划更新地址池，无变更时不发送请求
+func (c *Client) ApplyPoolPlan(plan *PoolPlan) error {
+	if len(plan.Changes) == 0 {
+		log.Printf("负载均衡地址池 %s 无需变更", plan.Pool.Name)
+		return nil
+	}
+	if err := c.UpdatePoolOrigins(plan.AccountID, plan.Pool.ID, plan.Origins); err != nil {
+		return err
+	}
+	log.Printf("已更新负载均衡地址池 %s (%d 项变更)", plan.Pool.Name, len(plan.Changes))
+	return nil
+}
+
+// PublishPool 将测速结果发布到负载均衡地址池
+func (c *Client) PublishPool(lb config.LoadBalancerConfig, results utils.DownloadSpeedSet) error {
+	plan, err := c.PlanPool(lb, results)
+	if err != nil {
+		return err
+	}
+	plan.Print()
+	return c.ApplyPoolPlan(plan)
+}
+
+// poolWeights 根据下载速度计算源站权重：最快的 IP 为 1，其余按速度比例，保留两位小数且不低于 0.01
+// 未进行下载测速时权重均为 1
+func poolWeights(results utils.DownloadSpeedSet) []float64 {
+	var fastest float64
+	for _, data := range results {
+		fastest = math.Max(fastest, data.DownloadSpeed)
+	}
+	weights := make([]float64, len(results))
+	for i, data := range results {
+		if fastest <= 0 {
+			weights[i] = 1
+			continue
+		}
+		weights[i] = math.Max(math.Round(data.DownloadSpeed/fastest*100)/100, 0.01)
+	}
+	return weights
+}
+
+// isIPv6 判断地址是否为 IPv6
+func isIPv6(address string) bool {
+	ip := net.ParseIP(address)
+	return ip != nil && ip.To4() == nil
+}
+
+// planPoolOrigins 计算变更后的源站列表
+// 只管理与本次结果地址族相同、名称以 autocdn- 开头的源站：已是目标 IP 的源站只更新权重，
+// 其余目标 IP 优先复用不再需要的源站，不足时新增源站，多余的源站停用
+func planPoolOrigins(current []PoolOrigin, results utils.DownloadSpeedSet, count int) ([]PoolOrigin, []string) {
+	if count > len(results) {
+		count = len(results)
+	}
+	results = results[:count]
+	weights := poolWeights(results)
+	weightOf := make(map[string]float64, count)
+	ips := make([]string, 0, count)
+	for i, data := range results {
+		ip := data.PingData.IP.String()
+		ips = append(ips, ip)
+		weightOf[ip] = weights[i]
+	}
+	v6 := isIPv6(ips[0])
+
+	origins := append([]PoolOrigin(nil), current...)
+	var managed []int
+	next := 1
+	for i, origin := range origins {
+		if !strings.HasPrefix(origin.Name, poolOriginPrefix) {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimPrefix(origin.Name, poolOriginPrefix)); err == nil && n >= next {
+			next = n + 1
+		}
+		if isIPv6(origin.Address) == v6 {
+			managed = append(managed, i)
+		}
+	}
+
+	var changes []string
+	set := func(i int, address string, weight float64, enabled bool) {
+		old := origins[i]
+		origins[i].Address = address
+		origins[i].Weight = weight
+		origins[i].Enabled = enabled
+		switch {
+		case old.Address != address:
+			changes = append(changes, fmt.Sprintf("~ 源站 %s: %s -> %s (权重 %.2f)", old.Name, old.Address, address, weight))
+		case old.Enabled && !enabled:
+			changes = append(changes, fmt.Sprintf("- 停用源站 %s: %s", old.Name, address))
+		case !old.Enabled && enabled:
+			changes = append(changes, fmt.Sprintf("+ 启用源站 %s: %s (权重 %.2f)", old.Name, address, weight))
+		case enabled && math.Abs(old.Weight-weight) >= 0.005:
+			changes = append(changes, fmt.Sprintf("~ 源站 %s: %s 权重 %.2f -> %.2f", old.Name, address, old.Weight, weight))
+		}
+	}
+
+	// 已是目标 IP 的源站保持不变，只更新权重
+	assigned := make(map[string]bool, count)
+	var free []int
+	for _, i := range managed {
+		address := origins[i].Address
+		if weight, ok := weightOf[address]; ok && !assigned[address] {
+			assigned[address] = true
+			set(i, address, weight, true)
+			continue
+		}
+		free = append(free, i)
+	}
+
+	// 其余目标 IP 复用空闲的源站或新增源站
+	for _, ip := range ips {
+		if assigned[ip] {
+			continue
+		}
+		assigned[ip] = true
+		if len(free) > 0 {
+			set(free[0], ip, weightOf[ip], true)
+			free = free[1:]
+			continue
+		}
+		name := fmt.Sprintf("%s%d", poolOriginPrefix, next)
+		next++
+		origins = append(origins, PoolOrigin{Name: name, Address: ip, Enabled: true, Weight: weightOf[ip]})
+		changes = append(changes, fmt.Sprintf("+ 新增源站 %s: %s (权重 %.2f)", name, ip, weightOf[ip]))
+	}
+
+	// 不再需要的源站停用，保留地址和权重以便手动恢复
+	for _, i := range free {
+		set(i, origins[i].Address, origins[i].Weight, false)
+	}
+	return origins, changes
+}
@@ -0,0 +1,109 @@
+package cdn
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"AutoCDN/cdn/cftest"
+	"AutoCDN/config"
+)
+
+// patchCount 统计 PATCH 请求次数
+func patchCount(server *cftest.Server) int {
+	n := 0
+	for _, req := range server.Requests() {
+		if req.Method == "PATCH" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestPublishPool(t *testing.T) {
+	client, server := newTestClient(t, config.CloudflareConfig{})
+	header := map[string]interface{}{"Host": []interface{}{"origin.example.com"}}
+	server.AddPool("acc1", cftest.Pool{ID: "pool1", Name: "cdn", Origins: []map[string]interface{}{
+		{"name": "manual", "address": "10.0.0.1", "enabled": true, "weight": 0.5},
+		{"name": "autocdn-1", "address": "1.1.1.1", "enabled": true, "weight": 1.0, "header": header},
+		{"name": "autocdn-2", "address": "9.9.9.9", "enabled": true, "weight": 1.0},
+		{"name": "autocdn-3", "address": "8.8.8.8", "enabled": true, "weight": 1.0},
+		{"name": "autocdn-4", "address": "2606:4700::1", "enabled": true, "weight": 1.0},
+	}})
+	lb := config.LoadBalancerConfig{AccountID: "acc1", PoolID: "pool1", Origins: 3}
+
+	// 2.2.2.2 最快 (权重 1)，1.1.1.1 权重 0.95；autocdn-1 保留原 IP，autocdn-2 复用，autocdn-3 停用
+	plan, err := client.PlanPool(lb, speedSet("2.2.2.2", "1.1.1.1"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if len(plan.Changes) != 3 {
+		t.Fatalf("期望 3 项变更，实际 %v", plan.Changes)
+	}
+	if err := client.ApplyPoolPlan(plan); err != nil {
+		t.Fatalf("更新地址池失败: %v", err)
+	}
+
+	want := []struct {
+		address string
+		enabled bool
+		weight  float64
+	}{
+		{"10.0.0.1", true, 0.5},
+		{"1.1.1.1", true, 0.95},
+		{"2.2.2.2", true, 1},
+		{"8.8.8.8", false, 1},
+		{"2606:4700::1", true, 1},
+	}
+	pool := server.Pool("acc1", "pool1")
+	if len(pool.Origins) != len(want) {
+		t.Fatalf("期望 %d 个源站，实际 %+v", len(want), pool.Origins)
+	}
+	for i, w := range want {
+		origin := pool.Origins[i]
+		if origin["address"] != w.address || origin["enabled"] != w.enabled || origin["weight"] != w.weight {
+			t.Fatalf("源站 %d 为 %+v，期望 %+v", i, origin, w)
+		}
+	}
+	if !reflect.DeepEqual(pool.Origins[1]["header"], header) {
+		t.Fatalf("未知字段未保留: %+v", pool.Origins[1])
+	}
+
+	// 结果不变时不发送 PATCH
+	before := patchCount(server)
+	if err := client.PublishPool(lb, speedSet("2.2.2.2", "1.1.1.1")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if n := patchCount(server) - before; n != 0 {
+		t.Fatalf("无变更时发送了 %d 次 PATCH", n)
+	}
+
+	// 源站不足时新增 autocdn-5，停用的 autocdn-3 优先复用
+	plan, err = client.PlanPool(lb, speedSet("3.3.3.3", "4.4.4.4", "2.2.2.2"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	var names []string
+	for _, origin := range plan.Origins {
+		if origin.Enabled {
+			names = append(names, origin.Name+"="+origin.Address)
+		}
+	}
+	expected := []string{"manual=10.0.0.1", "autocdn-1=3.3.3.3", "autocdn-2=2.2.2.2", "autocdn-3=4.4.4.4", "autocdn-4=2606:4700::1"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("启用的源站为 %v，期望 %v", names, expected)
+	}
+
+	if _, err := client.PlanPool(config.LoadBalancerConfig{AccountID: "acc1", PoolID: "missing"}, speedSet("1.1.1.1")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("期望地址池不存在错误，实际 %v", err)
+	}
+	// 质量保护不满足时不修改地址池
+	client.cf.Guard = config.GuardConfig{MinIPs: 3}
+	before = patchCount(server)
+	if err := client.PublishPool(lb, speedSet("5.5.5.5", "6.6.6.6")); !errors.Is(err, ErrPublishSkipped) {
+		t.Fatalf("期望跳过发布，实际 %v", err)
+	}
+	if n := patchCount(server) - before; n != 0 {
+		t.Fatalf("跳过发布时发送了 %d 次 PATCH", n)
+	}
+}
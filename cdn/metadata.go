@@ -0,0 +1,190 @@
+package cdn
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"AutoCDN/utils"
+)
+
+// metadataMarker 元数据记录内容的第一个字段，用于识别 AutoCDN 写入的 TXT 记录
+const metadataMarker = "autocdn=1"
+
+// txtChunkSize TXT 记录中单个字符串的最大长度
+const txtChunkSize = 255
+
+// quoteTXT 将 TXT 内容拆分为不超过 255 字节的带引号字符串，供要求引号格式的服务商使用
+func quoteTXT(value string) string {
+	var parts []string
+	for len(value) > txtChunkSize {
+		parts = append(parts, `"`+value[:txtChunkSize]+`"`)
+		value = value[txtChunkSize:]
+	}
+	parts = append(parts, `"`+value+`"`)
+	return strings.Join(parts, " ")
+}
+
+// unquoteTXT 去掉 TXT 内容的引号并拼接多个字符串，未加引号的内容原样返回
+func unquoteTXT(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, `"`) || !strings.HasSuffix(content, `"`) {
+		return content
+	}
+	parts := strings.Split(content[1:len(content)-1], `" "`)
+	return strings.Join(parts, "")
+}
+
+// metadataFields 解析元数据记录内容，不是 AutoCDN 写入的记录时返回 nil
+func metadataFields(content string) map[string]string {
+	fields := strings.Fields(unquoteTXT(content))
+	if len(fields) == 0 || fields[0] != metadataMarker {
+		return nil
+	}
+	values := make(map[string]string, len(fields))
+	for _, field := range fields[1:] {
+		if key, value, ok := strings.Cut(field, "="); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// metadataContent 生成元数据记录内容，各 IP 的延迟、速度和数据中心按 IP 的顺序以逗号分隔，未知时为 -
+func metadataContent(now time.Time, configName, recordType string, ips []string, metrics map[string]utils.CloudflareIPData) string {
+	delays := make([]string, 0, len(ips))
+	speeds := make([]string, 0, len(ips))
+	colos := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		data, ok := metrics[ip]
+		if !ok {
+			delays, speeds, colos = append(delays, "-"), append(speeds, "-"), append(colos, "-")
+			continue
+		}
+		delays = append(delays, fmt.Sprintf("%d", data.Delay.Milliseconds()))
+		speeds = append(speeds, fmt.Sprintf("%.2f", data.DownloadSpeed/1024/1024))
+		colo := data.Colo
+		if colo == "" {
+			colo = "-"
+		}
+		colos = append(colos, colo)
+	}
+	if configName == "" {
+		configName = "-"
+	}
+
+	return strings.Join([]string{
+		metadataMarker,
+		"time=" + now.UTC().Format(time.RFC3339),
+		"config=" + strings.Join(strings.Fields(configName), "_"),
+		"type=" + recordType,
+		"ips=" + strings.Join(ips, ","),
+		"delay_ms=" + strings.Join(delays, ","),
+		"speed_mbps=" + strings.Join(speeds, ","),
+		"colo=" + strings.Join(colos, ","),
+	}, " ")
+}
+
+// publishMetadata 为计划中有变更的域名写入元数据 TXT 记录
+// 每个域名每种地址记录类型各维护一条记录，写入失败只记录警告，不影响已完成的发布
+func (p *Publisher) publishMetadata(plan *Plan) {
+	if !p.cf.Metadata.Enabled || plan.metrics == nil {
+		return
+	}
+
+	var names []string
+	actionsOf := make(map[string][]PlanAction)
+	changed := make(map[string]bool)
+	for _, action := range plan.Actions {
+		if _, ok := actionsOf[action.Name]; !ok {
+			names = append(names, action.Name)
+		}
+		actionsOf[action.Name] = append(actionsOf[action.Name], action)
+		if action.Action != ActionNoop {
+			changed[action.Name] = true
+		}
+	}
+
+	caps := p.provider.Capabilities()
+	now := time.Now()
+	for _, name := range names {
+		if !changed[name] {
+			continue
+		}
+		var ips []string
+		for _, action := range actionsOf[name] {
+			if action.Action != ActionDelete && !contains(ips, action.NewContent) {
+				ips = append(ips, action.NewContent)
+			}
+		}
+		content := metadataContent(now, p.cf.Metadata.ConfigName, plan.Type, ips, plan.metrics)
+		if caps.QuotedTXT {
+			content = quoteTXT(content)
+		}
+
+		action := actionsOf[name][0]
+		if err := p.writeMetadata(action.ZoneID, p.cf.Metadata.RecordName(name), plan.Type, content, action.domain.RecordTTL()); err != nil {
+			log.Printf("警告: 写入 %s 的元数据记录失败: %v", name, err)
+		}
+	}
+}
+
+// writeMetadata 更新记录名下对应记录类型的元数据记录，不存在时创建
+func (p *Publisher) writeMetadata(zoneID, recordName, recordType, content string, ttl int) error {
+	records, err := p.provider.ListRecords(zoneID, RecordFilter{Name: recordName, Type: "TXT"})
+	if err != nil {
+		return err
+	}
+	record := DNSRecord{Name: recordName, Type: "TXT", Content: content, TTL: ttl}
+	for _, existing := range records {
+		if fields := metadataFields(existing.Content); fields != nil && fields["type"] == recordType {
+			record.ID = existing.ID
+			break
+		}
+	}
+	if err := p.provider.UpsertRecord(zoneID, record); err != nil {
+		return err
+	}
+	log.Printf("已写入元数据记录: %s", recordName)
+	return nil
+}
+
+// publishedMetrics 读取元数据记录中保存的各 IP 发布时的测速数据，读取失败或没有记录时返回 nil
+func (p *Publisher) publishedMetrics(zoneID, name, recordType string) map[string]utils.CloudflareIPData {
+	records, err := p.provider.ListRecords(zoneID, RecordFilter{Name: p.cf.Metadata.RecordName(name), Type: "TXT"})
+	if err != nil {
+		log.Printf("警告: 读取 %s 的元数据记录失败: %v", name, err)
+		return nil
+	}
+	for _, record := range records {
+		fields := metadataFields(record.Content)
+		if fields == nil || fields["type"] != recordType {
+			continue
+		}
+		ips := strings.Split(fields["ips"], ",")
+		delays := strings.Split(fields["delay_ms"], ",")
+		speeds := strings.Split(fields["speed_mbps"], ",")
+		result := make(map[string]utils.CloudflareIPData, len(ips))
+		for i, ip := range ips {
+			if i >= len(delays) || i >= len(speeds) {
+				break
+			}
+			delay, err := strconv.Atoi(delays[i])
+			if err != nil {
+				continue
+			}
+			speed, err := strconv.ParseFloat(speeds[i], 64)
+			if err != nil {
+				continue
+			}
+			result[ip] = utils.CloudflareIPData{
+				PingData:      &utils.PingData{Delay: time.Duration(delay) * time.Millisecond},
+				DownloadSpeed: speed * 1024 * 1024,
+			}
+		}
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package cdn
+
+import (
+	"strings"
+	"testing"
+
+	"AutoCDN/cdn/cftest"
+	"AutoCDN/config"
+)
+
+func TestTXTEncoding(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	quoted := quoteTXT(long)
+	if quoted != `"`+long[:255]+`" "`+long[255:]+`"` {
+		t.Fatalf("拆分结果为 %s", quoted)
+	}
+	if got := unquoteTXT(quoted); got != long {
+		t.Fatalf("还原结果为 %s", got)
+	}
+	if got := unquoteTXT("autocdn=1 type=A"); got != "autocdn=1 type=A" {
+		t.Fatalf("未加引号的内容被修改: %s", got)
+	}
+
+	data, err := rdata("TXT", long)
+	if err != nil || len(data) != 302 || data[0] != 255 || data[256] != 45 {
+		t.Fatalf("TXT RDATA 编码错误: %d 字节 (%v)", len(data), err)
+	}
+	if got := rdataContent("TXT", data); got != long {
+		t.Fatalf("TXT RDATA 解码结果为 %s", got)
+	}
+}
+
+// metadataRecords 返回记录名下 AutoCDN 写入的元数据，按记录类型索引
+func metadataRecords(server *cftest.Server, name string) map[string]map[string]string {
+	result := make(map[string]map[string]string)
+	for _, record := range server.Records("zone1") {
+		if record.Name != name || record.Type != "TXT" {
+			continue
+		}
+		if fields := metadataFields(record.Content); fields != nil {
+			result[fields["type"]] = fields
+		}
+	}
+	return result
+}
+
+func TestPublishMetadata(t *testing.T) {
+	publisher, server := newTestPublisher(t, config.CloudflareConfig{
+		Domains:       []config.DomainConfig{{Name: "a.example.com"}, {Name: "b.example.com"}},
+		DomainIPv6s:   []config.DomainConfig{{Name: "a.example.com"}},
+		RecordSetSize: 2,
+		Metadata:      config.MetadataConfig{Enabled: true, ConfigName: "prod"},
+	})
+	server.AddRecord("zone1", cftest.Record{Name: "_autocdn.a.example.com", Type: "TXT", Content: "v=spf1 -all"})
+
+	results := speedSet("1.1.1.1", "2.2.2.2")
+	results[0].Colo = "SJC"
+	if err := publisher.HandleDNSRecords(results); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+
+	meta := metadataRecords(server, "_autocdn.a.example.com")["A"]
+	want := map[string]string{"config": "prod", "ips": "1.1.1.1,2.2.2.2", "delay_ms": "100,110", "speed_mbps": "20.00,19.00", "colo": "SJC,-"}
+	for key, value := range want {
+		if meta[key] != value {
+			t.Fatalf("元数据 %s 为 %q，期望 %q (%v)", key, meta[key], value, meta)
+		}
+	}
+	if meta["time"] == "" {
+		t.Fatal("元数据缺少时间")
+	}
+	if len(metadataRecords(server, "_autocdn.b.example.com")) != 1 {
+		t.Fatal("b 的元数据记录未写入")
+	}
+
+	// 无变更时不写入
+	writes := func() int {
+		n := 0
+		for _, req := range server.Requests() {
+			if req.Method != "GET" {
+				n++
+			}
+		}
+		return n
+	}
+	before := writes()
+	if err := publisher.HandleDNSRecords(results); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if n := writes() - before; n != 0 {
+		t.Fatalf("无变更时发送了 %d 次写请求", n)
+	}
+
+	// IPv6 发布写入单独的记录，IPv4 记录变更时原地更新，其他 TXT 记录保持不变
+	if err := publisher.HandleDNSRecordsIPv6(speedSet("2606:4700::1")); err != nil {
+		t.Fatalf("发布 IPv6 失败: %v", err)
+	}
+	if err := publisher.HandleDNSRecords(speedSet("3.3.3.3", "1.1.1.1")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	records := metadataRecords(server, "_autocdn.a.example.com")
+	if records["AAAA"]["ips"] != "2606:4700::1" || records["A"]["ips"] != "1.1.1.1,3.3.3.3" {
+		t.Fatalf("元数据记录为 %v", records)
+	}
+	if got := server.Contents("zone1", "_autocdn.a.example.com", "TXT"); len(got) != 3 {
+		t.Fatalf("期望 3 条 TXT 记录，实际 %v", got)
+	}
+}
@@ -0,0 +1,199 @@
+package cdn
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"AutoCDN/config"
+)
+
+// ownerMarker AutoCDN 写在记录备注中的管理标记
+const ownerMarker = "autocdn:managed"
+
+// ownerTXTMarker 管理声明 TXT 记录内容的第一个字段，供不支持记录备注的服务商使用
+const ownerTXTMarker = "autocdn-owner"
+
+// hasMarker 判断记录备注中是否有管理标记
+func hasMarker(comment string) bool {
+	return strings.Contains(comment, ownerMarker)
+}
+
+// markComment 在备注末尾追加管理标记，已有标记时原样返回
+func markComment(comment string) string {
+	if hasMarker(comment) {
+		return comment
+	}
+	if comment == "" {
+		return ownerMarker
+	}
+	return comment + " " + ownerMarker
+}
+
+// ownerContent 返回声明管理某种记录类型的 TXT 记录内容
+func ownerContent(recordType string) string {
+	return ownerTXTMarker + " type=" + recordType
+}
+
+// ownsName 判断记录名下是否有 AutoCDN 对指定记录类型的管理声明
+func (p *Publisher) ownsName(zoneID, name, recordType string) (bool, error) {
+	records, err := p.provider.ListRecords(zoneID, RecordFilter{Name: p.cf.Metadata.RecordName(name), Type: "TXT"})
+	if err != nil {
+		return false, fmt.Errorf("读取 %s 的管理声明失败: %v", name, err)
+	}
+	for _, record := range records {
+		fields := strings.Fields(unquoteTXT(record.Content))
+		if len(fields) > 0 && fields[0] == ownerTXTMarker && contains(fields[1:], "type="+recordType) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// claimName 写入记录名对指定记录类型的管理声明
+func (p *Publisher) claimName(zoneID, name, recordType string, ttl int) error {
+	content := ownerContent(recordType)
+	if p.provider.Capabilities().QuotedTXT {
+		content = quoteTXT(content)
+	}
+	return p.provider.UpsertRecord(zoneID, DNSRecord{Name: p.cf.Metadata.RecordName(name), Type: "TXT", Content: content, TTL: ttl})
+}
+
+// checkOwnership 检查将要修改的现有记录是否都由 AutoCDN 管理，不是时返回跳过该域名的原因
+// 支持备注的服务商逐条检查备注中的标记，其他服务商检查记录名下的管理声明
+func (p *Publisher) checkOwnership(zoneID, recordType string, domain config.DomainConfig, existing []DNSRecord) (string, error) {
+	if p.cf.Ownership.AllowUnmarked || len(existing) == 0 {
+		return "", nil
+	}
+	label := PlanAction{Name: domain.Name, Line: domain.Line}.label()
+
+	if p.provider.Capabilities().Comments {
+		var unmarked []string
+		for _, record := range existing {
+			if !hasMarker(record.Comment) {
+				unmarked = append(unmarked, record.Content)
+			}
+		}
+		if len(unmarked) == 0 {
+			return "", nil
+		}
+		return fmt.Sprintf("%s 的 %s 记录 %s 没有 AutoCDN 标记，未修改 (可使用 -adopt 接管)", label, recordType, strings.Join(unmarked, ", ")), nil
+	}
+
+	owned, err := p.ownsName(zoneID, domain.Name, recordType)
+	if err != nil || owned {
+		return "", err
+	}
+	return fmt.Sprintf("%s 的 %s 记录没有 AutoCDN 管理声明 (%s TXT)，未修改 (可使用 -adopt 接管)",
+		label, recordType, p.cf.Metadata.RecordName(domain.Name)), nil
+}
+
+// claimNames 不支持备注的服务商：为计划中新建记录的记录名写入管理声明，写入失败只记录警告
+func (p *Publisher) claimNames(plan *Plan) {
+	if len(plan.claims) == 0 {
+		return
+	}
+	done := make(map[string]bool)
+	for _, action := range plan.Actions {
+		if action.Action != ActionCreate || !plan.claims[action.Name] || done[action.Name] {
+			continue
+		}
+		done[action.Name] = true
+		if owned, err := p.ownsName(action.ZoneID, action.Name, plan.Type); err == nil && owned {
+			continue
+		}
+		if err := p.claimName(action.ZoneID, action.Name, plan.Type, action.domain.RecordTTL()); err != nil {
+			log.Printf("警告: 写入 %s 的管理声明失败: %v", action.Name, err)
+		}
+	}
+}
+
+// AdoptRecords 接管配置中域名的现有记录，之后发布时可以修改这些记录
+// 支持备注的服务商在每条记录的备注中追加标记，其他服务商写入管理声明 TXT 记录；names 为空时接管全部配置的域名
+// 返回已接管的记录描述
+func (p *Publisher) AdoptRecords(names []string) ([]string, error) {
+	type target struct {
+		recordType string
+		domain     config.DomainConfig
+	}
+	caps := p.provider.Capabilities()
+	var targets []target
+	var configured []string
+	for _, group := range []struct {
+		recordType string
+		domains    []config.DomainConfig
+	}{{"A", p.cf.Domains}, {"AAAA", p.cf.DomainIPv6s}} {
+		for _, domain := range group.domains {
+			if len(names) > 0 && !contains(names, domain.Name) {
+				continue
+			}
+			targets = append(targets, target{group.recordType, domain})
+			if !contains(configured, domain.Name) {
+				configured = append(configured, domain.Name)
+				if p.cf.HTTPSRecord.Enabled && caps.HTTPS {
+					targets = append(targets, target{"HTTPS", domain})
+				}
+			}
+		}
+	}
+	for _, name := range names {
+		if !contains(configured, name) {
+			return nil, fmt.Errorf("%s 不在配置的域名中", name)
+		}
+	}
+
+	resolver := newZoneResolver(p.provider, p.cf)
+	var adopted, failures []string
+	for _, t := range targets {
+		domain := caps.normalize(t.domain)
+		label := PlanAction{Name: domain.Name, Line: domain.Line}.label()
+		zoneID, err := resolver.resolve(domain.Name)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		records, err := p.provider.ListRecords(zoneID, RecordFilter{Name: domain.Name, Type: t.recordType})
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("获取记录列表失败 (%s): %v", domain.Name, err))
+			continue
+		}
+		records = filterLine(records, domain.Line)
+		if len(records) == 0 {
+			continue
+		}
+
+		if caps.Comments {
+			for _, record := range records {
+				if hasMarker(record.Comment) {
+					continue
+				}
+				record.Comment = markComment(record.Comment)
+				if err := p.provider.UpsertRecord(zoneID, record); err != nil {
+					failures = append(failures, fmt.Sprintf("接管 %s %s 失败: %v", t.recordType, label, err))
+					continue
+				}
+				adopted = append(adopted, fmt.Sprintf("已接管 %s %s: %s", t.recordType, label, record.Content))
+			}
+			continue
+		}
+
+		owned, err := p.ownsName(zoneID, domain.Name, t.recordType)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		if owned {
+			continue
+		}
+		if err := p.claimName(zoneID, domain.Name, t.recordType, domain.RecordTTL()); err != nil {
+			failures = append(failures, fmt.Sprintf("接管 %s %s 失败: %v", t.recordType, label, err))
+			continue
+		}
+		adopted = append(adopted, fmt.Sprintf("已接管 %s %s: %s", t.recordType, label, strings.Join(recordContents(records), ", ")))
+	}
+
+	if len(failures) > 0 {
+		return adopted, fmt.Errorf("%d 项接管失败: %s", len(failures), strings.Join(failures, "; "))
+	}
+	return adopted, nil
+}
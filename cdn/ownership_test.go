@@ -0,0 +1,109 @@
+package cdn
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"AutoCDN/cdn/cftest"
+	"AutoCDN/config"
+)
+
+// recordComments 返回记录名下指定类型记录的备注
+func recordComments(server *cftest.Server, name, recordType string) []string {
+	var comments []string
+	for _, record := range server.Records("zone1") {
+		if record.Name == name && record.Type == recordType {
+			comments = append(comments, record.Comment)
+		}
+	}
+	return comments
+}
+
+func TestOwnershipComments(t *testing.T) {
+	cf := config.CloudflareConfig{
+		Domains: []config.DomainConfig{{Name: "a.example.com"}, {Name: "b.example.com"}},
+	}
+	publisher, server := newTestPublisher(t, cf)
+	server.AddRecord("zone1", cftest.Record{Name: "a.example.com", Type: "A", Content: "9.9.9.9", TTL: config.DefaultTTL, Comment: "手工维护"})
+
+	// 没有标记的 a 跳过，新建的 b 带有标记
+	plan, err := publisher.PlanDNSRecords(speedSet("1.1.1.1", "2.2.2.2"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if len(plan.Skipped) != 1 || !strings.Contains(plan.Skipped[0], "a.example.com 的 A 记录 9.9.9.9 没有 AutoCDN 标记") {
+		t.Fatalf("计划为 %v", plan.Lines())
+	}
+	if err := publisher.ApplyPlan(plan); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if got := server.Contents("zone1", "a.example.com", "A"); !reflect.DeepEqual(got, []string{"9.9.9.9"}) {
+		t.Fatalf("没有标记的记录被修改: %v", got)
+	}
+	if got := recordComments(server, "b.example.com", "A"); !reflect.DeepEqual(got, []string{ownerMarker}) {
+		t.Fatalf("b 的备注为 %v", got)
+	}
+
+	if _, err := publisher.AdoptRecords([]string{"x.example.com"}); err == nil || !strings.Contains(err.Error(), "x.example.com 不在配置的域名中") {
+		t.Fatalf("期望未配置域名的错误，实际 %v", err)
+	}
+
+	// 接管后保留原备注并追加标记，之后可以正常发布
+	adopted, err := publisher.AdoptRecords(nil)
+	if err != nil || !reflect.DeepEqual(adopted, []string{"已接管 A a.example.com: 9.9.9.9"}) {
+		t.Fatalf("接管结果为 %v (%v)", adopted, err)
+	}
+	if got := recordComments(server, "a.example.com", "A"); !reflect.DeepEqual(got, []string{"手工维护 " + ownerMarker}) {
+		t.Fatalf("接管后 a 的备注为 %v", got)
+	}
+	if err := publisher.HandleDNSRecords(speedSet("1.1.1.1", "2.2.2.2")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if got := server.Contents("zone1", "a.example.com", "A"); !reflect.DeepEqual(got, []string{"1.1.1.1"}) {
+		t.Fatalf("接管后 a 的记录为 %v", got)
+	}
+
+	// 允许修改没有标记的记录时按旧版本行为发布
+	cf.Ownership.AllowUnmarked = true
+	publisher, server = newTestPublisher(t, cf)
+	server.AddRecord("zone1", cftest.Record{Name: "a.example.com", Type: "A", Content: "9.9.9.9", TTL: config.DefaultTTL})
+	if err := publisher.HandleDNSRecords(speedSet("1.1.1.1", "2.2.2.2")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if got := server.Contents("zone1", "a.example.com", "A"); !reflect.DeepEqual(got, []string{"1.1.1.1"}) {
+		t.Fatalf("a 的记录为 %v", got)
+	}
+}
+
+func TestOwnershipTXT(t *testing.T) {
+	client, fake := newTestRoute53(t)
+	fake.sets["a.example.com./A"] = route53ResourceRecordSet{Name: "a.example.com.", Type: "A", TTL: 300, ResourceRecords: []string{"9.9.9.9"}}
+	publisher := NewPublisher(client, config.CloudflareConfig{
+		Domains:     []config.DomainConfig{{Name: "a.example.com", TTL: 300}, {Name: "b.example.com", TTL: 300}},
+		SnapshotDir: filepath.Join(t.TempDir(), "snapshots"),
+	})
+
+	// 没有管理声明的 a 跳过，新建的 b 写入管理声明
+	if err := publisher.HandleDNSRecords(speedSet("1.1.1.1", "2.2.2.2")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if got := fake.values("a.example.com.", "A"); !reflect.DeepEqual(got, []string{"9.9.9.9"}) {
+		t.Fatalf("没有管理声明的记录被修改: %v", got)
+	}
+	if got := fake.values("_autocdn.b.example.com.", "TXT"); !reflect.DeepEqual(got, []string{`"autocdn-owner type=A"`}) {
+		t.Fatalf("b 的管理声明为 %v", got)
+	}
+
+	adopted, err := publisher.AdoptRecords([]string{"a.example.com"})
+	if err != nil || !reflect.DeepEqual(adopted, []string{"已接管 A a.example.com: 9.9.9.9"}) {
+		t.Fatalf("接管结果为 %v (%v)", adopted, err)
+	}
+	if err := publisher.HandleDNSRecords(speedSet("1.1.1.1", "2.2.2.2")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if got := fake.values("a.example.com.", "A"); !reflect.DeepEqual(got, []string{"1.1.1.1"}) {
+		t.Fatalf("接管后 a 的记录为 %v", got)
+	}
+}
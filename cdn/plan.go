@@ -0,0 +1,600 @@
+package cdn
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"AutoCDN/config"
+	"AutoCDN/utils"
+)
+
+// 计划中的变更动作
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionNoop   = "noop"
+)
+
+// PlanAction 计划中针对单条记录的变更
+type PlanAction struct {
+	Action     string `json:"action"`         // create / update / delete / noop
+	ZoneID     string `json:"zoneId"`         // 记录所属 Zone
+	Name       string `json:"name"`           // 记录名
+	Type       string `json:"type"`           // A / AAAA
+	RecordID   string `json:"recordId"`       // update / delete / noop 的目标记录
+	OldContent string `json:"oldContent"`     // 当前内容
+	NewContent string `json:"newContent"`     // 变更后的内容
+	Line       string `json:"line,omitempty"` // 解析线路，默认线路为空
+
+	domain config.DomainConfig // 生成请求体所需的记录选项
+}
+
+// label 返回动作针对的记录名，非默认线路时附带线路
+func (a PlanAction) label() string {
+	if a.Line == "" {
+		return a.Name
+	}
+	return fmt.Sprintf("%s [%s]", a.Name, a.Line)
+}
+
+// Plan 一次发布的 DNS 变更计划
+type Plan struct {
+	Type    string       `json:"type"`
+	Actions []PlanAction `json:"actions"`
+	HTTPS   *Plan        `json:"https,omitempty"`   // 随地址记录更新的 HTTPS 记录，未启用时为空
+	Held    []string     `json:"held,omitempty"`    // 因变更频率限制暂缓的变更及原因
+	Skipped []string     `json:"skipped,omitempty"` // 因记录不由 AutoCDN 管理而跳过的域名及原因
+
+	SnapshotID   string        `json:"snapshotId,omitempty"`   // 执行时保存的变更前快照
+	Verification *VerifyReport `json:"verification,omitempty"` // 执行后的校验结果，未启用校验时为空
+	Canary       *CanaryReport `json:"canary,omitempty"`       // 金丝雀观察结果，未分阶段发布时为空
+
+	current map[string][]DNSRecord            // 生成计划时各记录名的现有记录，用于保存快照
+	metrics map[string]utils.CloudflareIPData // 生成计划时的测速结果，用于写入元数据记录
+	staged  bool                              // 已按金丝雀拆分，执行时不再拆分
+	claims  map[string]bool                   // 新建记录后需要写入管理声明的记录名（不支持备注的服务商）
+	kept    map[string][]string               // 单 IP 模式下同名记录中不维护的其余内容，整体替换记录集时原样保留
+}
+
+// Changes 返回需要实际执行的变更数量，包括 HTTPS 记录的变更
+func (p *Plan) Changes() int {
+	count := p.actionChanges()
+	if p.HTTPS != nil {
+		count += p.HTTPS.Changes()
+	}
+	return count
+}
+
+// actionChanges 返回计划自身需要实际执行的变更数量
+func (p *Plan) actionChanges() int {
+	count := 0
+	for _, action := range p.Actions {
+		if action.Action != ActionNoop {
+			count++
+		}
+	}
+	return count
+}
+
+// Lines 返回计划的可读描述，每个动作一行
+func (p *Plan) Lines() []string {
+	lines := make([]string, 0, len(p.Actions)+1)
+	lines = append(lines, fmt.Sprintf("DNS 变更计划 (%s): %d 项变更, %d 项无需变更", p.Type, p.actionChanges(), len(p.Actions)-p.actionChanges()))
+	for _, action := range p.Actions {
+		switch action.Action {
+		case ActionCreate:
+			lines = append(lines, fmt.Sprintf("  + 创建 %s %s -> %s", action.Type, action.label(), action.NewContent))
+		case ActionUpdate:
+			lines = append(lines, fmt.Sprintf("  ~ 更新 %s %s: %s -> %s", action.Type, action.label(), action.OldContent, action.NewContent))
+		case ActionDelete:
+			lines = append(lines, fmt.Sprintf("  - 删除 %s %s: %s", action.Type, action.label(), action.OldContent))
+		default:
+			lines = append(lines, fmt.Sprintf("  = 保持 %s %s: %s", action.Type, action.label(), action.OldContent))
+		}
+	}
+	for _, skipped := range p.Skipped {
+		lines = append(lines, "  ! 跳过 "+skipped)
+	}
+	for _, held := range p.Held {
+		lines = append(lines, "  ! 暂缓 "+held)
+	}
+	if p.HTTPS != nil {
+		lines = append(lines, p.HTTPS.Lines()...)
+	}
+	return lines
+}
+
+// Print 在命令行中打印计划
+func (p *Plan) Print() {
+	for _, line := range p.Lines() {
+		fmt.Println(line)
+	}
+}
+
+// recordMatches 判断现有记录是否已与目标内容和记录选项一致
+// Cloudflare 代理记录的 TTL 总是自动，不比较 TTL；未配置备注或标签时不比较对应字段
+func recordMatches(record DNSRecord, content string, domain config.DomainConfig) bool {
+	if record.Content != content || record.Proxied != domain.IsProxied() {
+		return false
+	}
+	if !domain.IsProxied() && record.TTL != domain.RecordTTL() {
+		return false
+	}
+	if len(domain.Tags) > 0 && !sameTags(record.Tags, domain.Tags) {
+		return false
+	}
+	return domain.Comment == "" || record.Comment == domain.Comment
+}
+
+// sameTags 判断两组标签是否相同（不考虑顺序）
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// listRecordGroups 获取各记录名所属Zone中指定类型的记录并按记录名分组
+// 通常每个Zone只请求一次；服务商只能按记录名查询时（NameLookup）逐个记录名查询
+func (p *Publisher) listRecordGroups(recordType string, zoneOf map[string]string) (map[string][]DNSRecord, error) {
+	names := make([]string, 0, len(zoneOf))
+	for name := range zoneOf {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	groups := make(map[string][]DNSRecord)
+	if p.provider.Capabilities().NameLookup {
+		for _, name := range names {
+			records, err := p.provider.ListRecords(zoneOf[name], RecordFilter{Name: name, Type: recordType})
+			if err != nil {
+				return nil, fmt.Errorf("获取记录列表失败 (%s): %v", name, err)
+			}
+			if len(records) > 0 {
+				groups[name] = records
+			}
+		}
+		return groups, nil
+	}
+
+	listed := make(map[string]bool)
+	for _, name := range names {
+		zoneID := zoneOf[name]
+		if listed[zoneID] {
+			continue
+		}
+		listed[zoneID] = true
+
+		records, err := p.provider.ListRecords(zoneID, RecordFilter{Type: recordType})
+		if err != nil {
+			return nil, fmt.Errorf("获取记录列表失败 (Zone %s): %v", zoneID, err)
+		}
+		for name, group := range groupRecordsByName(records) {
+			groups[name] = group
+		}
+	}
+	return groups, nil
+}
+
+// buildPlan 对比当前Zone状态，计算将域名列表发布为指定IP所需的变更
+func (p *Publisher) buildPlan(recordType string, domains []config.DomainConfig, results utils.DownloadSpeedSet, setSize int, hysteresis config.HysteresisConfig) (*Plan, error) {
+	results, err := checkGuard(p.cf.Guard, results)
+	if err != nil {
+		return nil, err
+	}
+	ipList := make([]string, 0, len(results))
+	for _, data := range results {
+		ipList = append(ipList, data.PingData.IP.String())
+	}
+	metrics := indexResults(results)
+
+	// 先解析全部域名所属的 Zone，任一域名无法解析时不生成计划，避免在错误的 Zone 中创建记录
+	resolver := newZoneResolver(p.provider, p.cf)
+	zoneOf := make(map[string]string, len(domains))
+	var failures []string
+	for _, domain := range domains {
+		zoneID, err := resolver.resolve(domain.Name)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		zoneOf[domain.Name] = zoneID
+	}
+	if len(failures) > 0 {
+		return nil, fmt.Errorf("解析域名所属 Zone 失败: %s", strings.Join(failures, "; "))
+	}
+
+	groups, err := p.listRecordGroups(recordType, zoneOf)
+	if err != nil {
+		return nil, err
+	}
+
+	caps := p.provider.Capabilities()
+	if setSize > 1 && !caps.MultiValue {
+		log.Printf("%s 不支持同名多条记录，每个域名只发布 1 个 IP", p.provider.Name())
+		setSize = 1
+	}
+
+	var ledger *changeLedger
+	if p.cf.RateLimit.Enabled() {
+		if ledger, err = p.loadLedger(); err != nil {
+			return nil, err
+		}
+	}
+
+	plan := &Plan{Type: recordType, current: groups, metrics: metrics}
+	for i, domain := range domains {
+		domain = caps.normalize(domain)
+		if caps.Comments {
+			domain.Comment = markComment(domain.Comment)
+		}
+		var ips, kept []string
+		existing := filterLine(groups[domain.Name], domain.Line)
+		if setSize > 1 {
+			// 记录集模式：每个域名发布前 N 个 IP
+			ips = topIPs(ipList, setSize)
+		} else {
+			// 使用取模运算循环分配 IP，同名多条记录时只维护第一条
+			ips = []string{ipList[i%len(ipList)]}
+			if len(existing) > 1 {
+				kept = recordContents(existing[1:])
+				existing = existing[:1]
+			}
+		}
+
+		reason, err := p.checkOwnership(zoneOf[domain.Name], recordType, domain, existing)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			log.Print(reason)
+			plan.Skipped = append(plan.Skipped, reason)
+			continue
+		}
+		if len(kept) > 0 {
+			if plan.kept == nil {
+				plan.kept = make(map[string][]string)
+			}
+			plan.kept[domain.Name] = kept
+		}
+		if len(existing) == 0 && !caps.Comments {
+			if plan.claims == nil {
+				plan.claims = make(map[string]bool)
+			}
+			plan.claims[domain.Name] = true
+		}
+
+		if hysteresis.Enabled {
+			ips = keepCurrentIPs(domain.Name, recordContents(existing), ips, metrics, hysteresis)
+		}
+		if ledger != nil {
+			var held []string
+			ips, held = limitChanges(ledger, p.cf.RateLimit, recordType, domain, recordContents(existing), ips, time.Now())
+			for _, reason := range held {
+				log.Printf("限流: %s", reason)
+			}
+			plan.Held = append(plan.Held, held...)
+		}
+
+		for _, action := range diffRecordSet(existing, ips, domain) {
+			action.ZoneID = zoneOf[domain.Name]
+			action.Name = domain.Name
+			action.Type = recordType
+			action.Line = domain.Line
+			action.domain = domain
+			plan.Actions = append(plan.Actions, action)
+		}
+	}
+
+	if err := p.checkDegradation(plan, domains, zoneOf); err != nil {
+		return nil, err
+	}
+
+	if p.cf.HTTPSRecord.Enabled {
+		if !caps.HTTPS {
+			log.Printf("%s 不支持 HTTPS 记录，跳过", p.provider.Name())
+		} else if plan.HTTPS, err = p.buildHTTPSPlan(plan, zoneOf); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// PlanDNSRecords 计算IPv4域名的变更计划
+func (p *Publisher) PlanDNSRecords(results utils.DownloadSpeedSet) (*Plan, error) {
+	if len(results) == 0 {
+		return nil, fmt.Errorf("没有可用的 IPv4 IP")
+	}
+	return p.buildPlan("A", p.cf.Domains, results, p.cf.RecordSetSize, p.cf.Hysteresis)
+}
+
+// PlanDNSRecordsIPv6 计算IPv6域名的变更计划
+func (p *Publisher) PlanDNSRecordsIPv6(results utils.DownloadSpeedSet) (*Plan, error) {
+	if len(results) == 0 {
+		return nil, fmt.Errorf("没有可用的 IPv6 IP")
+	}
+	return p.buildPlan("AAAA", p.cf.DomainIPv6s, results, p.cf.RecordSetSize, p.cf.Hysteresis)
+}
+
+// ApplyPlan 按计划执行变更，执行前保存受影响记录的快照，单项失败不影响其余变更
+// 启用校验时先校验地址记录，校验通过后才写入元数据记录和 HTTPS 记录
+// HTTPS 记录在地址记录全部更新成功后单独保存快照并更新，避免 hint 指向未发布的 IP
+func (p *Publisher) ApplyPlan(plan *Plan) error {
+	if plan.Changes() == 0 {
+		log.Printf("DNS记录无需变更 (%s)", plan.Type)
+		return nil
+	}
+	if p.cf.Canary.Enabled() && plan.metrics != nil && !plan.staged {
+		return p.applyCanary(plan)
+	}
+
+	if plan.actionChanges() > 0 {
+		snapshot, err := p.saveSnapshot(plan)
+		if err != nil {
+			return fmt.Errorf("保存快照失败，未修改任何记录: %v", err)
+		}
+		plan.SnapshotID = snapshot.ID
+		log.Printf("已保存变更前快照: %s", snapshot.ID)
+
+		if failures := p.applyActions(plan, false); len(failures) > 0 {
+			return fmt.Errorf("%d 项记录变更失败: %s", len(failures), strings.Join(failures, "; "))
+		}
+		p.claimNames(plan)
+		if p.cf.RateLimit.Enabled() && plan.metrics != nil {
+			if err := p.recordChanges(plan, time.Now()); err != nil {
+				log.Printf("警告: 写入变更记录失败: %v", err)
+			}
+		}
+		if err := p.verifyApplied(plan); err != nil {
+			return err
+		}
+		p.publishMetadata(plan)
+	}
+
+	if plan.HTTPS != nil {
+		return p.ApplyPlan(plan.HTTPS)
+	}
+	return nil
+}
+
+// verifyApplied 启用校验时校验已执行的地址记录变更，失败时按配置恢复变更前的快照
+func (p *Publisher) verifyApplied(plan *Plan) error {
+	if !p.cf.Verify.Enabled || plan.metrics == nil {
+		return nil
+	}
+
+	plan.Verification = p.VerifyPlan(plan)
+	for _, line := range plan.Verification.Lines() {
+		log.Print(line)
+	}
+	failed := plan.Verification.Failed()
+	if failed == 0 {
+		return nil
+	}
+	if !p.cf.Verify.Revert {
+		return fmt.Errorf("%d 个域名校验失败，记录未恢复 (可手动恢复快照 %s)", failed, plan.SnapshotID)
+	}
+
+	log.Printf("%d 个域名校验失败，正在恢复快照 %s...", failed, plan.SnapshotID)
+	if err := p.RestoreSnapshot(plan.SnapshotID); err != nil {
+		return fmt.Errorf("%d 个域名校验失败，恢复快照失败: %v", failed, err)
+	}
+	return fmt.Errorf("%d 个域名校验失败，已恢复快照 %s", failed, plan.SnapshotID)
+}
+
+// applyActions 执行计划中的变更，返回每项失败的描述
+// stopOnError 为 true 时遇到第一个失败即停止
+func (p *Publisher) applyActions(plan *Plan, stopOnError bool) []string {
+	if provider, ok := p.provider.(RecordSetProvider); ok {
+		return applyRecordSets(provider, plan, stopOnError)
+	}
+	if provider, ok := p.provider.(BatchProvider); ok {
+		return p.applyBatches(provider, plan, stopOnError)
+	}
+	return p.applySequential(plan.Actions, stopOnError)
+}
+
+// applySequential 依次执行变更，单项失败时继续执行其余变更（stopOnError 为 false 时）
+func (p *Publisher) applySequential(actions []PlanAction, stopOnError bool) []string {
+	var failures []string
+	for _, action := range actions {
+		if action.Action == ActionNoop {
+			continue
+		}
+		if err := p.applyAction(action); err != nil {
+			failure := fmt.Sprintf("%s记录失败 %s (%s): %v", actionVerb(action.Action), action.label(), action.Type, err)
+			log.Print(failure)
+			failures = append(failures, failure)
+			if stopOnError {
+				return failures
+			}
+			continue
+		}
+		logApplied(action)
+	}
+	return failures
+}
+
+// logApplied 记录已成功执行的变更
+func logApplied(action PlanAction) {
+	switch action.Action {
+	case ActionCreate:
+		log.Printf("成功创建记录: %s -> %s", action.label(), action.NewContent)
+	case ActionUpdate:
+		log.Printf("成功更新记录: %s -> %s", action.label(), action.NewContent)
+	case ActionDelete:
+		log.Printf("成功删除多余记录: %s -> %s", action.label(), action.OldContent)
+	}
+}
+
+// applyBatches 将每个 Zone 的全部变更通过批量接口一次提交，同一 Zone 的变更全部生效或全部不生效
+// 批量接口不可用时该 Zone 退回逐条修改
+func (p *Publisher) applyBatches(provider BatchProvider, plan *Plan, stopOnError bool) []string {
+	var zoneOrder []string
+	actionsOf := make(map[string][]PlanAction)
+	for _, action := range plan.Actions {
+		if action.Action == ActionNoop {
+			continue
+		}
+		if _, ok := actionsOf[action.ZoneID]; !ok {
+			zoneOrder = append(zoneOrder, action.ZoneID)
+		}
+		actionsOf[action.ZoneID] = append(actionsOf[action.ZoneID], action)
+	}
+
+	var failures []string
+	for _, zoneID := range zoneOrder {
+		actions := actionsOf[zoneID]
+		var batch RecordBatch
+		for _, action := range actions {
+			record := recordFromAction(action)
+			switch action.Action {
+			case ActionCreate:
+				batch.Creates = append(batch.Creates, record)
+			case ActionUpdate:
+				batch.Updates = append(batch.Updates, record)
+			case ActionDelete:
+				record.Content = action.OldContent
+				batch.Deletes = append(batch.Deletes, record)
+			}
+		}
+
+		err := provider.ApplyBatch(zoneID, batch)
+		if errors.Is(err, ErrBatchUnsupported) {
+			log.Printf("批量接口不可用，改为逐条修改 (Zone %s): %v", zoneID, err)
+			failures = append(failures, p.applySequential(actions, stopOnError)...)
+			if stopOnError && len(failures) > 0 {
+				return failures
+			}
+			continue
+		}
+		if err != nil {
+			failure := fmt.Sprintf("批量提交失败，Zone %s 的 %d 项变更均未生效: %v", zoneID, batch.Size(), err)
+			log.Print(failure)
+			failures = append(failures, failure)
+			if stopOnError {
+				return failures
+			}
+			continue
+		}
+		for _, action := range actions {
+			logApplied(action)
+		}
+	}
+	return failures
+}
+
+// applyRecordSets 将计划中的变更按 Zone 合并为记录集提交
+// 只提交有变更的记录集，记录集的值为计划执行后应保留的全部内容，包括单 IP 模式下不维护的同名记录
+func applyRecordSets(provider RecordSetProvider, plan *Plan, stopOnError bool) []string {
+	type setKey struct{ zoneID, name string }
+	var zoneOrder []string
+	var keyOrder []setKey
+	sets := make(map[setKey]*RecordSet)
+	changed := make(map[setKey]bool)
+
+	for _, action := range plan.Actions {
+		key := setKey{action.ZoneID, action.Name}
+		set, ok := sets[key]
+		if !ok {
+			set = &RecordSet{Name: action.Name, Type: action.Type, TTL: action.domain.RecordTTL()}
+			sets[key] = set
+			keyOrder = append(keyOrder, key)
+		}
+		if action.Action != ActionDelete {
+			set.Values = append(set.Values, action.NewContent)
+		}
+		if action.Action != ActionNoop && !changed[key] {
+			changed[key] = true
+			if !contains(zoneOrder, action.ZoneID) {
+				zoneOrder = append(zoneOrder, action.ZoneID)
+			}
+		}
+	}
+	for _, key := range keyOrder {
+		set := sets[key]
+		for _, value := range plan.kept[key.name] {
+			if !contains(set.Values, value) {
+				set.Values = append(set.Values, value)
+			}
+		}
+	}
+
+	var failures []string
+	for _, zoneID := range zoneOrder {
+		var batch []RecordSet
+		for _, key := range keyOrder {
+			if key.zoneID == zoneID && changed[key] {
+				batch = append(batch, *sets[key])
+			}
+		}
+
+		if err := provider.ApplyRecordSets(zoneID, batch); err != nil {
+			failure := fmt.Sprintf("提交记录集失败 (Zone %s, %d 个记录集): %v", zoneID, len(batch), err)
+			log.Print(failure)
+			failures = append(failures, failure)
+			if stopOnError {
+				return failures
+			}
+			continue
+		}
+		for _, set := range batch {
+			if len(set.Values) == 0 {
+				log.Printf("成功删除记录集: %s (%s)", set.Name, set.Type)
+			} else {
+				log.Printf("成功更新记录集: %s (%s) -> %s", set.Name, set.Type, strings.Join(set.Values, ", "))
+			}
+		}
+	}
+	return failures
+}
+
+// contains 判断字符串是否在列表中
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// applyAction 执行单项变更
+func (p *Publisher) applyAction(action PlanAction) error {
+	switch action.Action {
+	case ActionCreate, ActionUpdate:
+		return p.provider.UpsertRecord(action.ZoneID, recordFromAction(action))
+	case ActionDelete:
+		record := recordFromAction(action)
+		record.Content = action.OldContent
+		return p.provider.DeleteRecord(action.ZoneID, record)
+	}
+	return nil
+}
+
+// actionVerb 返回动作的中文描述
+func actionVerb(action string) string {
+	switch action {
+	case ActionCreate:
+		return "创建"
+	case ActionUpdate:
+		return "更新"
+	case ActionDelete:
+		return "删除"
+	}
+	return "保持"
+}
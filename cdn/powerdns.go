@@ -0,0 +1,346 @@
+package cdn
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"AutoCDN/config"
+)
+
+// PowerDNS 设置
+const (
+	powerdnsServerID       = "localhost"
+	powerdnsDefaultTTL     = 300 // ttl: auto 时使用的 TTL
+	powerdnsRequestTimeout = 30 * time.Second
+)
+
+// PowerDNSClient PowerDNS 权威服务器 HTTP API 客户端
+type PowerDNSClient struct {
+	retryPolicy
+	baseURL    string // 以 /api/v1/servers/{server_id} 结尾
+	cfg        config.PowerDNSConfig
+	httpClient *http.Client
+}
+
+// NewPowerDNSClient 创建 PowerDNS API 客户端，httpClient 为空时使用默认的 HTTP 客户端
+// API 地址可以只写到端口，也可以包含 /api/v1
+func NewPowerDNSClient(cfg config.PowerDNSConfig, httpClient *http.Client) *PowerDNSClient {
+	baseURL := strings.TrimSuffix(cfg.APIURL, "/")
+	if !strings.HasSuffix(baseURL, "/api/v1") {
+		baseURL += "/api/v1"
+	}
+	serverID := cfg.ServerID
+	if serverID == "" {
+		serverID = powerdnsServerID
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: powerdnsRequestTimeout}
+	}
+	return &PowerDNSClient{
+		retryPolicy: defaultRetryPolicy(),
+		baseURL:     baseURL + "/servers/" + url.PathEscape(serverID),
+		cfg:         cfg,
+		httpClient:  httpClient,
+	}
+}
+
+// powerdnsRRSet PowerDNS 记录集
+type powerdnsRRSet struct {
+	Name       string           `json:"name"`
+	Type       string           `json:"type"`
+	TTL        int              `json:"ttl,omitempty"`
+	ChangeType string           `json:"changetype,omitempty"`
+	Records    []powerdnsRecord `json:"records"`
+}
+
+// powerdnsRecord 记录集中的一个值
+type powerdnsRecord struct {
+	Content  string `json:"content"`
+	Disabled bool   `json:"disabled"`
+}
+
+// request 发送 API 请求并解析 JSON 响应
+func (c *PowerDNSClient) request(method, path string, body interface{}, idempotent bool, result interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	return c.retry(idempotent, func() error {
+		req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("X-API-Key", c.cfg.APIKey)
+		req.Header.Set("Accept", "application/json")
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 300 {
+			// 错误响应为 {"error": "..."}，认证失败时可能是纯文本
+			var apiErr struct {
+				Error string `json:"error"`
+			}
+			if json.Unmarshal(data, &apiErr) != nil || apiErr.Error == "" {
+				apiErr.Error = strings.TrimSpace(string(data))
+			}
+			return &APIError{
+				StatusCode: resp.StatusCode,
+				Status:     resp.Status,
+				Errors:     []APIMessage{{Message: apiErr.Error}},
+				RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+				kind:       classify(resp.StatusCode, nil),
+			}
+		}
+		if result == nil || len(data) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(data, result); err != nil {
+			return fmt.Errorf("解析 PowerDNS 响应失败: %v", err)
+		}
+		return nil
+	})
+}
+
+// zonePath 返回 Zone 的 API 路径
+func zonePath(zoneID string) string {
+	return "/zones/" + url.PathEscape(zoneID)
+}
+
+// Name 返回服务商名称
+func (c *PowerDNSClient) Name() string {
+	return ProviderPowerDNS
+}
+
+// Capabilities 返回 PowerDNS 支持的功能
+func (c *PowerDNSClient) Capabilities() Capabilities {
+	return Capabilities{MultiValue: true, QuotedTXT: true, AutoTTL: powerdnsDefaultTTL}
+}
+
+// ListZones 获取服务器上的全部 Zone，Zone ID 为 PowerDNS 返回的 ID（如 example.com.）
+func (c *PowerDNSClient) ListZones() ([]Zone, error) {
+	var result []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := c.request(http.MethodGet, "/zones", nil, true, &result); err != nil {
+		return nil, fmt.Errorf("获取 PowerDNS Zone 列表失败: %w", err)
+	}
+	zones := make([]Zone, 0, len(result))
+	for _, zone := range result {
+		zones = append(zones, Zone{ID: zone.ID, Name: strings.ToLower(strings.TrimSuffix(zone.Name, "."))})
+	}
+	return zones, nil
+}
+
+// listRRSets 获取 Zone 中的记录集，name / recordType 不为空时只返回匹配的记录集
+func (c *PowerDNSClient) listRRSets(zoneID, name, recordType string) ([]powerdnsRRSet, error) {
+	query := url.Values{}
+	query.Set("rrsets", "true")
+	if name != "" {
+		// rrset_name / rrset_type 需要 PowerDNS 4.8 及以上，旧版本会忽略，下面仍按名称和类型筛选
+		query.Set("rrset_name", fqdn(name))
+		if recordType != "" {
+			query.Set("rrset_type", recordType)
+		}
+	}
+
+	var zone struct {
+		RRSets []powerdnsRRSet `json:"rrsets"`
+	}
+	if err := c.request(http.MethodGet, zonePath(zoneID)+"?"+query.Encode(), nil, true, &zone); err != nil {
+		return nil, err
+	}
+
+	var sets []powerdnsRRSet
+	for _, set := range zone.RRSets {
+		if name != "" && !strings.EqualFold(set.Name, fqdn(name)) || recordType != "" && set.Type != recordType {
+			continue
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+// ListRecords 获取 Zone 中符合筛选条件的记录，记录集中的每个值作为一条记录，记录 ID 即为值
+// 已禁用的值不会返回
+func (c *PowerDNSClient) ListRecords(zoneID string, filter RecordFilter) ([]DNSRecord, error) {
+	sets, err := c.listRRSets(zoneID, filter.Name, filter.Type)
+	if err != nil {
+		return nil, fmt.Errorf("获取DNS记录失败: %w", err)
+	}
+
+	var records []DNSRecord
+	for _, set := range sets {
+		for _, value := range set.Records {
+			if value.Disabled {
+				continue
+			}
+			records = append(records, DNSRecord{
+				ID:      value.Content,
+				Name:    strings.ToLower(strings.TrimSuffix(set.Name, ".")),
+				Type:    set.Type,
+				Content: value.Content,
+				TTL:     set.TTL,
+			})
+		}
+	}
+	return records, nil
+}
+
+// currentSet 返回记录集当前的值，不存在时返回 nil
+func (c *PowerDNSClient) currentSet(zoneID, name, recordType string) (*powerdnsRRSet, error) {
+	sets, err := c.listRRSets(zoneID, name, recordType)
+	if err != nil || len(sets) == 0 {
+		return nil, err
+	}
+	return &sets[0], nil
+}
+
+// UpsertRecord 将记录写入所属记录集：ID 为空时追加值，否则替换 ID 对应的值
+func (c *PowerDNSClient) UpsertRecord(zoneID string, record DNSRecord) error {
+	current, err := c.currentSet(zoneID, record.Name, record.Type)
+	if err != nil {
+		return fmt.Errorf("获取记录集失败: %w", err)
+	}
+
+	var values []string
+	if current != nil {
+		for _, value := range current.Records {
+			if !value.Disabled && value.Content != record.ID && value.Content != record.Content {
+				values = append(values, value.Content)
+			}
+		}
+	}
+	values = append(values, record.Content)
+
+	set := RecordSet{Name: record.Name, Type: record.Type, TTL: record.TTL, Values: values}
+	if err := c.ApplyRecordSets(zoneID, []RecordSet{set}); err != nil {
+		return fmt.Errorf("更新DNS记录失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteRecord 从所属记录集中移除记录的值，记录集为空时删除记录集
+func (c *PowerDNSClient) DeleteRecord(zoneID string, record DNSRecord) error {
+	current, err := c.currentSet(zoneID, record.Name, record.Type)
+	if err != nil {
+		return fmt.Errorf("获取记录集失败: %w", err)
+	}
+	if current == nil {
+		return nil
+	}
+
+	set := RecordSet{Name: record.Name, Type: record.Type, TTL: current.TTL}
+	for _, value := range current.Records {
+		if !value.Disabled && value.Content != record.ID && value.Content != record.Content {
+			set.Values = append(set.Values, value.Content)
+		}
+	}
+	if err := c.ApplyRecordSets(zoneID, []RecordSet{set}); err != nil {
+		return fmt.Errorf("删除DNS记录失败: %w", err)
+	}
+	return nil
+}
+
+// rrsetIndex 记录集的索引键
+func rrsetIndex(name, recordType string) string {
+	return strings.ToLower(fqdn(name)) + "/" + recordType
+}
+
+// disabledSets 返回各记录集当前已禁用的值，按记录集索引；只提交一个记录集时只查询该记录集
+func (c *PowerDNSClient) disabledSets(zoneID string, sets []RecordSet) (map[string]powerdnsRRSet, error) {
+	name, recordType := "", ""
+	if len(sets) == 1 {
+		name, recordType = sets[0].Name, sets[0].Type
+	}
+	current, err := c.listRRSets(zoneID, name, recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	disabled := make(map[string]powerdnsRRSet)
+	for _, set := range current {
+		kept := powerdnsRRSet{TTL: set.TTL}
+		for _, value := range set.Records {
+			if value.Disabled {
+				kept.Records = append(kept.Records, value)
+			}
+		}
+		if len(kept.Records) > 0 {
+			disabled[rrsetIndex(set.Name, set.Type)] = kept
+		}
+	}
+	return disabled, nil
+}
+
+// ApplyRecordSets 在一次 PATCH 中以 REPLACE 替换各记录集，值为空的记录集以 DELETE 删除
+// 记录集中已禁用的值不由 AutoCDN 管理，替换时原样保留，只剩已禁用的值时不删除记录集
+// 按配置在更新后执行 rectify 和 NOTIFY，这两步失败只记录警告，记录已经更新
+func (c *PowerDNSClient) ApplyRecordSets(zoneID string, sets []RecordSet) error {
+	disabled, err := c.disabledSets(zoneID, sets)
+	if err != nil {
+		return fmt.Errorf("获取记录集失败: %w", err)
+	}
+
+	rrsets := make([]powerdnsRRSet, 0, len(sets))
+	for _, set := range sets {
+		rrset := powerdnsRRSet{Name: fqdn(set.Name), Type: set.Type, ChangeType: "REPLACE", TTL: set.TTL, Records: []powerdnsRecord{}}
+		for _, value := range set.Values {
+			rrset.Records = append(rrset.Records, powerdnsRecord{Content: value})
+		}
+		kept := disabled[rrsetIndex(set.Name, set.Type)]
+		for _, value := range kept.Records {
+			if !contains(set.Values, value.Content) {
+				rrset.Records = append(rrset.Records, value)
+			}
+		}
+		switch {
+		case len(rrset.Records) == 0:
+			rrset.ChangeType = "DELETE"
+			rrset.TTL = 0
+		case len(set.Values) == 0 || rrset.TTL == 0:
+			rrset.TTL = kept.TTL
+		}
+		rrsets = append(rrsets, rrset)
+	}
+
+	// REPLACE 和 DELETE 重复提交结果相同，可以安全重试
+	body := map[string]interface{}{"rrsets": rrsets}
+	if err := c.request(http.MethodPatch, zonePath(zoneID), body, true, nil); err != nil {
+		return err
+	}
+
+	if c.cfg.Rectify {
+		if err := c.request(http.MethodPut, zonePath(zoneID)+"/rectify", nil, true, nil); err != nil {
+			log.Printf("警告: 记录已更新，但 Zone %s 的 rectify 失败: %v", zoneID, err)
+		}
+	}
+	if c.cfg.Notify {
+		if err := c.request(http.MethodPut, zonePath(zoneID)+"/notify", nil, true, nil); err != nil {
+			log.Printf("警告: 记录已更新，但通知 Zone %s 的从服务器失败: %v", zoneID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package cdn
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"AutoCDN/config"
+)
+
+// fakePowerDNS 简单的 PowerDNS HTTP API 模拟服务，只模拟 example.com. 一个 Zone
+type fakePowerDNS struct {
+	mu       sync.Mutex
+	apiKey   string
+	rrsets   map[string]powerdnsRRSet // 名称/类型 -> 记录集
+	patches  [][]powerdnsRRSet
+	rectify  int
+	notify   int
+	badNames bool // PATCH 时拒绝所有记录集 (422)
+}
+
+func (f *fakePowerDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fail := func(status int, message string) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+	}
+	if r.Header.Get("X-API-Key") != f.apiKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, "Unauthorized")
+		return
+	}
+
+	const zone = "/api/v1/servers/localhost/zones/example.com."
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/servers/localhost/zones":
+		json.NewEncoder(w).Encode([]map[string]string{{"id": "example.com.", "name": "example.com.", "kind": "Native"}})
+	case r.Method == http.MethodGet && r.URL.Path == zone:
+		keys := make([]string, 0, len(f.rrsets))
+		for key := range f.rrsets {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		sets := []powerdnsRRSet{}
+		for _, key := range keys {
+			set := f.rrsets[key]
+			if name := r.URL.Query().Get("rrset_name"); name != "" && set.Name != name {
+				continue
+			}
+			if t := r.URL.Query().Get("rrset_type"); t != "" && set.Type != t {
+				continue
+			}
+			sets = append(sets, set)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "example.com.", "name": "example.com.", "rrsets": sets})
+	case r.Method == http.MethodPatch && r.URL.Path == zone:
+		var body struct {
+			RRSets []powerdnsRRSet `json:"rrsets"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		for _, set := range body.RRSets {
+			if f.badNames || !strings.HasSuffix(set.Name, "example.com.") {
+				fail(http.StatusUnprocessableEntity, fmt.Sprintf("RRset %s IN %s: Name is out of zone", set.Name, set.Type))
+				return
+			}
+			if set.ChangeType != "REPLACE" && set.ChangeType != "DELETE" {
+				fail(http.StatusUnprocessableEntity, "Changetype not understood")
+				return
+			}
+		}
+		for _, set := range body.RRSets {
+			key := set.Name + "/" + set.Type
+			if set.ChangeType == "DELETE" || len(set.Records) == 0 {
+				delete(f.rrsets, key)
+				continue
+			}
+			set.ChangeType = ""
+			f.rrsets[key] = set
+		}
+		f.patches = append(f.patches, body.RRSets)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut && r.URL.Path == zone+"/rectify":
+		f.rectify++
+		json.NewEncoder(w).Encode(map[string]string{"result": "Rectified"})
+	case r.Method == http.MethodPut && r.URL.Path == zone+"/notify":
+		f.notify++
+		json.NewEncoder(w).Encode(map[string]string{"result": "Notification queued"})
+	default:
+		fail(http.StatusNotFound, "Could not find domain")
+	}
+}
+
+// values 返回记录集的值（已排序）
+func (f *fakePowerDNS) values(name, recordType string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var values []string
+	for _, record := range f.rrsets[name+"/"+recordType].Records {
+		values = append(values, record.Content)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func newTestPowerDNS(t *testing.T, cfg config.PowerDNSConfig) (*PowerDNSClient, *fakePowerDNS) {
+	t.Helper()
+	fake := &fakePowerDNS{apiKey: "test-key", rrsets: make(map[string]powerdnsRRSet)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	cfg.APIURL = server.URL
+	cfg.APIKey = "test-key"
+	client := NewPowerDNSClient(cfg, server.Client())
+	client.initialBackoff = time.Millisecond
+	return client, fake
+}
+
+func TestPowerDNSPublish(t *testing.T) {
+	client, fake := newTestPowerDNS(t, config.PowerDNSConfig{Rectify: true, Notify: true})
+	fake.rrsets["a.example.com./A"] = powerdnsRRSet{Name: "a.example.com.", Type: "A", TTL: 300, Records: []powerdnsRecord{{Content: "9.9.9.9"}}}
+	fake.rrsets["_autocdn.a.example.com./TXT"] = powerdnsRRSet{Name: "_autocdn.a.example.com.", Type: "TXT", TTL: 300, Records: []powerdnsRecord{{Content: `"autocdn-owner type=A"`}}}
+	fake.rrsets["example.com./SOA"] = powerdnsRRSet{Name: "example.com.", Type: "SOA", TTL: 3600, Records: []powerdnsRecord{{Content: "ns1.example.com. hostmaster.example.com. 1 10800 3600 604800 3600"}}}
+
+	publisher := NewPublisher(client, config.CloudflareConfig{
+		Domains:       []config.DomainConfig{{Name: "a.example.com", TTL: 300}, {Name: "b.example.com", TTL: config.TTLAuto}},
+		RecordSetSize: 2,
+		SnapshotDir:   t.TempDir(),
+	})
+	if err := publisher.HandleDNSRecords(speedSet("1.1.1.1", "2.2.2.2")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+
+	// 两个记录集在一次 PATCH 中以 REPLACE 提交，随后执行 rectify 和 NOTIFY，再为新建的 b 写入管理声明
+	if len(fake.patches) != 2 || len(fake.patches[0]) != 2 || fake.patches[0][0].ChangeType != "REPLACE" {
+		t.Fatalf("期望 1 次包含 2 个 REPLACE 的 PATCH，实际 %+v", fake.patches)
+	}
+	if claim := fake.patches[1]; len(claim) != 1 || claim[0].Name != "_autocdn.b.example.com." || claim[0].Type != "TXT" {
+		t.Fatalf("管理声明的 PATCH 为 %+v", claim)
+	}
+	if fake.rectify != 2 || fake.notify != 2 {
+		t.Fatalf("rectify %d 次，notify %d 次", fake.rectify, fake.notify)
+	}
+	for _, name := range []string{"a.example.com.", "b.example.com."} {
+		if got := fake.values(name, "A"); !reflect.DeepEqual(got, []string{"1.1.1.1", "2.2.2.2"}) {
+			t.Fatalf("%s 的记录集为 %v", name, got)
+		}
+	}
+	if ttl := fake.rrsets["b.example.com./A"].TTL; ttl != powerdnsDefaultTTL {
+		t.Fatalf("ttl: auto 的记录集 TTL 为 %d", ttl)
+	}
+
+	plan, err := publisher.PlanDNSRecords(speedSet("1.1.1.1", "2.2.2.2"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if plan.Changes() != 0 {
+		t.Fatalf("期望无需变更，实际:\n%v", plan.Lines())
+	}
+
+	// 回滚：a 恢复原值，b 的记录集被删除
+	snapshots, err := publisher.ListSnapshots()
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("期望 1 个快照，实际 %d 个 (%v)", len(snapshots), err)
+	}
+	if err := publisher.RestoreSnapshot(snapshots[0].ID); err != nil {
+		t.Fatalf("恢复快照失败: %v", err)
+	}
+	if got := fake.values("a.example.com.", "A"); !reflect.DeepEqual(got, []string{"9.9.9.9"}) {
+		t.Fatalf("恢复后 a 的记录集为 %v", got)
+	}
+	if _, ok := fake.rrsets["b.example.com./A"]; ok {
+		t.Fatal("恢复后 b 的记录集仍存在")
+	}
+}
+
+func TestPowerDNSRecords(t *testing.T) {
+	client, fake := newTestPowerDNS(t, config.PowerDNSConfig{})
+	fake.rrsets["cdn.example.com./A"] = powerdnsRRSet{Name: "cdn.example.com.", Type: "A", TTL: 60, Records: []powerdnsRecord{{Content: "1.1.1.1"}, {Content: "2.2.2.2"}, {Content: "7.7.7.7", Disabled: true}}}
+
+	records, err := client.ListRecords("example.com.", RecordFilter{Name: "cdn.example.com", Type: "A"})
+	if err != nil || len(records) != 2 {
+		t.Fatalf("期望 2 条启用的记录，实际 %+v (%v)", records, err)
+	}
+
+	if err := client.UpsertRecord("example.com.", DNSRecord{ID: "2.2.2.2", Name: "cdn.example.com", Type: "A", Content: "3.3.3.3", TTL: 60}); err != nil {
+		t.Fatalf("更新记录失败: %v", err)
+	}
+	// 已禁用的值由其他人管理，替换记录集时保留
+	if got := fake.values("cdn.example.com.", "A"); !reflect.DeepEqual(got, []string{"1.1.1.1", "3.3.3.3", "7.7.7.7"}) {
+		t.Fatalf("更新后的记录集为 %v", got)
+	}
+	for _, value := range []string{"1.1.1.1", "3.3.3.3"} {
+		if err := client.DeleteRecord("example.com.", DNSRecord{ID: value, Name: "cdn.example.com", Type: "A", Content: value}); err != nil {
+			t.Fatalf("删除记录失败: %v", err)
+		}
+	}
+	fake.mu.Lock()
+	set := fake.rrsets["cdn.example.com./A"]
+	fake.mu.Unlock()
+	if len(set.Records) != 1 || !set.Records[0].Disabled || set.TTL != 60 {
+		t.Fatalf("删除全部启用的值后记录集为 %+v，期望只保留已禁用的值", set)
+	}
+
+	if err := client.ApplyRecordSets("example.com.", []RecordSet{{Name: "tmp.example.com", Type: "A", TTL: 60, Values: []string{"4.4.4.4"}}}); err != nil {
+		t.Fatalf("提交记录集失败: %v", err)
+	}
+	if err := client.DeleteRecord("example.com.", DNSRecord{ID: "4.4.4.4", Name: "tmp.example.com", Type: "A", Content: "4.4.4.4"}); err != nil {
+		t.Fatalf("删除记录失败: %v", err)
+	}
+	fake.mu.Lock()
+	_, ok := fake.rrsets["tmp.example.com./A"]
+	fake.mu.Unlock()
+	if ok {
+		t.Fatal("最后一个值删除后记录集仍存在")
+	}
+}
+
+func TestPowerDNSErrors(t *testing.T) {
+	client, fake := newTestPowerDNS(t, config.PowerDNSConfig{})
+
+	fake.badNames = true
+	err := client.ApplyRecordSets("example.com.", []RecordSet{{Name: "cdn.example.com", Type: "A", TTL: 60, Values: []string{"1.1.1.1"}}})
+	if !errors.Is(err, ErrValidation) || !strings.Contains(err.Error(), "out of zone") {
+		t.Fatalf("期望参数错误，实际 %v", err)
+	}
+
+	if _, err := client.ListRecords("other.com.", RecordFilter{}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("期望 Zone 不存在错误，实际 %v", err)
+	}
+
+	fake.apiKey = "other"
+	if _, err := client.ListZones(); !errors.Is(err, ErrAuth) {
+		t.Fatalf("期望认证错误，实际 %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package cdn
+
+import (
+	"errors"
+	"fmt"
+
+	"AutoCDN/config"
+)
+
+// 支持的 DNS 服务商
+const (
+	ProviderCloudflare = "cloudflare"
+	ProviderDNSPod     = "dnspod"
+	ProviderAliDNS     = "alidns"
+	ProviderRoute53    = "route53"
+	ProviderRFC2136    = "rfc2136"
+	ProviderPowerDNS   = "powerdns"
+)
+
+// Capabilities DNS 服务商支持的功能
+type Capabilities struct {
+	MultiValue bool // 同名同类型可以有多条记录（记录集 / DNS 轮询）
+	Proxied    bool // 支持代理开关（Cloudflare 橙色云朵）
+	Comments   bool // 支持记录备注
+	Tags       bool // 支持记录标签
+	Lines      bool // 支持按运营商线路解析
+	NameLookup bool // 只能按记录名查询记录，无法列出整个 Zone（如 RFC 2136）
+	HTTPS      bool // 支持 HTTPS (SVCB) 记录
+	QuotedTXT  bool // TXT 记录内容需要带引号，超过 255 字节时拆分为多个字符串（如 Route 53、PowerDNS）
+
+	// AutoTTL 不支持自动 TTL 时 ttl: auto 对应的 TTL，0 表示支持自动 TTL
+	AutoTTL int
+}
+
+// Provider 权威 DNS 服务商
+// 发布流程（计划、快照、回滚）只通过该接口读写记录，与具体服务商无关
+type Provider interface {
+	// Name 返回服务商名称
+	Name() string
+	// Capabilities 返回服务商支持的功能
+	Capabilities() Capabilities
+	// ListZones 返回当前凭据可访问的全部 Zone，用于确定域名所属的 Zone
+	ListZones() ([]Zone, error)
+	// ListRecords 返回 Zone 中符合筛选条件的记录
+	ListRecords(zoneID string, filter RecordFilter) ([]DNSRecord, error)
+	// UpsertRecord 创建记录（ID 为空）或更新指定 ID 的记录
+	UpsertRecord(zoneID string, record DNSRecord) error
+	// DeleteRecord 删除记录
+	DeleteRecord(zoneID string, record DNSRecord) error
+}
+
+// RecordSet 同名同类型的一组记录
+type RecordSet struct {
+	Name   string
+	Type   string
+	TTL    int
+	Values []string // 为空表示删除该记录集
+}
+
+// RecordSetProvider 以记录集为单位写入的服务商（如 Route 53、PowerDNS）
+// 发布时同一 Zone 的全部变更合并为记录集一次提交，而不是逐条修改记录
+type RecordSetProvider interface {
+	Provider
+	// ApplyRecordSets 将 Zone 中的各记录集替换为给定的值
+	ApplyRecordSets(zoneID string, sets []RecordSet) error
+}
+
+// RecordBatch 同一 Zone 中需要一次提交的一组记录变更
+type RecordBatch struct {
+	Deletes []DNSRecord
+	Updates []DNSRecord // ID 为要修改的记录
+	Creates []DNSRecord
+}
+
+// Size 返回变更数量
+func (b RecordBatch) Size() int {
+	return len(b.Deletes) + len(b.Updates) + len(b.Creates)
+}
+
+// ErrBatchUnsupported 服务商的批量接口不可用，发布流程会退回逐条修改
+var ErrBatchUnsupported = errors.New("批量接口不可用")
+
+// BatchProvider 支持在一次请求中原子提交多项记录变更的服务商（如 Cloudflare dns_records/batch）
+// 发布时同一 Zone 的全部变更要么全部生效，要么全部不生效
+type BatchProvider interface {
+	Provider
+	// ApplyBatch 原子地执行一组变更，批量接口不可用时返回包装了 ErrBatchUnsupported 的错误
+	ApplyBatch(zoneID string, batch RecordBatch) error
+}
+
+// NewProvider 根据配置中的 provider 字段创建 DNS 服务商，未填写时使用 Cloudflare
+func NewProvider(cfg *config.Config) (Provider, error) {
+	switch cfg.ProviderName() {
+	case ProviderCloudflare:
+		return NewClient(apiBaseURL, cfg.Cloudflare, nil), nil
+	case ProviderDNSPod:
+		if cfg.DNSPod.LoginToken() == "" {
+			return nil, fmt.Errorf("未配置 DNSPod API Token")
+		}
+		return NewDNSPodClient(dnspodBaseURL, cfg.DNSPod, nil), nil
+	case ProviderAliDNS:
+		if cfg.AliDNS.AccessKeyID == "" || cfg.AliDNS.AccessKeySecret == "" {
+			return nil, fmt.Errorf("未配置阿里云 AccessKey")
+		}
+		return NewAliDNSClient(alidnsBaseURL, cfg.AliDNS, nil), nil
+	case ProviderRoute53:
+		if cfg.Route53.AccessKeyID == "" || cfg.Route53.SecretAccessKey == "" {
+			return nil, fmt.Errorf("未配置 AWS Access Key")
+		}
+		return NewRoute53Client(cfg.Route53, nil), nil
+	case ProviderRFC2136:
+		client, err := NewRFC2136Client(cfg.RFC2136)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case ProviderPowerDNS:
+		if cfg.PowerDNS.APIURL == "" || cfg.PowerDNS.APIKey == "" {
+			return nil, fmt.Errorf("未配置 PowerDNS API 地址或 API Key")
+		}
+		return NewPowerDNSClient(cfg.PowerDNS, nil), nil
+	}
+	return nil, fmt.Errorf("不支持的 DNS 服务商: %s", cfg.Provider)
+}
+
+// normalize 去掉服务商不支持的记录选项，避免计划中出现永远无法同步的变更
+func (c Capabilities) normalize(domain config.DomainConfig) config.DomainConfig {
+	if !c.Proxied {
+		domain.Proxied = nil
+	}
+	if !c.Comments {
+		domain.Comment = ""
+	}
+	if !c.Tags {
+		domain.Tags = nil
+	}
+	if c.Lines {
+		domain.Line = domain.RecordLine()
+	} else {
+		domain.Line = ""
+	}
+	if domain.TTL == config.TTLAuto && c.AutoTTL > 0 {
+		domain.TTL = config.TTL(c.AutoTTL)
+	}
+	return domain
+}
+
+// recordFromAction 根据计划中的动作生成要提交的记录
+func recordFromAction(action PlanAction) DNSRecord {
+	return DNSRecord{
+		ID:      action.RecordID,
+		Name:    action.Name,
+		Type:    action.Type,
+		Content: action.NewContent,
+		TTL:     action.domain.RecordTTL(),
+		Proxied: action.domain.IsProxied(),
+		Comment: action.domain.Comment,
+		Tags:    action.domain.Tags,
+		Line:    action.Line,
+	}
+}
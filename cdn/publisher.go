@@ -0,0 +1,45 @@
+package cdn
+
+import (
+	"crypto/x509"
+	"time"
+
+	"AutoCDN/config"
+)
+
+// Publisher 将测速结果发布到 DNS 服务商，负责生成计划、保存快照和回滚
+type Publisher struct {
+	provider Provider
+	cf       config.CloudflareConfig // 域名、记录集、快照和防抖等发布设置
+
+	verifyRootCAs *x509.CertPool      // 发布后校验使用的根证书，为空时使用系统根证书
+	sleep         func(time.Duration) // 金丝雀观察的轮间等待
+}
+
+// NewPublisher 创建发布器
+func NewPublisher(provider Provider, cf config.CloudflareConfig) *Publisher {
+	return &Publisher{provider: provider, cf: cf, sleep: time.Sleep}
+}
+
+// DefaultPublisher 使用全局配置创建发布器
+func DefaultPublisher() (*Publisher, error) {
+	return PublisherFor(config.GetConfig())
+}
+
+// PublisherFor 使用指定配置创建发布器，不修改全局配置
+func PublisherFor(cfg *config.Config) (*Publisher, error) {
+	provider, err := NewProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cf := cfg.Cloudflare
+	if cf.Metadata.ConfigName == "" {
+		cf.Metadata.ConfigName = cfg.Name
+	}
+	return NewPublisher(provider, cf), nil
+}
+
+// Provider 返回发布器使用的 DNS 服务商
+func (p *Publisher) Provider() Provider {
+	return p.provider
+}
@@ -0,0 +1,186 @@
+package cdn
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"AutoCDN/config"
+)
+
+// ledgerChange 一个域名的一次地址记录变更
+type ledgerChange struct {
+	Time    time.Time `json:"time"`
+	Name    string    `json:"name"`
+	Type    string    `json:"type"`
+	Line    string    `json:"line,omitempty"`
+	Added   []string  `json:"added,omitempty"`   // 新发布的 IP
+	Removed []string  `json:"removed,omitempty"` // 撤下的 IP
+}
+
+// changeLedger 持久化的变更记录，用于限制每个域名的变更频率
+type changeLedger struct {
+	Changes []ledgerChange `json:"changes"`
+}
+
+// count 返回域名在 since 之后的变更次数
+func (l *changeLedger) count(name, recordType, line string, since time.Time) int {
+	n := 0
+	for _, change := range l.Changes {
+		if change.Name == name && change.Type == recordType && change.Line == line && change.Time.After(since) {
+			n++
+		}
+	}
+	return n
+}
+
+// publishedAt 返回 IP 最近一次发布到域名的时间，没有记录时返回零值
+func (l *changeLedger) publishedAt(name, recordType, line, ip string) time.Time {
+	var at time.Time
+	for _, change := range l.Changes {
+		if change.Name == name && change.Type == recordType && change.Line == line && contains(change.Added, ip) && change.Time.After(at) {
+			at = change.Time
+		}
+	}
+	return at
+}
+
+// loadLedger 读取变更记录，文件不存在时返回空记录
+func (p *Publisher) loadLedger() (*changeLedger, error) {
+	path := p.cf.RateLimit.LedgerPath()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &changeLedger{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取变更记录 %s 失败: %v", path, err)
+	}
+	var ledger changeLedger
+	if err := json.Unmarshal(data, &ledger); err != nil {
+		return nil, fmt.Errorf("解析变更记录 %s 失败: %v", path, err)
+	}
+	return &ledger, nil
+}
+
+// recordChanges 将计划中已执行的地址记录变更追加到变更记录
+// 同时清理既超出统计窗口、又超出最短停留时间的旧记录
+func (p *Publisher) recordChanges(plan *Plan, now time.Time) error {
+	ledger, err := p.loadLedger()
+	if err != nil {
+		return err
+	}
+
+	type domainKey struct{ name, line string }
+	var keys []domainKey
+	changes := make(map[domainKey]*ledgerChange)
+	for _, action := range plan.Actions {
+		if action.Action == ActionNoop || (action.Action == ActionUpdate && action.OldContent == action.NewContent) {
+			continue
+		}
+		key := domainKey{action.Name, action.Line}
+		change, ok := changes[key]
+		if !ok {
+			change = &ledgerChange{Time: now, Name: action.Name, Type: plan.Type, Line: action.Line}
+			changes[key] = change
+			keys = append(keys, key)
+		}
+		if action.Action != ActionDelete {
+			change.Added = append(change.Added, action.NewContent)
+		}
+		if action.Action != ActionCreate {
+			change.Removed = append(change.Removed, action.OldContent)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	rl := p.cf.RateLimit
+	keep := time.Duration(rl.WindowMinutes()) * time.Minute
+	if dwell := time.Duration(rl.MinDwell) * time.Minute; dwell > keep {
+		keep = dwell
+	}
+	var kept []ledgerChange
+	for _, change := range ledger.Changes {
+		if now.Sub(change.Time) < keep {
+			kept = append(kept, change)
+		}
+	}
+	for _, key := range keys {
+		kept = append(kept, *changes[key])
+	}
+	ledger.Changes = kept
+
+	data, err := json.MarshalIndent(ledger, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := rl.LedgerPath()
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// limitChanges 按变更频率限制调整域名的目标 IP，返回调整后的 IP 和暂缓的原因
+// 未达到最短停留时间的当前 IP 替换排名最低的新候选 IP；窗口内变更次数达到上限时保持当前 IP 不变
+// 变更记录中没有发布时间的当前 IP（如启用限制之前发布的）不受最短停留时间限制
+func limitChanges(ledger *changeLedger, rl config.RateLimitConfig, recordType string, domain config.DomainConfig, current, wanted []string, now time.Time) ([]string, []string) {
+	result := append([]string(nil), wanted...)
+	label := PlanAction{Name: domain.Name, Line: domain.Line}.label()
+	var held []string
+
+	if rl.MinDwell > 0 {
+		dwell := time.Duration(rl.MinDwell) * time.Minute
+		for _, cur := range current {
+			if contains(result, cur) {
+				continue
+			}
+			at := ledger.publishedAt(domain.Name, recordType, domain.Line, cur)
+			if at.IsZero() || now.Sub(at) >= dwell {
+				continue
+			}
+			replaced := false
+			for i := len(result) - 1; i >= 0; i-- {
+				if !contains(current, result[i]) {
+					result[i] = cur
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				result = append(result, cur)
+			}
+			held = append(held, fmt.Sprintf("%s 保持 %s: 发布于 %d 分钟前，未达到最短停留时间 %d 分钟",
+				label, cur, int(now.Sub(at).Minutes()), rl.MinDwell))
+		}
+	}
+
+	if rl.MaxChanges > 0 && len(current) > 0 && !sameIPs(current, result) {
+		window := rl.WindowMinutes()
+		if n := ledger.count(domain.Name, recordType, domain.Line, now.Add(-time.Duration(window)*time.Minute)); n >= rl.MaxChanges {
+			held = append(held, fmt.Sprintf("%s 保持 %s: %d 分钟内已变更 %d 次，达到上限 %d 次",
+				label, strings.Join(current, ", "), window, n, rl.MaxChanges))
+			return append([]string(nil), current...), held
+		}
+	}
+	return result, held
+}
+
+// sameIPs 判断两个 IP 列表包含的 IP 是否相同（不考虑顺序）
+func sameIPs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, ip := range a {
+		if !contains(b, ip) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package cdn
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"AutoCDN/config"
+)
+
+// ageLedger 将变更记录中的时间提前指定时长，模拟时间流逝
+func ageLedger(t *testing.T, publisher *Publisher, d time.Duration) {
+	t.Helper()
+	ledger, err := publisher.loadLedger()
+	if err != nil {
+		t.Fatalf("读取变更记录失败: %v", err)
+	}
+	for i := range ledger.Changes {
+		ledger.Changes[i].Time = ledger.Changes[i].Time.Add(-d)
+	}
+	data, _ := json.Marshal(ledger)
+	if err := os.WriteFile(publisher.cf.RateLimit.LedgerPath(), data, 0644); err != nil {
+		t.Fatalf("写入变更记录失败: %v", err)
+	}
+}
+
+func TestRateLimitMinDwell(t *testing.T) {
+	publisher, server := newTestPublisher(t, config.CloudflareConfig{
+		Domains:   []config.DomainConfig{{Name: "a.example.com"}},
+		RateLimit: config.RateLimitConfig{MinDwell: 30, LedgerFile: filepath.Join(t.TempDir(), "ledger.json")},
+	})
+	if err := publisher.HandleDNSRecords(speedSet("1.1.1.1")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+
+	// 1.1.1.1 刚发布，暂缓替换
+	plan, err := publisher.PlanDNSRecords(speedSet("2.2.2.2"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if plan.Changes() != 0 || len(plan.Held) != 1 || !strings.Contains(plan.Held[0], "a.example.com 保持 1.1.1.1: 发布于 0 分钟前，未达到最短停留时间 30 分钟") {
+		t.Fatalf("计划为 %v", plan.Lines())
+	}
+
+	// 超过最短停留时间后正常替换
+	ageLedger(t, publisher, 40*time.Minute)
+	if err := publisher.HandleDNSRecords(speedSet("2.2.2.2")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if got := server.Contents("zone1", "a.example.com", "A"); !reflect.DeepEqual(got, []string{"2.2.2.2"}) {
+		t.Fatalf("记录为 %v", got)
+	}
+}
+
+func TestRateLimitMaxChanges(t *testing.T) {
+	publisher, server := newTestPublisher(t, config.CloudflareConfig{
+		Domains:       []config.DomainConfig{{Name: "a.example.com"}, {Name: "b.example.com"}},
+		RecordSetSize: 2,
+		RateLimit:     config.RateLimitConfig{MaxChanges: 2, Window: 60, LedgerFile: filepath.Join(t.TempDir(), "ledger.json")},
+	})
+	for _, ips := range [][]string{{"1.1.1.1", "2.2.2.2"}, {"3.3.3.3", "1.1.1.1"}} {
+		if err := publisher.HandleDNSRecords(speedSet(ips...)); err != nil {
+			t.Fatalf("发布失败: %v", err)
+		}
+	}
+
+	// 窗口内已变更 2 次，全部域名保持当前记录
+	plan, err := publisher.PlanDNSRecords(speedSet("4.4.4.4", "1.1.1.1"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if plan.Changes() != 0 || len(plan.Held) != 2 || !strings.Contains(plan.Held[0], "60 分钟内已变更 2 次，达到上限 2 次") {
+		t.Fatalf("计划为 %v", plan.Lines())
+	}
+	if !strings.Contains(strings.Join(plan.Lines(), "\n"), "  ! 暂缓 a.example.com 保持 1.1.1.1, 3.3.3.3") {
+		t.Fatalf("计划描述为 %v", plan.Lines())
+	}
+
+	// 旧变更移出窗口后正常发布，并清理过期的变更记录
+	ageLedger(t, publisher, 90*time.Minute)
+	if err := publisher.HandleDNSRecords(speedSet("4.4.4.4", "1.1.1.1")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if got := server.Contents("zone1", "b.example.com", "A"); !reflect.DeepEqual(got, []string{"1.1.1.1", "4.4.4.4"}) {
+		t.Fatalf("记录为 %v", got)
+	}
+	ledger, err := publisher.loadLedger()
+	if err != nil || len(ledger.Changes) != 2 || !reflect.DeepEqual(ledger.Changes[0].Added, []string{"4.4.4.4"}) || !reflect.DeepEqual(ledger.Changes[0].Removed, []string{"3.3.3.3"}) {
+		t.Fatalf("变更记录为 %+v (%v)", ledger, err)
+	}
+}
@@ -0,0 +1,90 @@
+package cdn
+
+import "AutoCDN/config"
+
+// diffRecordSet 计算将现有记录调和为目标 IP 集合所需的变更
+// 已指向目标 IP 的记录保持不动；多余的记录优先原地更新为缺失的 IP，仍有剩余则删除；不足时创建新记录
+func diffRecordSet(existing []DNSRecord, ips []string, domain config.DomainConfig) []PlanAction {
+	wanted := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		wanted[ip] = true
+	}
+
+	// 找出已满足的 IP 和可复用的记录
+	var actions []PlanAction
+	satisfied := make(map[string]bool, len(ips))
+	var reusable []DNSRecord
+	for _, record := range existing {
+		if wanted[record.Content] && !satisfied[record.Content] {
+			satisfied[record.Content] = true
+			action := PlanAction{Action: ActionNoop, RecordID: record.ID, OldContent: record.Content, NewContent: record.Content}
+			if !recordMatches(record, record.Content, domain) {
+				// 内容一致但 TTL / 代理等选项需要同步
+				action.Action = ActionUpdate
+			}
+			actions = append(actions, action)
+			continue
+		}
+		reusable = append(reusable, record)
+	}
+
+	for _, ip := range ips {
+		if satisfied[ip] {
+			continue
+		}
+		satisfied[ip] = true
+		if len(reusable) > 0 {
+			actions = append(actions, PlanAction{Action: ActionUpdate, RecordID: reusable[0].ID, OldContent: reusable[0].Content, NewContent: ip})
+			reusable = reusable[1:]
+		} else {
+			actions = append(actions, PlanAction{Action: ActionCreate, NewContent: ip})
+		}
+	}
+	for _, record := range reusable {
+		actions = append(actions, PlanAction{Action: ActionDelete, RecordID: record.ID, OldContent: record.Content})
+	}
+	return actions
+}
+
+// groupRecordsByName 按记录名分组记录
+func groupRecordsByName(records []DNSRecord) map[string][]DNSRecord {
+	groups := make(map[string][]DNSRecord)
+	for _, record := range records {
+		groups[record.Name] = append(groups[record.Name], record)
+	}
+	return groups
+}
+
+// topIPs 返回前 n 个 IP，数量不足时返回全部
+func topIPs(ipList []string, n int) []string {
+	if n > len(ipList) {
+		n = len(ipList)
+	}
+	return ipList[:n]
+}
+
+// filterLine 返回指定解析线路的记录
+func filterLine(records []DNSRecord, line string) []DNSRecord {
+	var filtered []DNSRecord
+	for _, record := range records {
+		if record.Line == line {
+			filtered = append(filtered, record)
+		}
+	}
+	return filtered
+}
+
+// recordLines 返回记录中出现的解析线路，按首次出现的顺序
+func recordLines(groups ...[]DNSRecord) []string {
+	var lines []string
+	seen := make(map[string]bool)
+	for _, records := range groups {
+		for _, record := range records {
+			if !seen[record.Line] {
+				seen[record.Line] = true
+				lines = append(lines, record.Line)
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,411 @@
+package cdn
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"AutoCDN/config"
+)
+
+// RFC 2136 设置
+const (
+	rfc2136DefaultTTL     = 300 // ttl: auto 时使用的 TTL
+	rfc2136DefaultTimeout = 10  // 默认单次请求超时秒数
+	dnsUDPSize            = 512 // 超过该长度的报文直接使用 TCP 发送
+)
+
+// dnsTypes 支持的记录类型
+var dnsTypes = map[string]uint16{
+	"A":    dnsTypeA,
+	"AAAA": dnsTypeAAAA,
+	"TXT":  dnsTypeTXT,
+}
+
+// RFC2136Client 通过 RFC 2136 动态更新 (DNS UPDATE) 修改自建权威 DNS 服务器上的记录
+// 全部报文使用 TSIG (HMAC-SHA256) 签名；服务器不支持列出整个 Zone，记录按名称查询
+type RFC2136Client struct {
+	retryPolicy
+	server  string
+	zones   []string
+	key     tsigKey
+	timeout time.Duration
+}
+
+// NewRFC2136Client 创建 RFC 2136 客户端
+func NewRFC2136Client(cfg config.RFC2136Config) (*RFC2136Client, error) {
+	if cfg.Server == "" {
+		return nil, fmt.Errorf("未配置 RFC 2136 主服务器地址")
+	}
+	if len(cfg.Zones) == 0 {
+		return nil, fmt.Errorf("未配置 RFC 2136 Zone")
+	}
+	if cfg.KeyName == "" || cfg.Secret == "" {
+		return nil, fmt.Errorf("未配置 TSIG 密钥")
+	}
+	secret, err := base64.StdEncoding.DecodeString(cfg.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("TSIG 密钥不是有效的 base64: %v", err)
+	}
+
+	server := cfg.Server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = rfc2136DefaultTimeout
+	}
+	zones := make([]string, 0, len(cfg.Zones))
+	for _, zone := range cfg.Zones {
+		zones = append(zones, strings.ToLower(strings.TrimSuffix(zone, ".")))
+	}
+	return &RFC2136Client{
+		retryPolicy: defaultRetryPolicy(),
+		server:      server,
+		zones:       zones,
+		key:         tsigKey{Name: cfg.KeyName, Secret: secret},
+		timeout:     time.Duration(timeout) * time.Second,
+	}, nil
+}
+
+// rcodeError 将 DNS 响应码转换为 APIError
+func rcodeError(rcode, tsigErr int) *APIError {
+	kind := ErrValidation
+	switch rcode {
+	case dnsRcodeNotAuth, dnsRcodeRefused:
+		kind = ErrAuth
+	case dnsRcodeNotZone, dnsRcodeNXDomain:
+		kind = ErrNotFound
+	case dnsRcodeServFail:
+		kind = ErrServer
+	}
+	status := dnsRcodeName(rcode)
+	if tsigErr != 0 {
+		status += "/" + dnsRcodeName(tsigErr)
+	}
+	return &APIError{Status: status, kind: kind}
+}
+
+// exchange 签名并发送报文，网络错误和 SERVFAIL 时重试
+// 替换记录集和按值增删记录重复执行的结果相同，更新报文也可以安全重试
+func (c *RFC2136Client) exchange(msg *dnsMessage) (*dnsMessage, error) {
+	var reply *dnsMessage
+	err := c.retry(true, func() error {
+		var err error
+		reply, err = c.send(msg)
+		return err
+	})
+	return reply, err
+}
+
+// send 发送一次请求并校验响应签名
+func (c *RFC2136Client) send(msg *dnsMessage) (*dnsMessage, error) {
+	var id [2]byte
+	rand.Read(id[:])
+	msg.ID = binary.BigEndian.Uint16(id[:])
+
+	data, err := msg.pack()
+	if err != nil {
+		return nil, err
+	}
+	signed, requestMAC := c.key.sign(data, nil, time.Now())
+
+	var resp []byte
+	if len(signed) > dnsUDPSize {
+		resp, err = c.transfer("tcp", signed)
+	} else {
+		resp, err = c.transfer("udp", signed)
+		if err == nil && len(resp) > 2 && resp[2]&0x02 != 0 {
+			// 响应被截断 (TC)，改用 TCP 重新发送
+			resp, err = c.transfer("tcp", signed)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := unpackDNSMessage(resp)
+	if err != nil {
+		return nil, fmt.Errorf("解析 DNS 响应失败: %v", err)
+	}
+	if reply.ID != msg.ID || !reply.Response {
+		return nil, fmt.Errorf("DNS 响应与请求不匹配")
+	}
+	// 密钥无效等情况下服务器返回未签名的错误响应
+	if reply.tsig == nil && reply.Rcode != dnsRcodeSuccess {
+		return nil, rcodeError(reply.Rcode, 0)
+	}
+	if tsigErr, err := c.key.verify(resp, reply, requestMAC, time.Now()); err != nil {
+		apiErr := rcodeError(dnsRcodeNotAuth, tsigErr)
+		if reply.Rcode != dnsRcodeSuccess {
+			apiErr = rcodeError(reply.Rcode, tsigErr)
+		}
+		apiErr.Errors = []APIMessage{{Message: err.Error()}}
+		return nil, apiErr
+	}
+	if reply.Rcode != dnsRcodeSuccess && !(msg.Opcode == dnsOpcodeQuery && reply.Rcode == dnsRcodeNXDomain) {
+		return nil, rcodeError(reply.Rcode, 0)
+	}
+	return reply, nil
+}
+
+// transfer 通过 UDP 或 TCP 发送报文并读取响应
+func (c *RFC2136Client) transfer(network string, data []byte) ([]byte, error) {
+	conn, err := net.DialTimeout(network, c.server, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	if network == "tcp" {
+		// TCP 报文前有两字节长度
+		if _, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(data))), data...)); err != nil {
+			return nil, err
+		}
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return nil, err
+		}
+		resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	if _, err := conn.Write(data); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		// 忽略 ID 不匹配的迟到响应
+		if n >= 12 && binary.BigEndian.Uint16(buf) == binary.BigEndian.Uint16(data) {
+			return append([]byte(nil), buf[:n]...), nil
+		}
+	}
+}
+
+// Name 返回服务商名称
+func (c *RFC2136Client) Name() string {
+	return ProviderRFC2136
+}
+
+// Capabilities 返回 RFC 2136 支持的功能
+func (c *RFC2136Client) Capabilities() Capabilities {
+	return Capabilities{MultiValue: true, NameLookup: true, AutoTTL: rfc2136DefaultTTL}
+}
+
+// ListZones 返回配置中的 Zone，Zone ID 即为 Zone 名称
+func (c *RFC2136Client) ListZones() ([]Zone, error) {
+	zones := make([]Zone, 0, len(c.zones))
+	for _, zone := range c.zones {
+		zones = append(zones, Zone{ID: zone, Name: zone})
+	}
+	return zones, nil
+}
+
+// rdata 将记录内容编码为 RDATA，TXT 记录的内容按 255 字节拆分为多个字符串
+func rdata(recordType, content string) ([]byte, error) {
+	if recordType == "TXT" {
+		var data []byte
+		for {
+			chunk := content
+			if len(chunk) > txtChunkSize {
+				chunk = chunk[:txtChunkSize]
+			}
+			data = append(append(data, byte(len(chunk))), chunk...)
+			if content = content[len(chunk):]; content == "" {
+				return data, nil
+			}
+		}
+	}
+
+	ip := net.ParseIP(content)
+	switch {
+	case recordType == "A" && ip != nil && ip.To4() != nil:
+		return ip.To4(), nil
+	case recordType == "AAAA" && ip != nil && ip.To4() == nil:
+		return ip.To16(), nil
+	}
+	return nil, fmt.Errorf("无效的 %s 记录内容: %s", recordType, content)
+}
+
+// rdataContent 将 RDATA 解码为记录内容，TXT 记录的多个字符串直接拼接
+func rdataContent(recordType string, data []byte) string {
+	if recordType != "TXT" {
+		return net.IP(data).String()
+	}
+	var content []byte
+	for len(data) > 0 {
+		n := int(data[0])
+		if n >= len(data) {
+			n = len(data) - 1
+		}
+		content = append(content, data[1:1+n]...)
+		data = data[1+n:]
+	}
+	return string(content)
+}
+
+// ListRecords 向主服务器查询记录名的 A / AAAA / TXT 记录，未指定类型时查询 A / AAAA，必须指定记录名
+func (c *RFC2136Client) ListRecords(zoneID string, filter RecordFilter) ([]DNSRecord, error) {
+	if filter.Name == "" {
+		return nil, fmt.Errorf("RFC 2136 只能按记录名查询记录")
+	}
+	types := []string{"A", "AAAA"}
+	if filter.Type != "" {
+		types = []string{filter.Type}
+	}
+
+	var records []DNSRecord
+	for _, recordType := range types {
+		code, ok := dnsTypes[recordType]
+		if !ok {
+			return nil, fmt.Errorf("不支持的记录类型: %s", recordType)
+		}
+		msg := &dnsMessage{
+			Opcode:   dnsOpcodeQuery,
+			Question: []dnsQuestion{{Name: filter.Name, Type: code, Class: dnsClassIN}},
+		}
+		reply, err := c.exchange(msg)
+		if err != nil {
+			return nil, fmt.Errorf("查询 %s 记录失败: %w", filter.Name, err)
+		}
+		for _, rr := range reply.Answer {
+			if rr.Type != code || !strings.EqualFold(rr.Name, strings.TrimSuffix(filter.Name, ".")) {
+				continue
+			}
+			content := rdataContent(recordType, rr.Data)
+			records = append(records, DNSRecord{
+				ID:      content,
+				Name:    filter.Name,
+				Type:    recordType,
+				Content: content,
+				TTL:     int(rr.TTL),
+			})
+		}
+	}
+	return records, nil
+}
+
+// update 向 Zone 发送一条包含给定更新记录的 UPDATE 报文
+func (c *RFC2136Client) update(zoneID string, updates []dnsRR) error {
+	msg := &dnsMessage{
+		Opcode:    dnsOpcodeUpdate,
+		Question:  []dnsQuestion{{Name: zoneID, Type: dnsTypeSOA, Class: dnsClassIN}},
+		Authority: updates,
+	}
+	_, err := c.exchange(msg)
+	return err
+}
+
+// recordUpdate 生成添加（class IN）或按值删除（class NONE）单条记录的更新记录
+func recordUpdate(name, recordType, content string, class uint16, ttl int) (dnsRR, error) {
+	data, err := rdata(recordType, content)
+	if err != nil {
+		return dnsRR{}, err
+	}
+	rr := dnsRR{Name: name, Type: dnsTypes[recordType], Class: class, Data: data}
+	if class == dnsClassIN {
+		rr.TTL = uint32(ttl)
+	}
+	return rr, nil
+}
+
+// UpsertRecord 添加记录；ID（即原记录值）不为空时同时删除原记录
+func (c *RFC2136Client) UpsertRecord(zoneID string, record DNSRecord) error {
+	var updates []dnsRR
+	if record.ID != "" && record.ID != record.Content {
+		rr, err := recordUpdate(record.Name, record.Type, record.ID, dnsClassNONE, 0)
+		if err != nil {
+			return err
+		}
+		updates = append(updates, rr)
+	}
+	rr, err := recordUpdate(record.Name, record.Type, record.Content, dnsClassIN, record.TTL)
+	if err != nil {
+		return err
+	}
+	updates = append(updates, rr)
+
+	if err := c.update(zoneID, updates); err != nil {
+		if record.ID == "" {
+			return fmt.Errorf("创建DNS记录失败: %w", err)
+		}
+		return fmt.Errorf("更新DNS记录失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteRecord 按值删除记录
+func (c *RFC2136Client) DeleteRecord(zoneID string, record DNSRecord) error {
+	content := record.Content
+	if content == "" {
+		content = record.ID
+	}
+	rr, err := recordUpdate(record.Name, record.Type, content, dnsClassNONE, 0)
+	if err != nil {
+		return err
+	}
+	if err := c.update(zoneID, []dnsRR{rr}); err != nil {
+		return fmt.Errorf("删除DNS记录失败: %w", err)
+	}
+	return nil
+}
+
+// ApplyRecordSets 在一条 UPDATE 报文中删除并重建各记录集，然后重新查询确认服务器上的记录与提交的值一致
+func (c *RFC2136Client) ApplyRecordSets(zoneID string, sets []RecordSet) error {
+	var updates []dnsRR
+	for _, set := range sets {
+		code, ok := dnsTypes[set.Type]
+		if !ok {
+			return fmt.Errorf("不支持的记录类型: %s", set.Type)
+		}
+		// class ANY 表示删除整个记录集
+		updates = append(updates, dnsRR{Name: set.Name, Type: code, Class: dnsClassANY})
+		for _, value := range set.Values {
+			rr, err := recordUpdate(set.Name, set.Type, value, dnsClassIN, set.TTL)
+			if err != nil {
+				return err
+			}
+			updates = append(updates, rr)
+		}
+	}
+	if err := c.update(zoneID, updates); err != nil {
+		return fmt.Errorf("动态更新失败: %w", err)
+	}
+	return c.verify(zoneID, sets)
+}
+
+// verify 重新查询各记录集，确认更新已生效
+func (c *RFC2136Client) verify(zoneID string, sets []RecordSet) error {
+	for _, set := range sets {
+		records, err := c.ListRecords(zoneID, RecordFilter{Name: set.Name, Type: set.Type})
+		if err != nil {
+			return fmt.Errorf("更新后查询失败: %w", err)
+		}
+		got := recordContents(records)
+		want := make([]string, 0, len(set.Values))
+		for _, value := range set.Values {
+			want = append(want, net.ParseIP(value).String())
+		}
+		sort.Strings(got)
+		sort.Strings(want)
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			return fmt.Errorf("更新后查询 %s 的 %s 记录为 %v，与提交的 %v 不一致", set.Name, set.Type, got, want)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,401 @@
+package cdn
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"AutoCDN/config"
+)
+
+// fakeDNSServer 简单的权威 DNS 服务器，通过 UDP 和 TCP 处理查询和动态更新，会校验 TSIG 签名
+type fakeDNSServer struct {
+	mu       sync.Mutex
+	addr     string
+	key      tsigKey
+	zone     string
+	rrsets   map[string][]dnsRR // 名称/类型 -> 记录集
+	updates  int                // 成功的更新报文数
+	tcp      int                // TCP 请求数
+	truncate bool               // UDP 查询响应只返回 TC 标志，强制客户端改用 TCP
+	readOnly bool               // 更新报文返回成功但不修改记录
+}
+
+// rrsetKey 记录集的索引键
+func rrsetKey(name string, recordType uint16) string {
+	return fmt.Sprintf("%s/%d", strings.ToLower(strings.TrimSuffix(name, ".")), recordType)
+}
+
+func newFakeDNSServer(t *testing.T, zone string, secret []byte) *fakeDNSServer {
+	t.Helper()
+	fake := &fakeDNSServer{key: tsigKey{Name: "autocdn-key", Secret: secret}, zone: zone, rrsets: make(map[string][]dnsRR)}
+
+	// UDP 和 TCP 监听同一端口
+	var udp net.PacketConn
+	var tcp net.Listener
+	for i := 0; ; i++ {
+		var err error
+		if udp, err = net.ListenPacket("udp", "127.0.0.1:0"); err != nil {
+			t.Fatal(err)
+		}
+		if tcp, err = net.Listen("tcp", udp.LocalAddr().String()); err == nil {
+			break
+		}
+		udp.Close()
+		if i == 10 {
+			t.Fatal(err)
+		}
+	}
+	fake.addr = udp.LocalAddr().String()
+	t.Cleanup(func() {
+		udp.Close()
+		tcp.Close()
+	})
+
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := fake.handle(buf[:n], true); resp != nil {
+				udp.WriteTo(resp, addr)
+			}
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var length [2]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					return
+				}
+				req := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, req); err != nil {
+					return
+				}
+				resp := fake.handle(req, false)
+				conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(resp))), resp...))
+			}()
+		}
+	}()
+	return fake
+}
+
+func (f *fakeDNSServer) handle(data []byte, udp bool) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !udp {
+		f.tcp++
+	}
+
+	req, err := unpackDNSMessage(data)
+	if err != nil || len(req.Question) != 1 {
+		return nil
+	}
+	reply := &dnsMessage{ID: req.ID, Response: true, Opcode: req.Opcode, Question: req.Question}
+	if _, err := f.key.verify(data, req, nil, time.Now()); err != nil {
+		reply.Rcode = dnsRcodeNotAuth
+		resp, _ := reply.pack()
+		return resp
+	}
+
+	inZone := func(name string) bool {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		return name == f.zone || strings.HasSuffix(name, "."+f.zone)
+	}
+	q := req.Question[0]
+	switch req.Opcode {
+	case dnsOpcodeQuery:
+		switch {
+		case !inZone(q.Name):
+			reply.Rcode = dnsRcodeRefused
+		case udp && f.truncate:
+			reply.Truncated = true
+		default:
+			reply.Answer = f.rrsets[rrsetKey(q.Name, q.Type)]
+			if len(reply.Answer) == 0 && len(f.rrsets[rrsetKey(q.Name, dnsTypeA)])+len(f.rrsets[rrsetKey(q.Name, dnsTypeAAAA)]) == 0 {
+				reply.Rcode = dnsRcodeNXDomain
+			}
+		}
+	case dnsOpcodeUpdate:
+		if !strings.EqualFold(q.Name, f.zone) || q.Type != dnsTypeSOA {
+			reply.Rcode = dnsRcodeNotZone
+			break
+		}
+		for _, rr := range req.Authority {
+			if !inZone(rr.Name) {
+				reply.Rcode = dnsRcodeNotZone
+			}
+		}
+		if reply.Rcode != dnsRcodeSuccess {
+			break
+		}
+		f.updates++
+		if f.readOnly {
+			break
+		}
+		for _, rr := range req.Authority {
+			key := rrsetKey(rr.Name, rr.Type)
+			switch rr.Class {
+			case dnsClassANY:
+				delete(f.rrsets, key)
+			case dnsClassNONE:
+				var kept []dnsRR
+				for _, existing := range f.rrsets[key] {
+					if !bytes.Equal(existing.Data, rr.Data) {
+						kept = append(kept, existing)
+					}
+				}
+				f.rrsets[key] = kept
+			case dnsClassIN:
+				f.add(rr)
+			}
+		}
+	}
+
+	resp, _ := reply.pack()
+	signed, _ := f.key.sign(resp, req.tsig.MAC, time.Now())
+	return signed
+}
+
+// add 向记录集添加记录，已存在的值只更新 TTL，同一记录集的 TTL 保持一致
+func (f *fakeDNSServer) add(rr dnsRR) {
+	key := rrsetKey(rr.Name, rr.Type)
+	set := f.rrsets[key]
+	found := false
+	for i := range set {
+		set[i].TTL = rr.TTL
+		found = found || bytes.Equal(set[i].Data, rr.Data)
+	}
+	if !found {
+		set = append(set, rr)
+	}
+	f.rrsets[key] = set
+}
+
+// update 在锁内修改服务器的记录或行为，避免与处理请求的协程竞争
+func (f *fakeDNSServer) update(fn func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn()
+}
+
+// counts 返回成功的更新报文数和 TCP 请求数
+func (f *fakeDNSServer) counts() (updates, tcp int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.updates, f.tcp
+}
+
+// ttl 返回记录集的 TTL，记录集不存在时返回 0
+func (f *fakeDNSServer) ttl(name string, recordType uint16) uint32 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if set := f.rrsets[rrsetKey(name, recordType)]; len(set) > 0 {
+		return set[0].TTL
+	}
+	return 0
+}
+
+// values 返回记录集的值（已排序）
+func (f *fakeDNSServer) values(name string, recordType uint16) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var values []string
+	for _, rr := range f.rrsets[rrsetKey(name, recordType)] {
+		values = append(values, net.IP(rr.Data).String())
+	}
+	sort.Strings(values)
+	return values
+}
+
+func newTestRFC2136(t *testing.T) (*RFC2136Client, *fakeDNSServer) {
+	t.Helper()
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	fake := newFakeDNSServer(t, "example.com", secret)
+	client, err := NewRFC2136Client(config.RFC2136Config{
+		Server:  fake.addr,
+		Zones:   []string{"example.com."},
+		KeyName: "autocdn-key",
+		Secret:  base64.StdEncoding.EncodeToString(secret),
+		Timeout: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.initialBackoff = time.Millisecond
+	return client, fake
+}
+
+func TestTSIGRoundTrip(t *testing.T) {
+	key := tsigKey{Name: "Key.Example.", Secret: []byte("secret")}
+	msg, _ := (&dnsMessage{ID: 42, Opcode: dnsOpcodeUpdate, Question: []dnsQuestion{{Name: "example.com", Type: dnsTypeSOA, Class: dnsClassIN}}}).pack()
+	now := time.Unix(1700000000, 0)
+	signed, mac := key.sign(msg, nil, now)
+
+	parsed, err := unpackDNSMessage(signed)
+	if err != nil || parsed.tsig == nil || parsed.tsigKeyName != "Key.Example" {
+		t.Fatalf("解析签名报文失败: %v", err)
+	}
+	if len(mac) != 32 || !bytes.Equal(parsed.tsig.MAC, mac) || parsed.tsig.OriginalID != 42 {
+		t.Fatalf("TSIG 记录错误: %+v", parsed.tsig)
+	}
+	// 密钥名大小写不影响签名
+	if _, err := (tsigKey{Name: "key.example", Secret: []byte("secret")}).verify(signed, parsed, nil, now); err != nil {
+		t.Fatalf("校验签名失败: %v", err)
+	}
+	if code, err := (tsigKey{Name: "key.example", Secret: []byte("other")}).verify(signed, parsed, nil, now); code != dnsRcodeBadSig {
+		t.Fatalf("期望 BADSIG，实际 %d (%v)", code, err)
+	}
+	if code, _ := key.verify(signed, parsed, nil, now.Add(time.Hour)); code != dnsRcodeBadTime {
+		t.Fatalf("期望 BADTIME，实际 %d", code)
+	}
+
+	// 修改 ID 后重新签名的响应需要使用请求 MAC 校验
+	resp, _ := (&dnsMessage{ID: 42, Response: true, Opcode: dnsOpcodeUpdate}).pack()
+	signedResp, _ := key.sign(resp, mac, now)
+	binary.BigEndian.PutUint16(signedResp, 7)
+	parsedResp, _ := unpackDNSMessage(signedResp)
+	if _, err := key.verify(signedResp, parsedResp, mac, now); err != nil {
+		t.Fatalf("校验响应签名失败: %v", err)
+	}
+	if _, err := key.verify(signedResp, parsedResp, nil, now); err == nil {
+		t.Fatal("缺少请求 MAC 时响应签名不应通过")
+	}
+}
+
+func TestRFC2136Publish(t *testing.T) {
+	client, fake := newTestRFC2136(t)
+	owner, _ := rdata("TXT", ownerContent("A"))
+	fake.update(func() {
+		fake.add(dnsRR{Name: "a.example.com", Type: dnsTypeA, Class: dnsClassIN, TTL: 300, Data: net.ParseIP("9.9.9.9").To4()})
+		fake.add(dnsRR{Name: "_autocdn.a.example.com", Type: dnsTypeTXT, Class: dnsClassIN, TTL: 300, Data: owner})
+		fake.truncate = true
+	})
+
+	publisher := NewPublisher(client, config.CloudflareConfig{
+		Domains:       []config.DomainConfig{{Name: "a.example.com", TTL: 300}, {Name: "b.example.com", TTL: config.TTLAuto}},
+		RecordSetSize: 2,
+		SnapshotDir:   t.TempDir(),
+	})
+	if err := publisher.HandleDNSRecords(speedSet("1.1.1.1", "2.2.2.2")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+
+	// 两个记录集在一条更新报文中提交，另一条为新建的 b 写入管理声明；UDP 响应被截断时查询改用 TCP
+	updates, tcp := fake.counts()
+	if updates != 2 {
+		t.Fatalf("期望 2 条更新报文，实际 %d", updates)
+	}
+	if got := fake.values("_autocdn.b.example.com", dnsTypeTXT); len(got) != 1 {
+		t.Fatalf("b 的管理声明为 %v", got)
+	}
+	if tcp == 0 {
+		t.Fatal("响应被截断时未改用 TCP")
+	}
+	for _, name := range []string{"a.example.com", "b.example.com"} {
+		if got := fake.values(name, dnsTypeA); !reflect.DeepEqual(got, []string{"1.1.1.1", "2.2.2.2"}) {
+			t.Fatalf("%s 的记录集为 %v", name, got)
+		}
+	}
+	if ttl := fake.ttl("b.example.com", dnsTypeA); ttl != rfc2136DefaultTTL {
+		t.Fatalf("ttl: auto 的记录 TTL 为 %d", ttl)
+	}
+
+	plan, err := publisher.PlanDNSRecords(speedSet("1.1.1.1", "2.2.2.2"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if plan.Changes() != 0 {
+		t.Fatalf("期望无需变更，实际:\n%v", plan.Lines())
+	}
+
+	// 回滚：a 恢复原值，b 的记录集被删除
+	snapshots, err := publisher.ListSnapshots()
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("期望 1 个快照，实际 %d 个 (%v)", len(snapshots), err)
+	}
+	if err := publisher.RestoreSnapshot(snapshots[0].ID); err != nil {
+		t.Fatalf("恢复快照失败: %v", err)
+	}
+	if got := fake.values("a.example.com", dnsTypeA); !reflect.DeepEqual(got, []string{"9.9.9.9"}) {
+		t.Fatalf("恢复后 a 的记录集为 %v", got)
+	}
+	if got := fake.values("b.example.com", dnsTypeA); len(got) != 0 {
+		t.Fatalf("恢复后 b 的记录集仍存在: %v", got)
+	}
+}
+
+func TestRFC2136Records(t *testing.T) {
+	client, fake := newTestRFC2136(t)
+
+	if err := client.UpsertRecord("example.com", DNSRecord{Name: "cdn.example.com", Type: "AAAA", Content: "2606:4700::1", TTL: 60}); err != nil {
+		t.Fatalf("创建记录失败: %v", err)
+	}
+	if err := client.UpsertRecord("example.com", DNSRecord{ID: "2606:4700::1", Name: "cdn.example.com", Type: "AAAA", Content: "2606:4700::2", TTL: 60}); err != nil {
+		t.Fatalf("更新记录失败: %v", err)
+	}
+	if got := fake.values("cdn.example.com", dnsTypeAAAA); !reflect.DeepEqual(got, []string{"2606:4700::2"}) {
+		t.Fatalf("更新后的记录集为 %v", got)
+	}
+	if err := client.DeleteRecord("example.com", DNSRecord{Name: "cdn.example.com", Type: "AAAA", Content: "2606:4700::2"}); err != nil {
+		t.Fatalf("删除记录失败: %v", err)
+	}
+	if got := fake.values("cdn.example.com", dnsTypeAAAA); len(got) != 0 {
+		t.Fatalf("删除后记录集仍存在: %v", got)
+	}
+
+	// 超过 512 字节的更新报文通过 TCP 发送
+	var values []string
+	for i := 1; i <= 40; i++ {
+		values = append(values, net.IPv4(10, 0, 0, byte(i)).String())
+	}
+	fake.update(func() { fake.tcp = 0 })
+	if err := client.ApplyRecordSets("example.com", []RecordSet{{Name: "big.example.com", Type: "A", TTL: 60, Values: values}}); err != nil {
+		t.Fatalf("提交记录集失败: %v", err)
+	}
+	if _, tcp := fake.counts(); tcp == 0 || len(fake.values("big.example.com", dnsTypeA)) != 40 {
+		t.Fatalf("大报文未通过 TCP 提交 (TCP 请求 %d 次)", tcp)
+	}
+}
+
+func TestRFC2136Errors(t *testing.T) {
+	client, fake := newTestRFC2136(t)
+
+	// 更新未生效时查询结果不一致
+	fake.update(func() { fake.readOnly = true })
+	err := client.ApplyRecordSets("example.com", []RecordSet{{Name: "cdn.example.com", Type: "A", TTL: 60, Values: []string{"1.1.1.1"}}})
+	if err == nil || !strings.Contains(err.Error(), "不一致") {
+		t.Fatalf("期望查询结果不一致的错误，实际 %v", err)
+	}
+
+	if err := client.UpsertRecord("other.com", DNSRecord{Name: "cdn.other.com", Type: "A", Content: "1.1.1.1"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("期望 NOTZONE 错误，实际 %v", err)
+	}
+
+	fake.update(func() { fake.key.Secret = []byte("other") })
+	if _, err := client.ListRecords("example.com", RecordFilter{Name: "cdn.example.com", Type: "A"}); !errors.Is(err, ErrAuth) {
+		t.Fatalf("期望认证错误，实际 %v", err)
+	}
+
+	if _, err := NewRFC2136Client(config.RFC2136Config{Server: "127.0.0.1", Zones: []string{"example.com"}, KeyName: "k", Secret: "not base64!"}); err == nil {
+		t.Fatal("无效的 TSIG 密钥应返回错误")
+	}
+}
@@ -0,0 +1,463 @@
+package cdn
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"AutoCDN/config"
+)
+
+// Route 53 API 设置
+const (
+	route53Endpoint   = "https://route53.amazonaws.com"
+	route53APIVersion = "2013-04-01"
+	route53Namespace  = "https://route53.amazonaws.com/doc/2013-04-01/"
+	route53Region     = "us-east-1"
+	route53Service    = "route53"
+)
+
+// Route 53 其他设置
+const (
+	route53DefaultTTL     = 300              // ttl: auto 时使用的 TTL
+	route53MaxChanges     = 100              // 单次提交的最大变更数
+	route53WaitTimeout    = 120              // 默认等待变更生效的秒数
+	route53PollInterval   = 5 * time.Second  // 查询变更状态的间隔
+	route53RecordsPerPage = 300              // 每页获取的记录集数量
+	route53RequestTimeout = 30 * time.Second // 单次请求超时
+)
+
+// Route53Client AWS Route 53 API 客户端
+type Route53Client struct {
+	retryPolicy
+	endpoint     string
+	region       string
+	cred         awsCredentials
+	waitTimeout  time.Duration
+	pollInterval time.Duration
+	httpClient   *http.Client
+}
+
+// NewRoute53Client 创建 Route 53 API 客户端，httpClient 为空时使用默认的 HTTP 客户端
+func NewRoute53Client(cfg config.Route53Config, httpClient *http.Client) *Route53Client {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = route53Endpoint
+	}
+	region := cfg.Region
+	if region == "" {
+		region = route53Region
+	}
+	waitTimeout := cfg.WaitTimeout
+	if waitTimeout <= 0 {
+		waitTimeout = route53WaitTimeout
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: route53RequestTimeout}
+	}
+	return &Route53Client{
+		retryPolicy:  defaultRetryPolicy(),
+		endpoint:     strings.TrimSuffix(endpoint, "/"),
+		region:       region,
+		cred:         awsCredentials{AccessKeyID: cfg.AccessKeyID, SecretAccessKey: cfg.SecretAccessKey, SessionToken: cfg.SessionToken},
+		waitTimeout:  time.Duration(waitTimeout) * time.Second,
+		pollInterval: route53PollInterval,
+		httpClient:   httpClient,
+	}
+}
+
+// route53ResourceRecordSet Route 53 记录集
+type route53ResourceRecordSet struct {
+	Name            string   `xml:"Name"`
+	Type            string   `xml:"Type"`
+	SetIdentifier   string   `xml:"SetIdentifier,omitempty"`
+	TTL             int      `xml:"TTL,omitempty"`
+	ResourceRecords []string `xml:"ResourceRecords>ResourceRecord>Value"`
+	AliasTarget     *struct {
+		DNSName string `xml:"DNSName"`
+	} `xml:"AliasTarget,omitempty"`
+}
+
+// route53Change 记录集变更
+type route53Change struct {
+	Action            string                   `xml:"Action"`
+	ResourceRecordSet route53ResourceRecordSet `xml:"ResourceRecordSet"`
+}
+
+// route53ChangeRequest ChangeResourceRecordSets 请求体
+type route53ChangeRequest struct {
+	XMLName xml.Name        `xml:"ChangeResourceRecordSetsRequest"`
+	Xmlns   string          `xml:"xmlns,attr"`
+	Comment string          `xml:"ChangeBatch>Comment"`
+	Changes []route53Change `xml:"ChangeBatch>Changes>Change"`
+}
+
+// route53ChangeInfo 变更状态
+type route53ChangeInfo struct {
+	ID     string `xml:"ChangeInfo>Id"`
+	Status string `xml:"ChangeInfo>Status"`
+}
+
+// route53Error Route 53 错误响应
+type route53Error struct {
+	Code    string `xml:"Error>Code"`
+	Message string `xml:"Error>Message"`
+}
+
+// route53Classify 根据 Route 53 错误码确定错误类别
+func route53Classify(statusCode int, code string) error {
+	switch code {
+	case "InvalidClientTokenId", "SignatureDoesNotMatch", "AccessDenied", "IncompleteSignature",
+		"MissingAuthenticationToken", "ExpiredToken", "UnrecognizedClientException":
+		return ErrAuth
+	case "NoSuchHostedZone", "NoSuchChange":
+		return ErrNotFound
+	case "Throttling", "ThrottlingException", "PriorRequestNotComplete":
+		return ErrRateLimited
+	case "InvalidChangeBatch", "InvalidInput":
+		return ErrValidation
+	}
+	return classify(statusCode, nil)
+}
+
+// request 发送签名后的请求并解析 XML 响应
+func (c *Route53Client) request(method, path string, query url.Values, body interface{}, idempotent bool, result interface{}) error {
+	var payload []byte
+	if body != nil {
+		data, err := xml.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = append([]byte(xml.Header), data...)
+	}
+
+	reqURL := c.endpoint + "/" + route53APIVersion + path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	return c.retry(idempotent, func() error {
+		req, err := http.NewRequest(method, reqURL, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/xml")
+		}
+		signV4(req, payload, c.cred, c.region, route53Service, time.Now())
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 300 {
+			var apiErr route53Error
+			xml.Unmarshal(data, &apiErr)
+			return &APIError{
+				StatusCode: resp.StatusCode,
+				Status:     resp.Status,
+				Errors:     []APIMessage{{Message: fmt.Sprintf("%s: %s", apiErr.Code, apiErr.Message)}},
+				RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+				kind:       route53Classify(resp.StatusCode, apiErr.Code),
+			}
+		}
+		if result == nil {
+			return nil
+		}
+		if err := xml.Unmarshal(data, result); err != nil {
+			return fmt.Errorf("解析 Route 53 响应失败: %v", err)
+		}
+		return nil
+	})
+}
+
+// Name 返回服务商名称
+func (c *Route53Client) Name() string {
+	return ProviderRoute53
+}
+
+// Capabilities 返回 Route 53 支持的功能
+func (c *Route53Client) Capabilities() Capabilities {
+	return Capabilities{MultiValue: true, QuotedTXT: true, AutoTTL: route53DefaultTTL}
+}
+
+// ListZones 获取全部公有托管区域，Zone ID 为去掉 /hostedzone/ 前缀的托管区域 ID
+func (c *Route53Client) ListZones() ([]Zone, error) {
+	var zones []Zone
+	query := url.Values{}
+	for {
+		var result struct {
+			HostedZones []struct {
+				ID          string `xml:"Id"`
+				Name        string `xml:"Name"`
+				PrivateZone bool   `xml:"Config>PrivateZone"`
+			} `xml:"HostedZones>HostedZone"`
+			IsTruncated bool   `xml:"IsTruncated"`
+			NextMarker  string `xml:"NextMarker"`
+		}
+		if err := c.request(http.MethodGet, "/hostedzone", query, nil, true, &result); err != nil {
+			return nil, fmt.Errorf("获取 Route 53 托管区域失败: %w", err)
+		}
+		for _, zone := range result.HostedZones {
+			// 私有托管区域只在 VPC 内生效，不发布公网 IP
+			if zone.PrivateZone {
+				continue
+			}
+			zones = append(zones, Zone{ID: strings.TrimPrefix(zone.ID, "/hostedzone/"), Name: route53Name(zone.Name)})
+		}
+
+		if !result.IsTruncated || result.NextMarker == "" {
+			break
+		}
+		query.Set("marker", result.NextMarker)
+	}
+	return zones, nil
+}
+
+// route53Name 将 Route 53 返回的记录名转换为普通域名：去掉结尾的点并还原 \ddd 转义
+func route53Name(name string) string {
+	name = strings.TrimSuffix(name, ".")
+	if !strings.Contains(name, `\`) {
+		return strings.ToLower(name)
+	}
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+3 < len(name) {
+			if n, err := strconv.ParseUint(name[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(name[i])
+	}
+	return strings.ToLower(b.String())
+}
+
+// fqdn 返回以点结尾的完整域名
+func fqdn(name string) string {
+	return strings.TrimSuffix(name, ".") + "."
+}
+
+// listRecordSets 获取托管区域中的记录集，name 不为空时只返回该记录名的记录集
+func (c *Route53Client) listRecordSets(zoneID, name, recordType string) ([]route53ResourceRecordSet, error) {
+	var sets []route53ResourceRecordSet
+	query := url.Values{}
+	query.Set("maxitems", strconv.Itoa(route53RecordsPerPage))
+	if name != "" {
+		query.Set("name", fqdn(name))
+		if recordType != "" {
+			query.Set("type", recordType)
+		}
+	}
+
+	for {
+		var result struct {
+			ResourceRecordSets   []route53ResourceRecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+			IsTruncated          bool                       `xml:"IsTruncated"`
+			NextRecordName       string                     `xml:"NextRecordName"`
+			NextRecordType       string                     `xml:"NextRecordType"`
+			NextRecordIdentifier string                     `xml:"NextRecordIdentifier"`
+		}
+		if err := c.request(http.MethodGet, "/hostedzone/"+zoneID+"/rrset", query, nil, true, &result); err != nil {
+			return nil, err
+		}
+
+		for _, set := range result.ResourceRecordSets {
+			if name != "" && route53Name(set.Name) != strings.ToLower(strings.TrimSuffix(name, ".")) {
+				// 结果按名称排序，指定名称的记录集已经全部返回
+				return sets, nil
+			}
+			if recordType != "" && set.Type != recordType {
+				continue
+			}
+			sets = append(sets, set)
+		}
+
+		if !result.IsTruncated {
+			break
+		}
+		query.Set("name", result.NextRecordName)
+		query.Set("type", result.NextRecordType)
+		if result.NextRecordIdentifier != "" {
+			query.Set("identifier", result.NextRecordIdentifier)
+		} else {
+			query.Del("identifier")
+		}
+	}
+	return sets, nil
+}
+
+// ListRecords 获取托管区域中符合筛选条件的记录，记录集中的每个值作为一条记录，记录 ID 即为值
+// 别名记录和带路由策略 (SetIdentifier) 的记录集不由 AutoCDN 管理，不会返回
+func (c *Route53Client) ListRecords(zoneID string, filter RecordFilter) ([]DNSRecord, error) {
+	sets, err := c.listRecordSets(zoneID, filter.Name, filter.Type)
+	if err != nil {
+		return nil, fmt.Errorf("获取DNS记录失败: %w", err)
+	}
+
+	var records []DNSRecord
+	for _, set := range sets {
+		if set.AliasTarget != nil || set.SetIdentifier != "" {
+			continue
+		}
+		for _, value := range set.ResourceRecords {
+			records = append(records, DNSRecord{
+				ID:      value,
+				Name:    route53Name(set.Name),
+				Type:    set.Type,
+				Content: value,
+				TTL:     set.TTL,
+			})
+		}
+	}
+	return records, nil
+}
+
+// currentSet 返回记录集当前的值，不存在时返回 nil
+func (c *Route53Client) currentSet(zoneID, name, recordType string) (*route53ResourceRecordSet, error) {
+	sets, err := c.listRecordSets(zoneID, name, recordType)
+	if err != nil {
+		return nil, err
+	}
+	for _, set := range sets {
+		if set.SetIdentifier == "" && set.AliasTarget == nil {
+			return &set, nil
+		}
+	}
+	return nil, nil
+}
+
+// UpsertRecord 将记录写入所属记录集：ID 为空时追加值，否则替换 ID 对应的值
+func (c *Route53Client) UpsertRecord(zoneID string, record DNSRecord) error {
+	current, err := c.currentSet(zoneID, record.Name, record.Type)
+	if err != nil {
+		return fmt.Errorf("获取记录集失败: %w", err)
+	}
+
+	var values []string
+	if current != nil {
+		for _, value := range current.ResourceRecords {
+			if value != record.ID && value != record.Content {
+				values = append(values, value)
+			}
+		}
+	}
+	values = append(values, record.Content)
+
+	set := RecordSet{Name: record.Name, Type: record.Type, TTL: record.TTL, Values: values}
+	if err := c.ApplyRecordSets(zoneID, []RecordSet{set}); err != nil {
+		return fmt.Errorf("更新DNS记录失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteRecord 从所属记录集中移除记录的值，记录集为空时删除记录集
+func (c *Route53Client) DeleteRecord(zoneID string, record DNSRecord) error {
+	current, err := c.currentSet(zoneID, record.Name, record.Type)
+	if err != nil {
+		return fmt.Errorf("获取记录集失败: %w", err)
+	}
+	if current == nil {
+		return nil
+	}
+
+	set := RecordSet{Name: record.Name, Type: record.Type, TTL: current.TTL}
+	for _, value := range current.ResourceRecords {
+		if value != record.ID && value != record.Content {
+			set.Values = append(set.Values, value)
+		}
+	}
+	if err := c.ApplyRecordSets(zoneID, []RecordSet{set}); err != nil {
+		return fmt.Errorf("删除DNS记录失败: %w", err)
+	}
+	return nil
+}
+
+// ApplyRecordSets 以 UPSERT 批量提交记录集并等待变更生效 (INSYNC)
+// 值为空的记录集按当前内容提交 DELETE，不存在时跳过
+func (c *Route53Client) ApplyRecordSets(zoneID string, sets []RecordSet) error {
+	var changes []route53Change
+	for _, set := range sets {
+		if len(set.Values) > 0 {
+			changes = append(changes, route53Change{
+				Action: "UPSERT",
+				ResourceRecordSet: route53ResourceRecordSet{
+					Name:            fqdn(set.Name),
+					Type:            set.Type,
+					TTL:             set.TTL,
+					ResourceRecords: set.Values,
+				},
+			})
+			continue
+		}
+
+		// DELETE 必须与当前记录集完全一致
+		current, err := c.currentSet(zoneID, set.Name, set.Type)
+		if err != nil {
+			return err
+		}
+		if current != nil {
+			changes = append(changes, route53Change{Action: "DELETE", ResourceRecordSet: *current})
+		}
+	}
+
+	for start := 0; start < len(changes); start += route53MaxChanges {
+		end := start + route53MaxChanges
+		if end > len(changes) {
+			end = len(changes)
+		}
+		if err := c.submit(zoneID, changes[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// submit 提交一批变更并等待生效
+func (c *Route53Client) submit(zoneID string, changes []route53Change) error {
+	// 只包含 UPSERT 的批次重复提交结果相同，可以安全重试
+	idempotent := true
+	for _, change := range changes {
+		if change.Action != "UPSERT" {
+			idempotent = false
+		}
+	}
+
+	body := route53ChangeRequest{Xmlns: route53Namespace, Comment: "AutoCDN", Changes: changes}
+	var info route53ChangeInfo
+	if err := c.request(http.MethodPost, "/hostedzone/"+zoneID+"/rrset/", nil, body, idempotent, &info); err != nil {
+		return err
+	}
+	return c.waitInsync(info)
+}
+
+// waitInsync 轮询变更状态直到 INSYNC 或超时
+func (c *Route53Client) waitInsync(info route53ChangeInfo) error {
+	changeID := strings.TrimPrefix(info.ID, "/change/")
+	deadline := time.Now().Add(c.waitTimeout)
+	for info.Status != "INSYNC" {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待 Route 53 变更 %s 生效超时 (当前状态 %s)", changeID, info.Status)
+		}
+		log.Printf("等待 Route 53 变更 %s 生效 (当前状态 %s)...", changeID, info.Status)
+		time.Sleep(c.pollInterval)
+		if err := c.request(http.MethodGet, "/change/"+changeID, nil, nil, true, &info); err != nil {
+			return fmt.Errorf("查询 Route 53 变更状态失败: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package cdn
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"AutoCDN/config"
+)
+
+// 签名测试向量来自 AWS Signature Version 4 测试套件 (get-vanilla)
+func TestSignV4(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	now, _ := time.Parse("20060102T150405Z", "20150830T123600Z")
+	signV4(req, nil, awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}, "us-east-1", "service", now)
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Fatalf("签名错误:\n%s\n%s", got, want)
+	}
+}
+
+// fakeRoute53 简单的 Route 53 API 模拟服务，会校验请求签名
+type fakeRoute53 struct {
+	mu      sync.Mutex
+	secret  string
+	sets    map[string]route53ResourceRecordSet // 名称/类型 -> 记录集，只模拟 Z1 一个托管区域
+	batches [][]route53Change
+	polls   int
+}
+
+func (f *fakeRoute53) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fail := func(status int, code, message string) {
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "<ErrorResponse><Error><Type>Sender</Type><Code>%s</Code><Message>%s</Message></Error></ErrorResponse>", code, message)
+	}
+
+	// 按请求中的时间重新签名并比较
+	body, _ := io.ReadAll(r.Body)
+	signed, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	now, _ := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	signV4(signed, body, awsCredentials{AccessKeyID: "AKIDTEST", SecretAccessKey: f.secret}, "us-east-1", "route53", now)
+	if signed.Header.Get("Authorization") != r.Header.Get("Authorization") {
+		fail(http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/2013-04-01")
+	switch {
+	case r.Method == http.MethodGet && path == "/hostedzone":
+		fmt.Fprint(w, `<ListHostedZonesResponse><HostedZones>`+
+			`<HostedZone><Id>/hostedzone/Z2</Id><Name>example.com.</Name><Config><PrivateZone>true</PrivateZone></Config></HostedZone>`+
+			`<HostedZone><Id>/hostedzone/Z1</Id><Name>example.com.</Name><Config><PrivateZone>false</PrivateZone></Config></HostedZone>`+
+			`</HostedZones><IsTruncated>false</IsTruncated></ListHostedZonesResponse>`)
+	case r.Method == http.MethodGet && path == "/hostedzone/Z1/rrset":
+		keys := make([]string, 0, len(f.sets))
+		for key := range f.sets {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		start := r.URL.Query().Get("name") + "/" + r.URL.Query().Get("type")
+		var result struct {
+			XMLName xml.Name                   `xml:"ListResourceRecordSetsResponse"`
+			Sets    []route53ResourceRecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+		}
+		for _, key := range keys {
+			if start != "/" && key < start {
+				continue
+			}
+			result.Sets = append(result.Sets, f.sets[key])
+		}
+		xml.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodPost && path == "/hostedzone/Z1/rrset/":
+		var req route53ChangeRequest
+		if err := xml.Unmarshal(body, &req); err != nil {
+			fail(http.StatusBadRequest, "InvalidInput", err.Error())
+			return
+		}
+		for _, change := range req.Changes {
+			key := change.ResourceRecordSet.Name + "/" + change.ResourceRecordSet.Type
+			if change.Action == "DELETE" && !reflect.DeepEqual(f.sets[key], change.ResourceRecordSet) {
+				fail(http.StatusBadRequest, "InvalidChangeBatch", "Tried to delete resource record set but it was not found")
+				return
+			}
+		}
+		for _, change := range req.Changes {
+			key := change.ResourceRecordSet.Name + "/" + change.ResourceRecordSet.Type
+			if change.Action == "DELETE" {
+				delete(f.sets, key)
+			} else {
+				f.sets[key] = change.ResourceRecordSet
+			}
+		}
+		f.batches = append(f.batches, req.Changes)
+		fmt.Fprint(w, `<ChangeResourceRecordSetsResponse><ChangeInfo><Id>/change/C1</Id><Status>PENDING</Status></ChangeInfo></ChangeResourceRecordSetsResponse>`)
+	case r.Method == http.MethodGet && path == "/change/C1":
+		f.polls++
+		fmt.Fprint(w, `<GetChangeResponse><ChangeInfo><Id>/change/C1</Id><Status>INSYNC</Status></ChangeInfo></GetChangeResponse>`)
+	default:
+		fail(http.StatusNotFound, "NoSuchHostedZone", "No hosted zone found")
+	}
+}
+
+// values 返回记录集的值（已排序）
+func (f *fakeRoute53) values(name, recordType string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	values := append([]string(nil), f.sets[name+"/"+recordType].ResourceRecords...)
+	sort.Strings(values)
+	return values
+}
+
+func newTestRoute53(t *testing.T) (*Route53Client, *fakeRoute53) {
+	t.Helper()
+	fake := &fakeRoute53{secret: "test-secret", sets: make(map[string]route53ResourceRecordSet)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := NewRoute53Client(config.Route53Config{AccessKeyID: "AKIDTEST", SecretAccessKey: "test-secret", Endpoint: server.URL}, server.Client())
+	client.initialBackoff = time.Millisecond
+	client.pollInterval = time.Millisecond
+	return client, fake
+}
+
+func TestRoute53Publish(t *testing.T) {
+	client, fake := newTestRoute53(t)
+	fake.sets["a.example.com./A"] = route53ResourceRecordSet{Name: "a.example.com.", Type: "A", TTL: 300, ResourceRecords: []string{"9.9.9.9"}}
+	fake.sets["_autocdn.a.example.com./TXT"] = route53ResourceRecordSet{Name: "_autocdn.a.example.com.", Type: "TXT", TTL: 300, ResourceRecords: []string{`"autocdn-owner type=A"`}}
+	fake.sets["example.com./NS"] = route53ResourceRecordSet{Name: "example.com.", Type: "NS", TTL: 172800, ResourceRecords: []string{"ns-1.awsdns-00.com."}}
+
+	publisher := NewPublisher(client, config.CloudflareConfig{
+		Domains:       []config.DomainConfig{{Name: "a.example.com", TTL: 300}, {Name: "b.example.com", TTL: config.TTLAuto}},
+		RecordSetSize: 2,
+		SnapshotDir:   t.TempDir(),
+	})
+	if err := publisher.HandleDNSRecords(speedSet("1.1.1.1", "2.2.2.2")); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+
+	// 两个记录集在一次请求中以 UPSERT 提交，并等待 INSYNC
+	if len(fake.batches) != 2 || len(fake.batches[0]) != 2 || fake.batches[0][0].Action != "UPSERT" {
+		t.Fatalf("期望 1 次包含 2 个 UPSERT 的提交，实际 %+v", fake.batches)
+	}
+	// 随后为新建的 b 写入管理声明
+	if claim := fake.batches[1]; len(claim) != 1 || claim[0].ResourceRecordSet.Name != "_autocdn.b.example.com." {
+		t.Fatalf("管理声明的提交为 %+v", claim)
+	}
+	if fake.polls == 0 {
+		t.Fatal("未等待变更生效")
+	}
+	for _, name := range []string{"a.example.com.", "b.example.com."} {
+		if got := fake.values(name, "A"); !reflect.DeepEqual(got, []string{"1.1.1.1", "2.2.2.2"}) {
+			t.Fatalf("%s 的记录集为 %v", name, got)
+		}
+	}
+
+	plan, err := publisher.PlanDNSRecords(speedSet("1.1.1.1", "2.2.2.2"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if plan.Changes() != 0 {
+		t.Fatalf("期望无需变更，实际:\n%v", plan.Lines())
+	}
+
+	// 回滚：a 恢复原值，b 的记录集被删除
+	snapshots, err := publisher.ListSnapshots()
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("期望 1 个快照，实际 %d 个 (%v)", len(snapshots), err)
+	}
+	if err := publisher.RestoreSnapshot(snapshots[0].ID); err != nil {
+		t.Fatalf("恢复快照失败: %v", err)
+	}
+	if got := fake.values("a.example.com.", "A"); !reflect.DeepEqual(got, []string{"9.9.9.9"}) {
+		t.Fatalf("恢复后 a 的记录集为 %v", got)
+	}
+	if got := fake.values("b.example.com.", "A"); len(got) != 0 {
+		t.Fatalf("恢复后 b 的记录集仍存在: %v", got)
+	}
+}
+
+func TestRoute53Records(t *testing.T) {
+	client, fake := newTestRoute53(t)
+	fake.sets["cdn.example.com./A"] = route53ResourceRecordSet{Name: "cdn.example.com.", Type: "A", TTL: 60, ResourceRecords: []string{"1.1.1.1", "2.2.2.2"}}
+
+	if err := client.UpsertRecord("Z1", DNSRecord{ID: "2.2.2.2", Name: "cdn.example.com", Type: "A", Content: "3.3.3.3", TTL: 60}); err != nil {
+		t.Fatalf("更新记录失败: %v", err)
+	}
+	if got := fake.values("cdn.example.com.", "A"); !reflect.DeepEqual(got, []string{"1.1.1.1", "3.3.3.3"}) {
+		t.Fatalf("更新后的记录集为 %v", got)
+	}
+
+	for _, value := range []string{"1.1.1.1", "3.3.3.3"} {
+		if err := client.DeleteRecord("Z1", DNSRecord{ID: value, Name: "cdn.example.com", Type: "A", Content: value}); err != nil {
+			t.Fatalf("删除记录失败: %v", err)
+		}
+	}
+	if _, ok := fake.sets["cdn.example.com./A"]; ok {
+		t.Fatal("最后一个值删除后记录集仍存在")
+	}
+
+	fake.secret = "other"
+	if _, err := client.ListZones(); !errors.Is(err, ErrAuth) {
+		t.Fatalf("期望认证错误，实际 %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package cdn
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"AutoCDN/config"
+)
+
+// 默认快照目录
+const defaultSnapshotDir = "snapshots"
+
+// Snapshot 一次变更前受影响记录的快照
+type Snapshot struct {
+	ID        string            `json:"id"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Provider  string            `json:"provider,omitempty"` // 记录所在的 DNS 服务商，旧版本快照为空（Cloudflare）
+	ZoneID    string            `json:"zoneId,omitempty"`   // 旧版本快照只记录单个 Zone
+	Zones     map[string]string `json:"zones"`              // 记录名 -> 所属 Zone
+	Type      string            `json:"type"`
+	Names     []string          `json:"names"`   // 受影响的记录名，包括变更前不存在记录的域名
+	Records   []DNSRecord       `json:"records"` // 变更前的记录
+}
+
+// zoneOf 返回快照中记录名所属的 Zone
+func (s *Snapshot) zoneOf(name string) string {
+	if zoneID, ok := s.Zones[name]; ok {
+		return zoneID
+	}
+	return s.ZoneID
+}
+
+// providerName 返回快照所属的 DNS 服务商
+func (s *Snapshot) providerName() string {
+	if s.Provider == "" {
+		return ProviderCloudflare
+	}
+	return s.Provider
+}
+
+// snapshotDir 返回快照保存目录
+func (p *Publisher) snapshotDir() string {
+	if dir := p.cf.SnapshotDir; dir != "" {
+		return dir
+	}
+	return defaultSnapshotDir
+}
+
+// saveSnapshot 保存计划中将被修改的记录名的当前状态
+func (p *Publisher) saveSnapshot(plan *Plan) (*Snapshot, error) {
+	snapshot := &Snapshot{
+		CreatedAt: time.Now(),
+		Provider:  p.provider.Name(),
+		Zones:     make(map[string]string),
+		Type:      plan.Type,
+	}
+
+	seen := make(map[string]bool)
+	for _, action := range plan.Actions {
+		if action.Action == ActionNoop || seen[action.Name] {
+			continue
+		}
+		seen[action.Name] = true
+		snapshot.Zones[action.Name] = action.ZoneID
+		snapshot.Names = append(snapshot.Names, action.Name)
+		snapshot.Records = append(snapshot.Records, plan.current[action.Name]...)
+	}
+
+	dir := p.snapshotDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	// 以时间和记录类型命名，同一秒内多次保存时追加序号
+	base := snapshot.CreatedAt.Format("20060102-150405") + "-" + strings.ToLower(plan.Type)
+	snapshot.ID = base
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filepath.Join(dir, snapshot.ID+".json")); os.IsNotExist(err) {
+			break
+		}
+		snapshot.ID = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, snapshot.ID+".json"), data, 0644); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// LoadSnapshot 读取指定快照
+func (p *Publisher) LoadSnapshot(id string) (*Snapshot, error) {
+	data, err := os.ReadFile(filepath.Join(p.snapshotDir(), filepath.Base(id)+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("读取快照 %s 失败: %v", id, err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("解析快照 %s 失败: %v", id, err)
+	}
+	return &snapshot, nil
+}
+
+// ListSnapshots 列出所有快照，最新的在前
+func (p *Publisher) ListSnapshots() ([]Snapshot, error) {
+	files, err := filepath.Glob(filepath.Join(p.snapshotDir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+	for _, file := range files {
+		snapshot, err := p.LoadSnapshot(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			log.Printf("跳过无效快照 %s: %v", file, err)
+			continue
+		}
+		snapshots = append(snapshots, *snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// domainFromRecords 从快照记录还原域名的记录选项
+func domainFromRecords(name, line string, records []DNSRecord) config.DomainConfig {
+	domain := config.DomainConfig{Name: name, Line: line}
+	if len(records) > 0 {
+		proxied := records[0].Proxied
+		domain.TTL = config.TTL(records[0].TTL)
+		domain.Proxied = &proxied
+		domain.Comment = records[0].Comment
+		domain.Tags = records[0].Tags
+	}
+	return domain
+}
+
+// planRestore 计算将快照中的记录名恢复为快照内容所需的变更
+// 同一记录名的各解析线路分别恢复，快照之后新增线路的记录会被删除
+func (p *Publisher) planRestore(snapshot *Snapshot) (*Plan, error) {
+	zoneOf := make(map[string]string, len(snapshot.Names))
+	for _, name := range snapshot.Names {
+		zoneOf[name] = snapshot.zoneOf(name)
+	}
+	groups, err := p.listRecordGroups(snapshot.Type, zoneOf)
+	if err != nil {
+		return nil, err
+	}
+	wanted := groupRecordsByName(snapshot.Records)
+
+	plan := &Plan{Type: snapshot.Type, current: groups}
+	for _, name := range snapshot.Names {
+		for _, line := range recordLines(wanted[name], groups[name]) {
+			records := filterLine(wanted[name], line)
+			domain := domainFromRecords(name, line, records)
+			for _, action := range diffRecordSet(filterLine(groups[name], line), recordContents(records), domain) {
+				action.ZoneID = snapshot.zoneOf(name)
+				action.Name = name
+				action.Type = snapshot.Type
+				action.Line = line
+				action.domain = domain
+				plan.Actions = append(plan.Actions, action)
+			}
+		}
+	}
+	return plan, nil
+}
+
+// RestoreSnapshot 将快照中的记录恢复为快照时的内容
+// 恢复前会保存当前状态的快照；任一变更失败时停止并回退到恢复前的状态
+func (p *Publisher) RestoreSnapshot(id string) error {
+	snapshot, err := p.LoadSnapshot(id)
+	if err != nil {
+		return err
+	}
+	if provider := snapshot.providerName(); provider != p.provider.Name() {
+		return fmt.Errorf("快照 %s 来自 %s，当前 DNS 服务商为 %s，无法恢复", id, provider, p.provider.Name())
+	}
+
+	plan, err := p.planRestore(snapshot)
+	if err != nil {
+		return err
+	}
+	plan.Print()
+	if plan.Changes() == 0 {
+		log.Printf("记录已与快照 %s 一致，无需恢复", id)
+		return nil
+	}
+
+	before, err := p.saveSnapshot(plan)
+	if err != nil {
+		return fmt.Errorf("保存恢复前快照失败，未修改任何记录: %v", err)
+	}
+	log.Printf("已保存恢复前快照: %s", before.ID)
+
+	if failures := p.applyActions(plan, true); len(failures) > 0 {
+		log.Printf("恢复快照 %s 失败，正在回退到恢复前状态...", id)
+		undo, err := p.planRestore(before)
+		if err != nil {
+			return fmt.Errorf("恢复快照失败，且无法回退 (可手动恢复快照 %s): %v", before.ID, err)
+		}
+		if undoFailures := p.applyActions(undo, false); len(undoFailures) > 0 {
+			return fmt.Errorf("恢复快照失败 (%s)，回退时有 %d 项失败 (可手动恢复快照 %s)", failures[0], len(undoFailures), before.ID)
+		}
+		return fmt.Errorf("恢复快照 %s 失败，已回退到恢复前状态: %s", id, failures[0])
+	}
+
+	log.Printf("已恢复快照: %s", id)
+	return nil
+}
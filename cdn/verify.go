@@ -0,0 +1,166 @@
+package cdn
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"AutoCDN/config"
+)
+
+// DomainVerification 单个域名发布后的校验结果
+type DomainVerification struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Line    string   `json:"line,omitempty"`
+	Passed  bool     `json:"passed"`
+	Details []string `json:"details"` // 读回记录和每个 IP 的检查结果
+}
+
+// String 返回校验结果的可读描述
+func (d DomainVerification) String() string {
+	result := "通过"
+	if !d.Passed {
+		result = "失败"
+	}
+	label := PlanAction{Name: d.Name, Line: d.Line}.label()
+	return fmt.Sprintf("校验%s %s (%s): %s", result, label, d.Type, strings.Join(d.Details, "; "))
+}
+
+// VerifyReport 一次发布的校验结果
+type VerifyReport struct {
+	Domains []DomainVerification `json:"domains"`
+}
+
+// Failed 返回校验失败的域名数量
+func (r *VerifyReport) Failed() int {
+	count := 0
+	for _, domain := range r.Domains {
+		if !domain.Passed {
+			count++
+		}
+	}
+	return count
+}
+
+// Lines 返回每个域名的校验结果，每个域名一行
+func (r *VerifyReport) Lines() []string {
+	lines := make([]string, 0, len(r.Domains))
+	for _, domain := range r.Domains {
+		lines = append(lines, domain.String())
+	}
+	return lines
+}
+
+// VerifyPlan 校验计划中有变更的域名：从服务商读回记录与计划对比，再以域名作为 SNI / Host 连接每个新 IP 请求校验路径
+func (p *Publisher) VerifyPlan(plan *Plan) *VerifyReport {
+	type domainKey struct{ zoneID, name, line string }
+	var keys []domainKey
+	expected := make(map[domainKey][]string)
+	changed := make(map[domainKey]bool)
+	for _, action := range plan.Actions {
+		key := domainKey{action.ZoneID, action.Name, action.Line}
+		if _, ok := expected[key]; !ok {
+			keys = append(keys, key)
+			expected[key] = nil
+		}
+		if action.Action != ActionDelete {
+			expected[key] = append(expected[key], action.NewContent)
+		}
+		if action.Action != ActionNoop {
+			changed[key] = true
+		}
+	}
+
+	report := &VerifyReport{}
+	for _, key := range keys {
+		if !changed[key] {
+			continue
+		}
+		result := DomainVerification{Name: key.name, Type: plan.Type, Line: key.line, Passed: true}
+		fail := func(detail string) {
+			result.Passed = false
+			result.Details = append(result.Details, detail)
+		}
+
+		records, err := p.provider.ListRecords(key.zoneID, RecordFilter{Name: key.name, Type: plan.Type})
+		if err != nil {
+			fail(fmt.Sprintf("读回记录失败: %v", err))
+		} else {
+			actual := recordContents(filterLine(records, key.line))
+			// 单 IP 模式下不维护的同名记录保持原样
+			want := append([]string(nil), expected[key]...)
+			for _, value := range plan.kept[key.name] {
+				if !contains(want, value) {
+					want = append(want, value)
+				}
+			}
+			sort.Strings(actual)
+			sort.Strings(want)
+			if strings.Join(actual, ",") != strings.Join(want, ",") {
+				fail(fmt.Sprintf("记录不一致: 期望 %s，实际 %s", strings.Join(want, ", "), strings.Join(actual, ", ")))
+			}
+		}
+
+		for _, ip := range expected[key] {
+			status, err := p.checkHTTP(key.name, ip)
+			switch {
+			case err != nil:
+				fail(fmt.Sprintf("%s 请求失败: %v", ip, err))
+			case status != p.cf.Verify.ExpectedStatus():
+				fail(fmt.Sprintf("%s HTTP %d (期望 %d)", ip, status, p.cf.Verify.ExpectedStatus()))
+			default:
+				result.Details = append(result.Details, fmt.Sprintf("%s HTTP %d", ip, status))
+			}
+		}
+		report.Domains = append(report.Domains, result)
+	}
+	return report
+}
+
+// checkHTTP 直接连接 IP，以域名作为 SNI 和 Host 请求校验路径，返回状态码，不跟随重定向
+func (p *Publisher) checkHTTP(host, ip string) (int, error) {
+	verify := p.cf.Verify
+	address := net.JoinHostPort(ip, strconv.Itoa(verify.URLPort()))
+	dialer := &net.Dialer{}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, address)
+		},
+		TLSClientConfig: &tls.Config{ServerName: host, RootCAs: p.verifyRootCAs},
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(verify.RequestTimeout()) * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	// 非默认端口时 Host 带上端口
+	authority := host
+	if defaultPort := (config.VerifyConfig{Scheme: verify.Scheme}).URLPort(); verify.URLPort() != defaultPort {
+		authority = net.JoinHostPort(host, strconv.Itoa(verify.URLPort()))
+	}
+	req, err := http.NewRequest(http.MethodGet, verify.URLScheme()+"://"+authority+verify.URLPath(), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "AutoCDN-Verify")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	return resp.StatusCode, nil
+}
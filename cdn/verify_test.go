@@ -0,0 +1,94 @@
+package cdn
+
+import (
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"AutoCDN/config"
+)
+
+// newVerifyServer 启动 HTTPS 校验目标，只有 Host 为 example.com 且路径为 /health 时返回 200
+// httptest 的证书对 example.com 有效，测试记录使用 Zone 顶点
+func newVerifyServer(t *testing.T) (*x509.CertPool, int) {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); host != "example.com" || r.TLS.ServerName != "example.com" || r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	u, _ := url.Parse(server.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return pool, port
+}
+
+func TestVerifyPlan(t *testing.T) {
+	pool, port := newVerifyServer(t)
+	publisher, server := newTestPublisher(t, config.CloudflareConfig{
+		Domains:  []config.DomainConfig{{Name: "example.com"}},
+		Metadata: config.MetadataConfig{Enabled: true},
+		Verify:   config.VerifyConfig{Enabled: true, Path: "/health", Port: port, Revert: true},
+	})
+	publisher.verifyRootCAs = pool
+
+	plan, err := publisher.PlanDNSRecords(speedSet("127.0.0.1"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	if err := publisher.ApplyPlan(plan); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if plan.Verification == nil || plan.Verification.Failed() != 0 || len(plan.Verification.Domains) != 1 {
+		t.Fatalf("期望校验通过，实际 %+v", plan.Verification)
+	}
+	if line := plan.Verification.Lines()[0]; !strings.Contains(line, "校验通过 example.com (A): 127.0.0.1 HTTP 200") {
+		t.Fatalf("校验结果为 %s", line)
+	}
+
+	// 新 IP 上没有服务：校验失败，恢复为原 IP，且不写入元数据记录
+	plan, err = publisher.PlanDNSRecords(speedSet("127.0.0.2"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	err = publisher.ApplyPlan(plan)
+	if err == nil || !strings.Contains(err.Error(), "已恢复快照") {
+		t.Fatalf("期望校验失败并恢复，实际 %v", err)
+	}
+	if plan.Verification.Failed() != 1 || !strings.Contains(plan.Verification.Lines()[0], "127.0.0.2 请求失败") {
+		t.Fatalf("校验结果为 %v", plan.Verification.Lines())
+	}
+	if got := server.Contents("zone1", "example.com", "A"); !reflect.DeepEqual(got, []string{"127.0.0.1"}) {
+		t.Fatalf("恢复后的记录为 %v", got)
+	}
+	meta := metadataRecords(server, "_autocdn.example.com")["A"]
+	if meta["ips"] != "127.0.0.1" {
+		t.Fatalf("元数据记录为 %v", meta)
+	}
+
+	// 状态码不符合期望且不恢复
+	publisher.cf.Verify.Path = "/missing"
+	publisher.cf.Verify.Revert = false
+	plan, err = publisher.PlanDNSRecords(speedSet("127.0.0.1"))
+	if err != nil {
+		t.Fatalf("生成计划失败: %v", err)
+	}
+	plan.Actions[0].Action = ActionUpdate // 内容不变，强制执行一次更新以触发校验
+	if err := publisher.ApplyPlan(plan); err == nil || !strings.Contains(err.Error(), "记录未恢复") {
+		t.Fatalf("期望校验失败，实际 %v", err)
+	}
+	if line := plan.Verification.Lines()[0]; !strings.Contains(line, "HTTP 404 (期望 200)") {
+		t.Fatalf("校验结果为 %s", line)
+	}
+}
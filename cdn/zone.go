@@ -0,0 +1,124 @@
+package cdn
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"AutoCDN/config"
+)
+
+// 每页获取的 Zone 数量（Cloudflare 允许的最大值为 50）
+const zonesPerPage = 50
+
+// Zone Cloudflare Zone
+type Zone struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ListZones 获取当前凭据可访问的全部Zone（自动翻页）
+func (c *Client) ListZones() ([]Zone, error) {
+	var zones []Zone
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(zonesPerPage))
+
+		resp, err := c.doRequest("GET", "/zones?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("获取Zone列表失败: %w", err)
+		}
+
+		var result []Zone
+		if err := json.Unmarshal(resp.Result, &result); err != nil {
+			return nil, err
+		}
+		zones = append(zones, result...)
+
+		if len(result) == 0 || page >= resp.ResultInfo.TotalPages {
+			break
+		}
+	}
+	return zones, nil
+}
+
+// zoneResolver 将域名解析为所属Zone，Zone列表在一次运行中只获取一次
+type zoneResolver struct {
+	provider Provider
+	cf       config.CloudflareConfig
+	zones    []Zone
+	loaded   bool
+	loadErr  error
+	cache    map[string]string
+}
+
+// newZoneResolver 创建Zone解析器
+// 配置中的 ZoneID / ZoneName 只对 Cloudflare 生效
+func newZoneResolver(provider Provider, cf config.CloudflareConfig) *zoneResolver {
+	if provider.Name() != ProviderCloudflare {
+		cf.ZoneID, cf.ZoneName = "", ""
+	}
+	return &zoneResolver{provider: provider, cf: cf, cache: make(map[string]string)}
+}
+
+// matchZone 按最长后缀匹配域名所属Zone
+func matchZone(host string, zones []Zone) (Zone, bool) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	var best Zone
+	for _, zone := range zones {
+		name := strings.ToLower(zone.Name)
+		if host != name && !strings.HasSuffix(host, "."+name) {
+			continue
+		}
+		if len(name) > len(best.Name) {
+			best = zone
+		}
+	}
+	return best, best.ID != ""
+}
+
+// resolve 返回域名所属Zone的ID
+func (r *zoneResolver) resolve(host string) (string, error) {
+	if zoneID, ok := r.cache[host]; ok {
+		return zoneID, nil
+	}
+
+	if !r.loaded {
+		r.loaded = true
+		r.zones, r.loadErr = r.provider.ListZones()
+		if r.loadErr != nil {
+			log.Printf("获取Zone列表失败，仅使用配置中的 Zone: %v", r.loadErr)
+		}
+		// 只填写了 ZoneID 时从Zone列表中查找其域名，之后同样按后缀匹配
+		if r.cf.ZoneID != "" && r.cf.ZoneName == "" {
+			for _, zone := range r.zones {
+				if zone.ID == r.cf.ZoneID {
+					r.cf.ZoneName = zone.Name
+					break
+				}
+			}
+		}
+	}
+
+	candidates := r.zones
+	if r.cf.ZoneID != "" && r.cf.ZoneName != "" {
+		candidates = append(candidates, Zone{ID: r.cf.ZoneID, Name: r.cf.ZoneName})
+	}
+
+	zone, ok := matchZone(host, candidates)
+	if !ok {
+		if r.cf.ZoneID != "" && r.cf.ZoneName == "" {
+			return "", fmt.Errorf("无法确定 %s 所属的 Zone: 未找到配置的 Zone %s 的域名，请填写 zone_name", host, r.cf.ZoneID)
+		}
+		if r.loadErr != nil {
+			return "", fmt.Errorf("无法确定 %s 所属的 Zone: %v", host, r.loadErr)
+		}
+		return "", fmt.Errorf("%s 不属于当前凭据可访问的任何 Zone", host)
+	}
+	r.cache[host] = zone.ID
+	return zone.ID, nil
+}
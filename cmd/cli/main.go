@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -49,10 +50,18 @@ func detectIPTypeFromFile(filename string) (bool, error) {
 func main() {
 	var configPath string
 	var printVersion bool
+	var dryRun bool
+	var listSnapshots bool
+	var rollbackID string
+	var adoptNames string
 
 	// 定义命令行参数
 	flag.StringVar(&configPath, "c", "config.yaml", "配置文件路径")
 	flag.BoolVar(&printVersion, "v", false, "打印程序版本")
+	flag.BoolVar(&dryRun, "dry-run", false, "只生成 DNS 变更计划，不修改记录")
+	flag.BoolVar(&listSnapshots, "snapshots", false, "列出 DNS 变更前快照")
+	flag.StringVar(&rollbackID, "rollback", "", "将 DNS 记录恢复为指定快照")
+	flag.StringVar(&adoptNames, "adopt", "", "接管配置中域名的现有记录 (all 或逗号分隔的域名)")
 
 	// 其他参数 (使用零值作为默认值，稍后应用配置)
 	flag.IntVar(&task.Routines, "n", 0, "延迟测速线程 (默认使用配置文件)")
@@ -96,15 +105,52 @@ func main() {
 	// 更新全局配置单例，确保其他包(如 cdn)调用 config.GetConfig() 时能获取到正确的配置
 	config.SetConfig(cfg)
 
-	fmt.Printf("DEBUG: Loaded Config - ZoneID: %s, Domains: %v\n", cfg.Cloudflare.ZoneID, cfg.Cloudflare.Domains)
+	log.Printf("已加载配置 - ZoneID: %s, 域名: %v", cfg.Cloudflare.ZoneID, config.DomainNames(cfg.Cloudflare.Domains))
+	log.Printf("DNS 服务商: %s", cfg.ProviderName())
+	if cfg.ProviderName() == cdn.ProviderCloudflare {
+		log.Printf("Cloudflare 认证方式: %s", cfg.Cloudflare.AuthMode())
+	}
+
+	// 快照管理命令，执行后直接退出
+	if listSnapshots {
+		printSnapshots()
+		return
+	}
+	if rollbackID != "" {
+		if err := cdn.RestoreSnapshot(rollbackID); err != nil {
+			log.Fatalf("恢复快照失败: %v", err)
+		}
+		fmt.Printf("已恢复快照 %s\n", rollbackID)
+		return
+	}
+	if adoptNames != "" {
+		adoptRecords(adoptNames)
+		return
+	}
 
 	// 提前验证 API 连通性，避免跑完测速才发现 API 不通
 	// 这里通过尝试获取记录列表来验证，如果是 404/401 等错误直接打印并退出（或者警告）
-	if _, err := cdn.GetRecordList(cfg.Cloudflare.ZoneID); err != nil {
-		log.Printf("警告: API 连接测试失败 (ZoneID: %s): %v", cfg.Cloudflare.ZoneID, err)
-		log.Printf("请检查配置文件中的 ZoneID 和 API Key 是否正确，或是否有权限访问该 Zone。")
+	if cfg.ProviderName() == cdn.ProviderCloudflare && cfg.Cloudflare.ZoneID != "" {
+		if _, err := cdn.GetRecordList(cfg.Cloudflare.ZoneID); err != nil {
+			log.Printf("警告: API 连接测试失败 (ZoneID: %s): %v", cfg.Cloudflare.ZoneID, err)
+			if errors.Is(err, cdn.ErrAuth) {
+				log.Printf("认证失败：请检查 API Token / API Key 是否正确，以及是否具有该 Zone 的 DNS 编辑权限。")
+			} else {
+				log.Printf("请检查配置文件中的 ZoneID 和 API Key 是否正确，或是否有权限访问该 Zone。")
+			}
+		} else {
+			log.Printf("API 连接测试成功 (ZoneID 有效)")
+		}
 	} else {
-		fmt.Println("DEBUG: API 连接测试成功 (ZoneID 有效)")
+		// 未配置 ZoneID 时按域名自动匹配 Zone，需要能够列出 Zone
+		if zones, err := cdn.ListZones(); err != nil {
+			log.Printf("警告: API 连接测试失败 (获取 Zone 列表): %v", err)
+			if cfg.ProviderName() == cdn.ProviderCloudflare {
+				log.Printf("未配置 ZoneID 时需要 API 凭据具有 Zone:Read 权限。")
+			}
+		} else {
+			log.Printf("API 连接测试成功 (可访问 %d 个 Zone)", len(zones))
+		}
 	}
 
 	// 将 CLI 参数覆盖到配置中 (如果 CLI 参数不为零值)
@@ -199,7 +245,7 @@ func main() {
 
 	if isIPv6 {
 		// 处理IPv6
-		if len(cfg.Cloudflare.DomainIPv6s) == 0 {
+		if len(cfg.Cloudflare.DomainIPv6s) == 0 && !cfg.Cloudflare.LoadBalancer.Enabled() {
 			log.Fatal("检测到IPv6文件，但未配置IPv6域名，请在配置文件中设置 domainipv6s")
 		}
 
@@ -209,27 +255,33 @@ func main() {
 		utils.ExportCsvToFile(speedData, cfg.SpeedTest.Output)
 		speedData.Print() // 打印结果
 
-		// 提取IPv6 IP列表
-		var ips []string
-		for _, data := range speedData {
-			ips = append(ips, data.PingData.IP.String())
-		}
-
-		// 获取与IPv6 domains数组长度相同的ipList
-		ipList, err := cdn.GetIPListForIPv6Domains(ips, cfg.Cloudflare.DomainIPv6s)
-		if err != nil {
-			log.Printf("获取IPv6 IP列表失败: %v", err)
-		} else {
-			// 处理IPv6 DNS记录
-			if err := cdn.HandleDNSRecordsIPv6(ipList); err != nil {
-				log.Printf("处理IPv6 DNS记录失败: %v", err)
+		if len(cfg.Cloudflare.DomainIPv6s) > 0 {
+			// 获取与IPv6 domains数组长度相同的ipList
+			ipList, err := cdn.GetIPListForIPv6Domains(speedData, cfg.Cloudflare.DomainIPv6s)
+			if err != nil {
+				log.Printf("获取IPv6 IP列表失败: %v", err)
 			} else {
-				fmt.Println("IPv6 DNS记录处理完成")
+				if dryRun {
+					// 仅预览变更计划
+					plan, err := cdn.PlanDNSRecordsIPv6(ipList)
+					if err != nil {
+						logPublishError("IPv6", "生成IPv6 DNS变更计划失败", err)
+					} else {
+						plan.Print()
+						fmt.Println("预览模式 (-dry-run)，未修改任何 DNS 记录")
+					}
+				} else if err := cdn.HandleDNSRecordsIPv6(ipList); err != nil {
+					// 处理IPv6 DNS记录
+					logPublishError("IPv6", "处理IPv6 DNS记录失败", err)
+				} else {
+					fmt.Println("IPv6 DNS记录处理完成")
+				}
 			}
 		}
+		publishPool(cfg, speedData, dryRun)
 	} else {
 		// 处理IPv4
-		if len(cfg.Cloudflare.Domains) == 0 {
+		if len(cfg.Cloudflare.Domains) == 0 && !cfg.Cloudflare.LoadBalancer.Enabled() {
 			log.Fatal("检测到IPv4文件，但未配置IPv4域名，请在配置文件中设置 domains")
 		}
 
@@ -239,29 +291,105 @@ func main() {
 		utils.ExportCsvToFile(speedData, cfg.SpeedTest.Output)
 		speedData.Print() // 打印结果
 
-		// 提取IP列表
-		var ips []string
-		for _, data := range speedData {
-			ips = append(ips, data.PingData.IP.String())
-		}
-
-		// 获取与domains数组长度相同的ipList
-		ipList, err := cdn.GetIPListForDomains(ips, cfg.Cloudflare.Domains)
-		if err != nil {
-			log.Printf("获取IPv4 IP列表失败: %v", err)
-		} else {
-			// 处理IPv4 DNS记录
-			if err := cdn.HandleDNSRecords(ipList); err != nil {
-				log.Printf("处理IPv4 DNS记录失败: %v", err)
+		if len(cfg.Cloudflare.Domains) > 0 {
+			// 获取与domains数组长度相同的ipList
+			ipList, err := cdn.GetIPListForDomains(speedData, cfg.Cloudflare.Domains)
+			if err != nil {
+				log.Printf("获取IPv4 IP列表失败: %v", err)
 			} else {
-				fmt.Println("IPv4 DNS记录处理完成")
+				if dryRun {
+					// 仅预览变更计划
+					plan, err := cdn.PlanDNSRecords(ipList)
+					if err != nil {
+						logPublishError("IPv4", "生成IPv4 DNS变更计划失败", err)
+					} else {
+						plan.Print()
+						fmt.Println("预览模式 (-dry-run)，未修改任何 DNS 记录")
+					}
+				} else if err := cdn.HandleDNSRecords(ipList); err != nil {
+					// 处理IPv4 DNS记录
+					logPublishError("IPv4", "处理IPv4 DNS记录失败", err)
+				} else {
+					fmt.Println("IPv4 DNS记录处理完成")
+				}
 			}
 		}
+		publishPool(cfg, speedData, dryRun)
 	}
 
 	endPrint()
 }
 
+// publishPool 配置了负载均衡地址池时，将测速结果发布为地址池源站
+func publishPool(cfg *config.Config, speedData utils.DownloadSpeedSet, dryRun bool) {
+	if !cfg.Cloudflare.LoadBalancer.Enabled() {
+		return
+	}
+	plan, err := cdn.PlanPool(speedData)
+	if err != nil {
+		logPublishError("负载均衡地址池", "生成负载均衡地址池变更计划失败", err)
+		return
+	}
+	plan.Print()
+	if dryRun {
+		fmt.Println("预览模式 (-dry-run)，未修改负载均衡地址池")
+		return
+	}
+	if err := cdn.ApplyPoolPlan(plan); err != nil {
+		log.Printf("更新负载均衡地址池失败: %v", err)
+		return
+	}
+	fmt.Println("负载均衡地址池处理完成")
+}
+
+// logPublishError 打印发布失败的原因，质量保护跳过发布时单独提示原因
+func logPublishError(target, failure string, err error) {
+	if errors.Is(err, cdn.ErrPublishSkipped) {
+		fmt.Printf("[%s] %v，未修改任何记录\n", target, err)
+		return
+	}
+	log.Printf("%s: %v", failure, err)
+}
+
+// adoptRecords 接管配置中域名的现有记录并打印结果，names 为 all 时接管全部配置的域名
+func adoptRecords(names string) {
+	var list []string
+	if names != "all" {
+		for _, name := range strings.Split(names, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				list = append(list, name)
+			}
+		}
+	}
+	adopted, err := cdn.AdoptRecords(list)
+	for _, line := range adopted {
+		fmt.Println(line)
+	}
+	if err != nil {
+		log.Fatalf("接管记录失败: %v", err)
+	}
+	if len(adopted) == 0 {
+		fmt.Println("没有需要接管的记录")
+	}
+}
+
+// printSnapshots 打印所有快照
+func printSnapshots() {
+	snapshots, err := cdn.ListSnapshots()
+	if err != nil {
+		log.Fatalf("读取快照失败: %v", err)
+	}
+	if len(snapshots) == 0 {
+		fmt.Println("暂无快照")
+		return
+	}
+	fmt.Printf("%-26s%-22s%-6s%s\n", "快照 ID", "时间", "类型", "记录")
+	for _, snapshot := range snapshots {
+		fmt.Printf("%-28s%-24s%-8s%s\n", snapshot.ID, snapshot.CreatedAt.Format("2006-01-02 15:04:05"), snapshot.Type, strings.Join(snapshot.Names, ", "))
+	}
+	fmt.Println("\n使用 -rollback <快照 ID> 恢复指定快照")
+}
+
 func endPrint() {
 	if utils.NoPrintResult() {
 		return
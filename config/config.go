@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	"gopkg.in/yaml.v3"
@@ -15,18 +17,381 @@ var (
 
 // Config 总配置结构
 type Config struct {
+	// Name 配置名称，即加载时的配置文件名（不含扩展名），不写入配置文件
+	Name string `yaml:"-" json:"-"`
+
+	// Provider 发布记录的 DNS 服务商，默认为 cloudflare
+	Provider   string           `yaml:"provider,omitempty" json:"Provider"`
 	Cloudflare CloudflareConfig `yaml:"cloudflare" json:"Cloudflare"`
+	DNSPod     DNSPodConfig     `yaml:"dnspod,omitempty" json:"DNSPod"`
+	AliDNS     AliDNSConfig     `yaml:"alidns,omitempty" json:"AliDNS"`
+	Route53    Route53Config    `yaml:"route53,omitempty" json:"Route53"`
+	RFC2136    RFC2136Config    `yaml:"rfc2136,omitempty" json:"RFC2136"`
+	PowerDNS   PowerDNSConfig   `yaml:"powerdns,omitempty" json:"PowerDNS"`
 	SpeedTest  SpeedTestConfig  `yaml:"speed_test" json:"SpeedTest"`
 }
 
+// 默认 DNS 服务商
+const DefaultProvider = "cloudflare"
+
+// ProviderName 返回 DNS 服务商名称（小写），未填写时返回默认值
+func (c *Config) ProviderName() string {
+	if c.Provider == "" {
+		return DefaultProvider
+	}
+	return strings.ToLower(strings.TrimSpace(c.Provider))
+}
+
 // CloudflareConfig Cloudflare相关配置
+// 域名、记录集、快照和防抖等发布设置对所有 DNS 服务商生效
 type CloudflareConfig struct {
-	APIKey      string   `yaml:"api_key" json:"APIKey"`
-	ZoneID      string   `yaml:"zone_id" json:"ZoneID"`
-	ZoneName    string   `yaml:"zone_name" json:"ZoneName"`
-	Email       string   `yaml:"email" json:"Email"`
-	Domains     []string `yaml:"domains" json:"Domains"`
-	DomainIPv6s []string `yaml:"domainipv6s" json:"DomainIPv6s"`
+	APIKey      string         `yaml:"api_key" json:"APIKey"`
+	APIToken    string         `yaml:"api_token,omitempty" json:"APIToken"`
+	ZoneID      string         `yaml:"zone_id" json:"ZoneID"`
+	ZoneName    string         `yaml:"zone_name" json:"ZoneName"`
+	Email       string         `yaml:"email" json:"Email"`
+	Domains     []DomainConfig `yaml:"domains" json:"Domains"`
+	DomainIPv6s []DomainConfig `yaml:"domainipv6s" json:"DomainIPv6s"`
+
+	// RecordSetSize 每个域名发布的 IP 数量，大于 1 时为每个域名维护多值记录集（DNS 轮询）
+	RecordSetSize int `yaml:"record_set_size,omitempty" json:"RecordSetSize"`
+
+	// SnapshotDir 变更前快照的保存目录，默认为 snapshots
+	SnapshotDir string `yaml:"snapshot_dir,omitempty" json:"SnapshotDir"`
+
+	// Hysteresis 防抖设置，避免当前 IP 仍然可用时频繁改写记录
+	Hysteresis HysteresisConfig `yaml:"hysteresis,omitempty" json:"Hysteresis"`
+
+	// RateLimit 限制每个域名的变更频率，避免网络波动时频繁改写记录
+	RateLimit RateLimitConfig `yaml:"rate_limit,omitempty" json:"RateLimit"`
+
+	// LoadBalancer 将测速结果发布到 Cloudflare 负载均衡地址池的源站
+	LoadBalancer LoadBalancerConfig `yaml:"load_balancer,omitempty" json:"LoadBalancer"`
+
+	// HTTPSRecord 为每个域名维护 HTTPS 记录，以 ipv4hint / ipv6hint 携带本次发布的 IP
+	HTTPSRecord HTTPSRecordConfig `yaml:"https_record,omitempty" json:"HTTPSRecord"`
+
+	// Ownership 记录管理权设置，默认只修改带有 AutoCDN 标记的记录
+	Ownership OwnershipConfig `yaml:"ownership,omitempty" json:"Ownership"`
+
+	// Metadata 发布成功后为有变更的域名写入元数据 TXT 记录
+	Metadata MetadataConfig `yaml:"metadata,omitempty" json:"Metadata"`
+
+	// Verify 发布后校验记录和站点是否可用
+	Verify VerifyConfig `yaml:"verify,omitempty" json:"Verify"`
+
+	// Guard 发布前的质量保护，条件不满足时跳过本次发布
+	Guard GuardConfig `yaml:"guard,omitempty" json:"Guard"`
+
+	// Canary 金丝雀发布：先只更新金丝雀域名并观察一段时间，健康时再更新其余域名
+	Canary CanaryConfig `yaml:"canary,omitempty" json:"Canary"`
+}
+
+// GuardConfig 发布前的质量保护，在修改任何记录之前检查，条件不满足时跳过本次发布
+// 未达到延迟和速度限制的 IP 不参与发布；禁用下载测速时速度为 0，不应设置速度下限
+type GuardConfig struct {
+	MinIPs         int      `yaml:"min_ips,omitempty" json:"MinIPs"`                 // 合格 IP 的最少数量，默认 1
+	MaxDelay       int      `yaml:"max_delay,omitempty" json:"MaxDelay"`             // 平均延迟上限 (ms)，0 表示不限制
+	MinSpeed       float64  `yaml:"min_speed,omitempty" json:"MinSpeed"`             // 下载速度下限 (MB/s)，0 表示不限制
+	MaxDegradation float64  `yaml:"max_degradation,omitempty" json:"MaxDegradation"` // 新 IP 的平均延迟或速度比当前发布的 IP 变差的最大幅度 (%)，0 表示不检查
+	CheckRanges    bool     `yaml:"check_ranges,omitempty" json:"CheckRanges"`       // 检查发布的 IP 是否都属于 CDN 官方地址段
+	Ranges         []string `yaml:"ranges,omitempty" json:"Ranges"`                  // 官方地址段 (CIDR)，为空时使用内置的 Cloudflare 地址段
+}
+
+// MinIPCount 返回合格 IP 的最少数量，未设置时为 1
+func (g GuardConfig) MinIPCount() int {
+	if g.MinIPs <= 0 {
+		return 1
+	}
+	return g.MinIPs
+}
+
+// CanaryConfig 金丝雀发布设置
+// 观察期间以金丝雀域名作为 SNI / Host 探测本次发布的全部新 IP，协议、端口、路径和期望状态码使用 verify 中的设置
+type CanaryConfig struct {
+	Domain      string `yaml:"domain" json:"Domain"`                      // 金丝雀域名，需在 domains 或 domainipv6s 中，为空时不启用
+	Soak        int    `yaml:"soak,omitempty" json:"Soak"`                // 观察时长（秒），默认 300
+	Interval    int    `yaml:"interval,omitempty" json:"Interval"`        // 探测间隔（秒），默认 30
+	MaxLatency  int    `yaml:"max_latency,omitempty" json:"MaxLatency"`   // 单次请求耗时上限 (ms)，超过视为失败，0 表示不限制
+	SuccessRate int    `yaml:"success_rate,omitempty" json:"SuccessRate"` // 探测成功率下限 (%)，默认 90
+	Revert      bool   `yaml:"revert,omitempty" json:"Revert"`            // 观察未通过时恢复金丝雀域名
+}
+
+// Enabled 是否启用金丝雀发布
+func (c CanaryConfig) Enabled() bool {
+	return c.Domain != ""
+}
+
+// SoakSeconds 返回观察时长
+func (c CanaryConfig) SoakSeconds() int {
+	if c.Soak <= 0 {
+		return 300
+	}
+	return c.Soak
+}
+
+// IntervalSeconds 返回探测间隔
+func (c CanaryConfig) IntervalSeconds() int {
+	if c.Interval <= 0 {
+		return 30
+	}
+	return c.Interval
+}
+
+// MinSuccessRate 返回探测成功率下限 (%)
+func (c CanaryConfig) MinSuccessRate() int {
+	if c.SuccessRate <= 0 || c.SuccessRate > 100 {
+		return 90
+	}
+	return c.SuccessRate
+}
+
+// VerifyConfig 发布后校验设置
+// 从服务商读回有变更的记录，再以域名作为 SNI / Host 直接连接每个新 IP 请求校验路径
+type VerifyConfig struct {
+	Enabled bool   `yaml:"enabled" json:"Enabled"`
+	Scheme  string `yaml:"scheme,omitempty" json:"Scheme"`   // https 或 http，默认 https
+	Port    int    `yaml:"port,omitempty" json:"Port"`       // 默认 https 为 443，http 为 80
+	Path    string `yaml:"path,omitempty" json:"Path"`       // 默认 /
+	Status  int    `yaml:"status,omitempty" json:"Status"`   // 期望的状态码，默认 200
+	Timeout int    `yaml:"timeout,omitempty" json:"Timeout"` // 单次请求超时秒数，默认 10
+	Revert  bool   `yaml:"revert,omitempty" json:"Revert"`   // 校验失败时恢复变更前的快照
+}
+
+// URLScheme 返回校验请求的协议
+func (v VerifyConfig) URLScheme() string {
+	if strings.EqualFold(v.Scheme, "http") {
+		return "http"
+	}
+	return "https"
+}
+
+// URLPort 返回校验请求连接的端口
+func (v VerifyConfig) URLPort() int {
+	if v.Port > 0 {
+		return v.Port
+	}
+	if v.URLScheme() == "http" {
+		return 80
+	}
+	return 443
+}
+
+// URLPath 返回校验请求的路径
+func (v VerifyConfig) URLPath() string {
+	if v.Path == "" {
+		return "/"
+	}
+	if !strings.HasPrefix(v.Path, "/") {
+		return "/" + v.Path
+	}
+	return v.Path
+}
+
+// ExpectedStatus 返回期望的状态码
+func (v VerifyConfig) ExpectedStatus() int {
+	if v.Status <= 0 {
+		return 200
+	}
+	return v.Status
+}
+
+// RequestTimeout 返回单次请求的超时秒数
+func (v VerifyConfig) RequestTimeout() int {
+	if v.Timeout <= 0 {
+		return 10
+	}
+	return v.Timeout
+}
+
+// 元数据记录名的默认前缀
+const DefaultMetadataPrefix = "_autocdn"
+
+// OwnershipConfig 记录管理权设置
+// 支持记录备注的服务商（Cloudflare）在备注中写入标记，其他服务商在元数据记录名下写入管理声明 TXT 记录
+// 没有标记的同名记录默认不修改，需通过 -adopt 命令接管
+type OwnershipConfig struct {
+	AllowUnmarked bool `yaml:"allow_unmarked,omitempty" json:"AllowUnmarked"` // 允许修改没有标记的记录（旧版本行为）
+}
+
+// MetadataConfig 元数据 TXT 记录设置
+// 记录名为 <prefix>.<域名>，内容包括运行时间、发布的 IP、延迟、速度、数据中心和配置名称
+type MetadataConfig struct {
+	Enabled    bool   `yaml:"enabled" json:"Enabled"`
+	Prefix     string `yaml:"prefix,omitempty" json:"Prefix"`          // 记录名前缀，默认 _autocdn
+	ConfigName string `yaml:"config_name,omitempty" json:"ConfigName"` // 写入记录的配置名称，默认为配置文件名
+}
+
+// RecordName 返回域名对应的元数据记录名
+func (m MetadataConfig) RecordName(host string) string {
+	prefix := m.Prefix
+	if prefix == "" {
+		prefix = DefaultMetadataPrefix
+	}
+	return prefix + "." + host
+}
+
+// HTTPS 记录的默认优先级和目标（"." 表示记录名本身）
+const (
+	DefaultHTTPSPriority = 1
+	DefaultHTTPSTarget   = "."
+)
+
+// HTTPSRecordConfig HTTPS (SVCB) 记录设置
+// 只改写与本次发布地址族对应的 hint，记录中的其他参数（如 ech、port）保持不变
+type HTTPSRecordConfig struct {
+	Enabled  bool     `yaml:"enabled" json:"Enabled"`
+	Priority int      `yaml:"priority,omitempty" json:"Priority"` // 新建记录的优先级，默认 1
+	Target   string   `yaml:"target,omitempty" json:"Target"`     // 新建记录的目标，默认 "."
+	ALPN     []string `yaml:"alpn,omitempty" json:"ALPN"`         // 如 [h3, h2]，为空时不修改记录中的 alpn
+}
+
+// RecordPriority 返回新建记录的优先级
+func (h HTTPSRecordConfig) RecordPriority() int {
+	if h.Priority <= 0 {
+		return DefaultHTTPSPriority
+	}
+	return h.Priority
+}
+
+// RecordTarget 返回新建记录的目标
+func (h HTTPSRecordConfig) RecordTarget() string {
+	if h.Target == "" {
+		return DefaultHTTPSTarget
+	}
+	return h.Target
+}
+
+// 负载均衡地址池默认发布的源站数量
+const DefaultPoolOrigins = 3
+
+// LoadBalancerConfig Cloudflare 负载均衡地址池设置
+// AutoCDN 只管理名称以 autocdn- 开头的源站，地址池中的其他源站保持不变
+type LoadBalancerConfig struct {
+	AccountID string `yaml:"account_id" json:"AccountID"`
+	PoolID    string `yaml:"pool_id" json:"PoolID"`
+	Origins   int    `yaml:"origins,omitempty" json:"Origins"` // 发布测速结果前 N 个 IP，默认 3
+}
+
+// Enabled 是否配置了负载均衡地址池
+func (l LoadBalancerConfig) Enabled() bool {
+	return l.AccountID != "" && l.PoolID != ""
+}
+
+// OriginCount 返回发布的源站数量
+func (l LoadBalancerConfig) OriginCount() int {
+	if l.Origins <= 0 {
+		return DefaultPoolOrigins
+	}
+	return l.Origins
+}
+
+// HysteresisConfig 防抖设置
+// 当前 IP 仍在本次测速结果中时保持不变，除非新 IP 在所有已设置的指标上都领先至少对应的幅度
+type HysteresisConfig struct {
+	Enabled     bool    `yaml:"enabled" json:"Enabled"`
+	DelayMargin int     `yaml:"delay_margin,omitempty" json:"DelayMargin"` // 新 IP 平均延迟需至少低多少毫秒，0 表示不比较延迟
+	SpeedMargin float64 `yaml:"speed_margin,omitempty" json:"SpeedMargin"` // 新 IP 下载速度需至少高多少 MB/s，0 表示不比较速度
+}
+
+// RateLimitConfig 变更频率限制，依据持久化的变更记录判断，与测速的运行频率无关
+type RateLimitConfig struct {
+	MaxChanges int    `yaml:"max_changes,omitempty" json:"MaxChanges"` // 每个域名在统计窗口内的最大变更次数，0 表示不限制
+	Window     int    `yaml:"window,omitempty" json:"Window"`          // 统计窗口（分钟），默认 60
+	MinDwell   int    `yaml:"min_dwell,omitempty" json:"MinDwell"`     // 新发布的 IP 至少保留多久（分钟）才能被替换，0 表示不限制
+	LedgerFile string `yaml:"ledger_file,omitempty" json:"LedgerFile"` // 变更记录文件，默认为 change_ledger.json
+}
+
+// Enabled 是否启用变更频率限制
+func (r RateLimitConfig) Enabled() bool {
+	return r.MaxChanges > 0 || r.MinDwell > 0
+}
+
+// WindowMinutes 返回统计窗口（分钟），未设置时为 60
+func (r RateLimitConfig) WindowMinutes() int {
+	if r.Window <= 0 {
+		return 60
+	}
+	return r.Window
+}
+
+// LedgerPath 返回变更记录文件路径
+func (r RateLimitConfig) LedgerPath() string {
+	if r.LedgerFile == "" {
+		return "change_ledger.json"
+	}
+	return r.LedgerFile
+}
+
+// Cloudflare 认证方式
+const (
+	AuthModeKey   = "key"   // 全局 API 密钥 (X-Auth-Key + X-Auth-Email)
+	AuthModeToken = "token" // API 令牌 (Authorization: Bearer)
+)
+
+// AuthMode 自动判断认证方式
+// 填写了 APIToken 时使用令牌方式；未填写 Email 时将 APIKey 视为令牌（与 cfst_ddns.sh 行为一致）
+func (c CloudflareConfig) AuthMode() string {
+	if c.APIToken != "" || c.Email == "" {
+		return AuthModeToken
+	}
+	return AuthModeKey
+}
+
+// Token 返回令牌方式下使用的令牌
+func (c CloudflareConfig) Token() string {
+	if c.APIToken != "" {
+		return c.APIToken
+	}
+	return c.APIKey
+}
+
+// DNSPodConfig DNSPod 相关配置
+type DNSPodConfig struct {
+	ID    string `yaml:"id" json:"ID"`       // API Token 的 ID
+	Token string `yaml:"token" json:"Token"` // API Token，也可以直接填写 "ID,Token"
+}
+
+// LoginToken 返回 DNSPod API 使用的 login_token
+func (c DNSPodConfig) LoginToken() string {
+	if c.ID == "" || strings.Contains(c.Token, ",") {
+		return c.Token
+	}
+	return c.ID + "," + c.Token
+}
+
+// AliDNSConfig 阿里云云解析 DNS 相关配置
+type AliDNSConfig struct {
+	AccessKeyID     string `yaml:"access_key_id" json:"AccessKeyID"`
+	AccessKeySecret string `yaml:"access_key_secret" json:"AccessKeySecret"`
+}
+
+// Route53Config AWS Route 53 相关配置
+type Route53Config struct {
+	AccessKeyID     string `yaml:"access_key_id" json:"AccessKeyID"`
+	SecretAccessKey string `yaml:"secret_access_key" json:"SecretAccessKey"`
+	SessionToken    string `yaml:"session_token,omitempty" json:"SessionToken"` // 临时凭据的会话令牌
+	Region          string `yaml:"region,omitempty" json:"Region"`              // 签名使用的区域，默认 us-east-1
+	Endpoint        string `yaml:"endpoint,omitempty" json:"Endpoint"`          // API 地址，默认 https://route53.amazonaws.com
+	WaitTimeout     int    `yaml:"wait_timeout,omitempty" json:"WaitTimeout"`   // 等待变更生效 (INSYNC) 的秒数，默认 120
+}
+
+// RFC2136Config RFC 2136 动态更新相关配置，用于 BIND、Knot 等自建权威 DNS 服务器
+type RFC2136Config struct {
+	Server  string   `yaml:"server" json:"Server"`             // 主服务器地址，如 192.0.2.1:53，未写端口时使用 53
+	Zones   []string `yaml:"zones" json:"Zones"`               // 允许更新的 Zone，如 example.com
+	KeyName string   `yaml:"key_name" json:"KeyName"`          // TSIG 密钥名
+	Secret  string   `yaml:"secret" json:"Secret"`             // TSIG 密钥（base64），算法为 HMAC-SHA256
+	Timeout int      `yaml:"timeout,omitempty" json:"Timeout"` // 单次请求的超时秒数，默认 10
+}
+
+// PowerDNSConfig PowerDNS 权威服务器 HTTP API 相关配置
+type PowerDNSConfig struct {
+	APIURL   string `yaml:"api_url" json:"APIURL"`               // API 地址，如 http://127.0.0.1:8081
+	APIKey   string `yaml:"api_key" json:"APIKey"`               // X-API-Key
+	ServerID string `yaml:"server_id,omitempty" json:"ServerID"` // 服务器 ID，默认 localhost
+	Rectify  bool   `yaml:"rectify,omitempty" json:"Rectify"`    // 更新后执行 rectify（DNSSEC 签名的 Zone 需要）
+	Notify   bool   `yaml:"notify,omitempty" json:"Notify"`      // 更新后通知从服务器 (NOTIFY)
 }
 
 // SpeedTestConfig 速度测试相关配置
@@ -101,6 +466,7 @@ func LoadConfig(path string) (*Config, error) {
 	if err := yaml.Unmarshal(file, cfg); err != nil {
 		return nil, err
 	}
+	cfg.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
 
 	return cfg, nil
 }
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultTTL 未指定 TTL 时使用的默认值（秒）
+	DefaultTTL = 3600
+	// TTLAuto Cloudflare 中 TTL 为 1 表示"自动"
+	TTLAuto TTL = 1
+)
+
+// TTL 记录 TTL，配置中可写为秒数或 "auto"
+type TTL int
+
+// parseTTL 解析 TTL 字符串
+func parseTTL(s string) (TTL, error) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "auto") {
+		return TTLAuto, nil
+	}
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("无效的 TTL: %q", s)
+	}
+	return TTL(v), nil
+}
+
+// UnmarshalYAML 支持数字和 "auto"，null 表示使用默认值
+func (t *TTL) UnmarshalYAML(node *yaml.Node) error {
+	if node.Tag == "!!null" {
+		*t = 0
+		return nil
+	}
+	v, err := parseTTL(node.Value)
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// MarshalYAML 自动 TTL 输出为 "auto"
+func (t TTL) MarshalYAML() (interface{}, error) {
+	if t == TTLAuto {
+		return "auto", nil
+	}
+	return int(t), nil
+}
+
+// UnmarshalJSON 支持数字和字符串（秒数或 "auto"），null 表示使用默认值
+func (t *TTL) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*t = 0
+		return nil
+	}
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		if n < 0 {
+			return fmt.Errorf("无效的 TTL: %d", n)
+		}
+		*t = TTL(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("无效的 TTL: %s", data)
+	}
+	v, err := parseTTL(s)
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// DomainConfig 单个域名的记录配置
+// 配置文件中既可以写为字符串（仅域名），也可以写为包含记录选项的对象
+type DomainConfig struct {
+	Name    string   `yaml:"name" json:"Name"`
+	TTL     TTL      `yaml:"ttl,omitempty" json:"TTL"`         // 秒数，1 或 "auto" 表示自动，0 使用默认值
+	Proxied *bool    `yaml:"proxied,omitempty" json:"Proxied"` // 是否开启 Cloudflare 代理，默认关闭
+	Comment string   `yaml:"comment,omitempty" json:"Comment"` // 记录备注
+	Tags    []string `yaml:"tags,omitempty" json:"Tags"`       // 记录标签，如 team:cdn
+	Line    string   `yaml:"line,omitempty" json:"Line"`       // 解析线路（DNSPod / 阿里云），如 电信、联通、移动，留空为默认线路
+}
+
+// domainConfigFields 用于避免自定义解析时的递归
+type domainConfigFields DomainConfig
+
+// simple 是否只配置了域名
+func (d DomainConfig) simple() bool {
+	return d.TTL == 0 && d.Proxied == nil && d.Comment == "" && len(d.Tags) == 0 && d.Line == ""
+}
+
+// RecordTTL 返回实际提交的 TTL，开启代理且未指定 TTL 时为自动
+func (d DomainConfig) RecordTTL() int {
+	if d.TTL == 0 {
+		if d.IsProxied() {
+			return int(TTLAuto)
+		}
+		return DefaultTTL
+	}
+	return int(d.TTL)
+}
+
+// IsProxied 返回是否开启代理
+func (d DomainConfig) IsProxied() bool {
+	return d.Proxied != nil && *d.Proxied
+}
+
+// 解析线路的英文别名，统一为 DNSPod 使用的中文线路名
+var lineAliases = map[string]string{
+	"default": "",
+	"默认":      "",
+	"telecom": "电信",
+	"unicom":  "联通",
+	"mobile":  "移动",
+}
+
+// RecordLine 返回统一后的解析线路，默认线路返回空字符串
+func (d DomainConfig) RecordLine() string {
+	line := strings.TrimSpace(d.Line)
+	if alias, ok := lineAliases[strings.ToLower(line)]; ok {
+		return alias
+	}
+	return line
+}
+
+// UnmarshalYAML 支持字符串和对象两种写法
+func (d *DomainConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*d = DomainConfig{Name: strings.TrimSpace(node.Value)}
+		return nil
+	}
+	var fields domainConfigFields
+	if err := node.Decode(&fields); err != nil {
+		return err
+	}
+	*d = DomainConfig(fields)
+	return nil
+}
+
+// MarshalYAML 只配置了域名时输出为字符串，保持配置文件简洁
+func (d DomainConfig) MarshalYAML() (interface{}, error) {
+	if d.simple() {
+		return d.Name, nil
+	}
+	return domainConfigFields(d), nil
+}
+
+// UnmarshalJSON 支持字符串和对象两种写法
+func (d *DomainConfig) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*d = DomainConfig{Name: strings.TrimSpace(name)}
+		return nil
+	}
+	var fields domainConfigFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*d = DomainConfig(fields)
+	return nil
+}
+
+// DomainNames 返回域名列表中的域名
+func DomainNames(domains []DomainConfig) []string {
+	names := make([]string, 0, len(domains))
+	for _, d := range domains {
+		names = append(names, d.Name)
+	}
+	return names
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestTTLUnmarshal(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  TTL
+		fail  bool
+	}{
+		{"数字", `300`, 300, false},
+		{"auto", `"auto"`, TTLAuto, false},
+		{"字符串数字", `"600"`, 600, false},
+		{"null 使用默认值", `null`, 0, false},
+		{"负数", `-1`, 0, true},
+		{"小数", `1.5`, 0, true},
+		{"无效字符串", `"fast"`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run("JSON "+tt.name, func(t *testing.T) {
+			var d DomainConfig
+			err := json.Unmarshal([]byte(`{"Name":"cdn.example.com","TTL":`+tt.input+`}`), &d)
+			if tt.fail {
+				if err == nil {
+					t.Fatalf("期望解析失败，实际 TTL 为 %d", d.TTL)
+				}
+				return
+			}
+			if err != nil || d.TTL != tt.want {
+				t.Fatalf("期望 %d，实际 %d (%v)", tt.want, d.TTL, err)
+			}
+		})
+	}
+
+	yamlTests := []struct {
+		name  string
+		input string
+		want  TTL
+		fail  bool
+	}{
+		{"数字", `300`, 300, false},
+		{"auto", `auto`, TTLAuto, false},
+		{"null 使用默认值", `null`, 0, false},
+		{"~ 使用默认值", `~`, 0, false},
+		{"空值使用默认值", ``, 0, false},
+		{"无效字符串", `fast`, 0, true},
+	}
+	for _, tt := range yamlTests {
+		t.Run("YAML "+tt.name, func(t *testing.T) {
+			var d DomainConfig
+			err := yaml.Unmarshal([]byte("name: cdn.example.com\nttl: "+tt.input+"\n"), &d)
+			if tt.fail {
+				if err == nil {
+					t.Fatalf("期望解析失败，实际 TTL 为 %d", d.TTL)
+				}
+				return
+			}
+			if err != nil || d.TTL != tt.want {
+				t.Fatalf("期望 %d，实际 %d (%v)", tt.want, d.TTL, err)
+			}
+			if d.RecordTTL() != DefaultTTL && tt.want == 0 {
+				t.Fatalf("未指定 TTL 时应使用默认值，实际 %d", d.RecordTTL())
+			}
+		})
+	}
+}
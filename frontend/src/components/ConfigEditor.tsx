@@ -62,7 +62,7 @@ export default function ConfigEditor({ activeConfig }: ConfigEditorProps) {
   };
 
   const handleChange = (
-    section: "Cloudflare" | "SpeedTest",
+    section: "Cloudflare" | "DNSPod" | "AliDNS" | "Route53" | "RFC2136" | "PowerDNS" | "SpeedTest",
     field: string,
     value: any,
   ) => {
@@ -72,17 +72,54 @@ export default function ConfigEditor({ activeConfig }: ConfigEditorProps) {
     setCfg(newCfg);
   };
 
-  const handleArrayChange = (
-    section: "Cloudflare",
-    field: string,
+  const handleProviderChange = (value: string) => {
+    const newCfg = new ConfigModels.Config(cfg);
+    // @ts-ignore
+    newCfg.Provider = value;
+    setCfg(newCfg);
+  };
+
+  // 域名列表只编辑域名，保留已有条目的 TTL / 代理 / 备注等记录选项
+  const handleDomainsChange = (
+    field: "Domains" | "DomainIPv6s",
     value: string,
+  ) => {
+    const newCfg = new ConfigModels.Config(cfg);
+    const existing: any[] = cfg.Cloudflare?.[field] || [];
+    // @ts-ignore
+    newCfg.Cloudflare[field] = value.split("\n").map(
+      (name) => existing.find((d) => d.Name === name) || { Name: name },
+    );
+    setCfg(newCfg);
+  };
+
+  // Cloudflare 下的嵌套设置（负载均衡地址池 / HTTPS 记录 / 元数据记录 / 发布后校验）
+  const handleNestedChange = (
+    group:
+      | "LoadBalancer"
+      | "HTTPSRecord"
+      | "Metadata"
+      | "Verify"
+      | "Canary"
+      | "Guard"
+      | "RateLimit"
+      | "Ownership",
+    field: string,
+    value: any,
   ) => {
     const newCfg = new ConfigModels.Config(cfg);
     // @ts-ignore
-    newCfg[section][field] = value.split("\n");
+    newCfg.Cloudflare[group] = {
+      // @ts-ignore
+      ...(cfg.Cloudflare?.[group] || {}),
+      [field]: value,
+    };
     setCfg(newCfg);
   };
 
+  const domainNames = (domains?: any[]) =>
+    (domains || []).map((d) => d.Name).join("\n");
+
   if (!activeConfig) {
     return (
       <div className="flex h-full items-center justify-center text-slate-500 gap-2">
@@ -124,6 +161,229 @@ export default function ConfigEditor({ activeConfig }: ConfigEditorProps) {
       </header>
 
       <div className="flex-1 overflow-y-auto pr-4 space-y-8 pb-10">
+        {/* DNS 服务商 */}
+        <section>
+          <h3 className="text-lg font-medium text-blue-400 mb-4 border-b border-blue-500/20 pb-2">
+            DNS 服务商
+          </h3>
+          <div className="grid grid-cols-1 md:grid-cols-2 gap-6">
+            <div className="space-y-2">
+              <label className="text-sm text-slate-400">
+                发布测速结果的权威 DNS
+              </label>
+              <select
+                // @ts-ignore
+                value={cfg.Provider || "cloudflare"}
+                onChange={(e) => handleProviderChange(e.target.value)}
+                className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition appearance-none cursor-pointer"
+              >
+                <option value="cloudflare">Cloudflare</option>
+                <option value="dnspod">DNSPod</option>
+                <option value="alidns">阿里云 DNS</option>
+                <option value="route53">AWS Route 53</option>
+                <option value="rfc2136">RFC 2136 (自建 DNS)</option>
+                <option value="powerdns">PowerDNS</option>
+              </select>
+            </div>
+          </div>
+          {/* @ts-ignore */}
+          {cfg.Provider === "dnspod" && (
+            <div className="grid grid-cols-1 md:grid-cols-2 gap-6 mt-6">
+              <div className="space-y-2">
+                <label className="text-sm text-slate-400">DNSPod Token ID</label>
+                <input
+                  type="text"
+                  // @ts-ignore
+                  value={cfg.DNSPod?.ID || ""}
+                  onChange={(e) => handleChange("DNSPod", "ID", e.target.value)}
+          {/* @ts-ignore */}
+          {cfg.Provider === "alidns" && (
+            <div className="grid grid-cols-1 md:grid-cols-2 gap-6 mt-6">
+              <div className="space-y-2">
+                <label className="text-sm text-slate-400">AccessKey ID</label>
+                <input
+                  type="text"
+                  // @ts-ignore
+                  value={cfg.AliDNS?.AccessKeyID || ""}
+                  onChange={(e) =>
+                    handleChange("AliDNS", "AccessKeyID", e.target.value)
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+              <div className="space-y-2">
+                <label className="text-sm text-slate-400">AccessKey Secret</label>
+                <input
+                  type="password"
+                  // @ts-ignore
+                  value={cfg.AliDNS?.AccessKeySecret || ""}
+                  onChange={(e) =>
+                    handleChange("AliDNS", "AccessKeySecret", e.target.value)
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+            </div>
+          )}
+          {/* @ts-ignore */}
+          {cfg.Provider === "route53" && (
+            <div className="grid grid-cols-1 md:grid-cols-2 gap-6 mt-6">
+              <div className="space-y-2">
+                <label className="text-sm text-slate-400">Access Key ID</label>
+                <input
+                  type="text"
+                  // @ts-ignore
+                  value={cfg.Route53?.AccessKeyID || ""}
+                  onChange={(e) =>
+                    handleChange("Route53", "AccessKeyID", e.target.value)
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+              <div className="space-y-2">
+                <label className="text-sm text-slate-400">Secret Access Key</label>
+                <input
+                  type="password"
+                  // @ts-ignore
+                  value={cfg.Route53?.SecretAccessKey || ""}
+                  onChange={(e) =>
+                    handleChange("Route53", "SecretAccessKey", e.target.value)
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+            </div>
+          )}
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+              <div className="space-y-2">
+                <label className="text-sm text-slate-400">DNSPod Token</label>
+                <input
+                  type="password"
+                  // @ts-ignore
+                  value={cfg.DNSPod?.Token || ""}
+                  onChange={(e) =>
+                    handleChange("DNSPod", "Token", e.target.value)
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+            </div>
+          )}
+          {/* @ts-ignore */}
+          {cfg.Provider === "rfc2136" && (
+            <div className="grid grid-cols-1 md:grid-cols-2 gap-6 mt-6">
+              <div className="space-y-2">
+                <label className="text-sm text-slate-400">主服务器地址</label>
+                <input
+                  type="text"
+                  // @ts-ignore
+                  value={cfg.RFC2136?.Server || ""}
+                  onChange={(e) =>
+                    handleChange("RFC2136", "Server", e.target.value)
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+              <div className="space-y-2">
+                <label className="text-sm text-slate-400">Zone (逗号分隔)</label>
+                <input
+                  type="text"
+                  // @ts-ignore
+                  value={(cfg.RFC2136?.Zones || []).join(",")}
+                  onChange={(e) =>
+                    handleChange(
+                      "RFC2136",
+                      "Zones",
+                      e.target.value
+                        .split(",")
+                        .map((zone) => zone.trim())
+                        .filter((zone) => zone !== ""),
+                    )
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+              <div className="space-y-2">
+                <label className="text-sm text-slate-400">TSIG 密钥名</label>
+                <input
+                  type="text"
+                  // @ts-ignore
+                  value={cfg.RFC2136?.KeyName || ""}
+                  onChange={(e) =>
+                    handleChange("RFC2136", "KeyName", e.target.value)
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+              <div className="space-y-2">
+                <label className="text-sm text-slate-400">TSIG 密钥 (HMAC-SHA256, base64)</label>
+                <input
+                  type="password"
+                  // @ts-ignore
+                  value={cfg.RFC2136?.Secret || ""}
+                  onChange={(e) =>
+                    handleChange("RFC2136", "Secret", e.target.value)
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+            </div>
+          )}
+          {/* @ts-ignore */}
+          {cfg.Provider === "powerdns" && (
+            <div className="grid grid-cols-1 md:grid-cols-2 gap-6 mt-6">
+              <div className="space-y-2">
+                <label className="text-sm text-slate-400">API 地址</label>
+                <input
+                  type="text"
+                  // @ts-ignore
+                  value={cfg.PowerDNS?.APIURL || ""}
+                  onChange={(e) =>
+                    handleChange("PowerDNS", "APIURL", e.target.value)
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+              <div className="space-y-2">
+                <label className="text-sm text-slate-400">API Key</label>
+                <input
+                  type="password"
+                  // @ts-ignore
+                  value={cfg.PowerDNS?.APIKey || ""}
+                  onChange={(e) =>
+                    handleChange("PowerDNS", "APIKey", e.target.value)
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+              <label className="flex items-center gap-2 text-sm text-slate-400">
+                <input
+                  type="checkbox"
+                  // @ts-ignore
+                  checked={cfg.PowerDNS?.Rectify || false}
+                  onChange={(e) =>
+                    handleChange("PowerDNS", "Rectify", e.target.checked)
+                  }
+                />
+                更新后执行 rectify
+              </label>
+              <label className="flex items-center gap-2 text-sm text-slate-400">
+                <input
+                  type="checkbox"
+                  // @ts-ignore
+                  checked={cfg.PowerDNS?.Notify || false}
+                  onChange={(e) =>
+                    handleChange("PowerDNS", "Notify", e.target.checked)
+                  }
+                />
+                更新后通知从服务器 (NOTIFY)
+              </label>
+            </div>
+          )}
+        </section>
+
         {/* Cloudflare Section */}
         <section>
           <h3 className="text-lg font-medium text-blue-400 mb-4 border-b border-blue-500/20 pb-2">
@@ -141,6 +401,19 @@ export default function ConfigEditor({ activeConfig }: ConfigEditorProps) {
                 className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
               />
             </div>
+            <div className="space-y-2">
+              <label className="text-sm text-slate-400">
+                API Token (推荐，填写后忽略 API Key)
+              </label>
+              <input
+                type="password"
+                value={cfg.Cloudflare?.APIToken || ""}
+                onChange={(e) =>
+                  handleChange("Cloudflare", "APIToken", e.target.value)
+                }
+                className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+              />
+            </div>
             <div className="space-y-2">
               <label className="text-sm text-slate-400">注册邮箱 (Email)</label>
               <input
@@ -178,14 +451,31 @@ export default function ConfigEditor({ activeConfig }: ConfigEditorProps) {
                 className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition"
               />
             </div>
+            <div className="space-y-2">
+              <label className="text-sm text-slate-400">
+                每域名 IP 数量 (大于 1 启用轮询记录集)
+              </label>
+              <input
+                type="number"
+                value={cfg.Cloudflare?.RecordSetSize || 0}
+                onChange={(e) =>
+                  handleChange(
+                    "Cloudflare",
+                    "RecordSetSize",
+                    parseInt(e.target.value),
+                  )
+                }
+                className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+              />
+            </div>
             <div className="space-y-2 row-span-2">
               <label className="text-sm text-slate-400">
                 IPv4 域名列表 (每行一个)
               </label>
               <textarea
-                value={cfg.Cloudflare?.Domains?.join("\n") || ""}
+                value={domainNames(cfg.Cloudflare?.Domains)}
                 onChange={(e) =>
-                  handleArrayChange("Cloudflare", "Domains", e.target.value)
+                  handleDomainsChange("Domains", e.target.value)
                 }
                 className="w-full h-32 bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono text-sm leading-relaxed"
               />
@@ -195,13 +485,310 @@ export default function ConfigEditor({ activeConfig }: ConfigEditorProps) {
                 IPv6 域名列表 (每行一个)
               </label>
               <textarea
-                value={cfg.Cloudflare?.DomainIPv6s?.join("\n") || ""}
+                value={domainNames(cfg.Cloudflare?.DomainIPv6s)}
                 onChange={(e) =>
-                  handleArrayChange("Cloudflare", "DomainIPv6s", e.target.value)
+                  handleDomainsChange("DomainIPv6s", e.target.value)
                 }
                 className="w-full h-32 bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono text-sm leading-relaxed"
               />
             </div>
+            <div className="space-y-2">
+              <label className="text-sm text-slate-400">
+                负载均衡账号 ID (Account ID)
+              </label>
+              <input
+                type="text"
+                value={cfg.Cloudflare?.LoadBalancer?.AccountID || ""}
+                onChange={(e) =>
+                  handleNestedChange("LoadBalancer", "AccountID", e.target.value)
+                }
+                className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+              />
+            </div>
+            <div className="space-y-2">
+              <label className="text-sm text-slate-400">
+                负载均衡地址池 ID (Pool ID)
+              </label>
+              <input
+                type="text"
+                value={cfg.Cloudflare?.LoadBalancer?.PoolID || ""}
+                onChange={(e) =>
+                  handleNestedChange("LoadBalancer", "PoolID", e.target.value)
+                }
+                className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+              />
+            </div>
+            <div className="space-y-2">
+              <label className="text-sm text-slate-400">
+                地址池源站数量
+              </label>
+              <input
+                type="number"
+                value={cfg.Cloudflare?.LoadBalancer?.Origins || 0}
+                onChange={(e) =>
+                  handleNestedChange("LoadBalancer", "Origins", parseInt(e.target.value))
+                }
+                className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+              />
+            </div>
+            <div className="space-y-2">
+              <label className="text-sm text-slate-400">
+                HTTPS 记录 ALPN (逗号分隔，如 h3,h2)
+              </label>
+              <input
+                type="text"
+                // @ts-ignore
+                value={(cfg.Cloudflare?.HTTPSRecord?.ALPN || []).join(",")}
+                onChange={(e) =>
+                  handleNestedChange(
+                    "HTTPSRecord",
+                    "ALPN",
+                    e.target.value
+                      .split(",")
+                      .map((v) => v.trim())
+                      .filter((v) => v),
+                  )
+                }
+                className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+              />
+              <label className="flex items-center gap-2 text-sm text-slate-400">
+                <input
+                  type="checkbox"
+                  // @ts-ignore
+                  checked={cfg.Cloudflare?.HTTPSRecord?.Enabled || false}
+                  onChange={(e) =>
+                    handleNestedChange("HTTPSRecord", "Enabled", e.target.checked)
+                  }
+                />
+                维护 HTTPS 记录 (ipv4hint / ipv6hint)
+              </label>
+              <label className="flex items-center gap-2 text-sm text-slate-400">
+                <input
+                  type="checkbox"
+                  // @ts-ignore
+                  checked={cfg.Cloudflare?.Metadata?.Enabled || false}
+                  onChange={(e) =>
+                    handleNestedChange("Metadata", "Enabled", e.target.checked)
+                  }
+                />
+                写入元数据 TXT 记录 (_autocdn.域名)
+              </label>
+            </div>
+            <div className="space-y-2">
+              <label className="text-sm text-slate-400">
+                发布后校验路径 / 期望状态码
+              </label>
+              <div className="flex gap-2">
+                <input
+                  type="text"
+                  // @ts-ignore
+                  value={cfg.Cloudflare?.Verify?.Path || ""}
+                  placeholder="/"
+                  onChange={(e) =>
+                    handleNestedChange("Verify", "Path", e.target.value)
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+                <input
+                  type="number"
+                  // @ts-ignore
+                  value={cfg.Cloudflare?.Verify?.Status || 200}
+                  onChange={(e) =>
+                    handleNestedChange("Verify", "Status", parseInt(e.target.value))
+                  }
+                  className="w-28 bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+              <label className="flex items-center gap-2 text-sm text-slate-400">
+                <input
+                  type="checkbox"
+                  // @ts-ignore
+                  checked={cfg.Cloudflare?.Verify?.Enabled || false}
+                  onChange={(e) =>
+                    handleNestedChange("Verify", "Enabled", e.target.checked)
+                  }
+                />
+                发布后校验记录和站点
+              </label>
+              <label className="flex items-center gap-2 text-sm text-slate-400">
+                <input
+                  type="checkbox"
+                  // @ts-ignore
+                  checked={cfg.Cloudflare?.Verify?.Revert || false}
+                  onChange={(e) =>
+                    handleNestedChange("Verify", "Revert", e.target.checked)
+                  }
+                />
+                校验失败时自动恢复
+              </label>
+            </div>
+            <div className="space-y-2">
+              <label className="text-sm text-slate-400">
+                金丝雀域名 / 观察时长 (秒)
+              </label>
+              <div className="flex gap-2">
+                <input
+                  type="text"
+                  // @ts-ignore
+                  value={cfg.Cloudflare?.Canary?.Domain || ""}
+                  placeholder="留空则同时发布全部域名"
+                  onChange={(e) =>
+                    handleNestedChange("Canary", "Domain", e.target.value)
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+                <input
+                  type="number"
+                  // @ts-ignore
+                  value={cfg.Cloudflare?.Canary?.Soak || 300}
+                  onChange={(e) =>
+                    handleNestedChange("Canary", "Soak", parseInt(e.target.value))
+                  }
+                  className="w-28 bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+              <label className="flex items-center gap-2 text-sm text-slate-400">
+                <input
+                  type="checkbox"
+                  // @ts-ignore
+                  checked={cfg.Cloudflare?.Canary?.Revert || false}
+                  onChange={(e) =>
+                    handleNestedChange("Canary", "Revert", e.target.checked)
+                  }
+                />
+                观察未通过时恢复金丝雀域名
+              </label>
+            </div>
+            <div className="space-y-2">
+              <label className="text-sm text-slate-400">
+                发布保护: 最少合格 IP / 最大劣化 (%)
+              </label>
+              <div className="flex gap-2">
+                <input
+                  type="number"
+                  // @ts-ignore
+                  value={cfg.Cloudflare?.Guard?.MinIPs || ""}
+                  placeholder="1"
+                  onChange={(e) =>
+                    handleNestedChange("Guard", "MinIPs", parseInt(e.target.value) || 0)
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+                <input
+                  type="number"
+                  // @ts-ignore
+                  value={cfg.Cloudflare?.Guard?.MaxDegradation || ""}
+                  placeholder="0 不检查"
+                  onChange={(e) =>
+                    handleNestedChange("Guard", "MaxDegradation", parseFloat(e.target.value) || 0)
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+              <label className="text-sm text-slate-400">
+                发布保护: 延迟上限 (ms) / 速度下限 (MB/s)
+              </label>
+              <div className="flex gap-2">
+                <input
+                  type="number"
+                  // @ts-ignore
+                  value={cfg.Cloudflare?.Guard?.MaxDelay || ""}
+                  placeholder="0 不限制"
+                  onChange={(e) =>
+                    handleNestedChange("Guard", "MaxDelay", parseInt(e.target.value) || 0)
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+                <input
+                  type="number"
+                  // @ts-ignore
+                  value={cfg.Cloudflare?.Guard?.MinSpeed || ""}
+                  placeholder="0 不限制"
+                  onChange={(e) =>
+                    handleNestedChange("Guard", "MinSpeed", parseFloat(e.target.value) || 0)
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+              <label className="flex items-center gap-2 text-sm text-slate-400">
+                <input
+                  type="checkbox"
+                  // @ts-ignore
+                  checked={cfg.Cloudflare?.Guard?.CheckRanges || false}
+                  onChange={(e) =>
+                    handleNestedChange("Guard", "CheckRanges", e.target.checked)
+                  }
+                />
+                只发布 CDN 官方地址段内的 IP
+              </label>
+            </div>
+            <div className="space-y-2">
+              <label className="text-sm text-slate-400">
+                变更限流: 窗口内最大变更次数 / 窗口 (分钟) / 最短停留 (分钟)
+              </label>
+              <div className="flex gap-2">
+                <input
+                  type="number"
+                  // @ts-ignore
+                  value={cfg.Cloudflare?.RateLimit?.MaxChanges || ""}
+                  placeholder="0 不限制"
+                  onChange={(e) =>
+                    handleNestedChange(
+                      "RateLimit",
+                      "MaxChanges",
+                      parseInt(e.target.value) || 0,
+                    )
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+                <input
+                  type="number"
+                  // @ts-ignore
+                  value={cfg.Cloudflare?.RateLimit?.Window || ""}
+                  placeholder="60"
+                  onChange={(e) =>
+                    handleNestedChange(
+                      "RateLimit",
+                      "Window",
+                      parseInt(e.target.value) || 0,
+                    )
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+                <input
+                  type="number"
+                  // @ts-ignore
+                  value={cfg.Cloudflare?.RateLimit?.MinDwell || ""}
+                  placeholder="0 不限制"
+                  onChange={(e) =>
+                    handleNestedChange(
+                      "RateLimit",
+                      "MinDwell",
+                      parseInt(e.target.value) || 0,
+                    )
+                  }
+                  className="w-full bg-slate-950 border border-white/10 rounded-lg px-4 py-2 focus:outline-none focus:border-blue-500 transition font-mono"
+                />
+              </div>
+            </div>
+            <div className="space-y-2">
+              <label className="text-sm text-slate-400">记录管理权</label>
+              <label className="flex items-center gap-2 text-sm text-slate-400">
+                <input
+                  type="checkbox"
+                  // @ts-ignore
+                  checked={cfg.Cloudflare?.Ownership?.AllowUnmarked || false}
+                  onChange={(e) =>
+                    handleNestedChange(
+                      "Ownership",
+                      "AllowUnmarked",
+                      e.target.checked,
+                    )
+                  }
+                />
+                允许修改没有 AutoCDN 标记的记录
+              </label>
+            </div>
           </div>
         </section>
 
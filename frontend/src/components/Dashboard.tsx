@@ -1,5 +1,5 @@
 import { useState, useEffect, useRef } from "react";
-import { Play, Terminal, Pause, CircleStop } from "lucide-react";
+import { Play, Terminal, Pause, CircleStop, Eye } from "lucide-react";
 import { StartSpeedTest, StopSpeedTest } from "../../wailsjs/go/main/App";
 import * as runtime from "../../wailsjs/runtime/runtime";
 import clsx from "clsx";
@@ -14,11 +14,52 @@ interface DashboardProps {
   activeConfig: string;
 }
 
+// 与 cdn.Plan 的 JSON 字段对应
+interface PlanAction {
+  action: "create" | "update" | "delete" | "noop";
+  name: string;
+  type: string;
+  oldContent: string;
+  newContent: string;
+  line?: string;
+}
+
+interface Plan {
+  type: string;
+  actions: PlanAction[] | null;
+  https?: Plan;
+  held?: string[];
+  skipped?: string[];
+}
+
+const actionStyles: Record<PlanAction["action"], { mark: string; color: string }> = {
+  create: { mark: "+", color: "text-emerald-400" },
+  update: { mark: "~", color: "text-amber-400" },
+  delete: { mark: "-", color: "text-red-400" },
+  noop: { mark: "=", color: "text-slate-500" },
+};
+
+// planActions 展开地址记录和 HTTPS 记录的变更
+function planActions(plan: Plan): PlanAction[] {
+  const actions = plan.actions || [];
+  return plan.https ? [...actions, ...planActions(plan.https)] : actions;
+}
+
+// planNotes 展开跳过和暂缓的说明
+function planNotes(plan: Plan): string[] {
+  const notes = [
+    ...(plan.skipped || []).map((s) => `跳过 ${s}`),
+    ...(plan.held || []).map((s) => `暂缓 ${s}`),
+  ];
+  return plan.https ? [...notes, ...planNotes(plan.https)] : notes;
+}
+
 export default function Dashboard({ activeConfig }: DashboardProps) {
   const [running, setRunning] = useState(false);
   const [progress, setProgress] = useState(0);
   const [statusAction, setStatusAction] = useState("就绪");
   const [logs, setLogs] = useState<string[]>([]);
+  const [plan, setPlan] = useState<Plan | null>(null);
   const logsEndRef = useRef<HTMLDivElement>(null);
 
   useEffect(() => {
@@ -38,12 +79,16 @@ export default function Dashboard({ activeConfig }: DashboardProps) {
     const cleanError = runtime.EventsOn("error", (msg: string) => {
       setLogs((prev) => [...prev, `[错误] ${msg}`].slice(-100));
     });
+    const cleanPlan = runtime.EventsOn("plan", (data: Plan) => {
+      setPlan(data);
+    });
 
     return () => {
       cleanLog();
       cleanStatus();
       cleanProgress();
       cleanError();
+      cleanPlan();
     };
   }, []);
 
@@ -51,10 +96,11 @@ export default function Dashboard({ activeConfig }: DashboardProps) {
     logsEndRef.current?.scrollIntoView({ behavior: "smooth" });
   }, [logs]);
 
-  const handleStart = async (mode: "auto" | "manual") => {
+  const handleStart = async (mode: "auto" | "dryrun" | "manual") => {
     if (!activeConfig) return;
     setRunning(true);
     setLogs([]);
+    setPlan(null);
     setProgress(0);
     setStatusAction("正在初始化...");
     try {
@@ -99,7 +145,7 @@ export default function Dashboard({ activeConfig }: DashboardProps) {
               自动托管模式
             </h3>
             <p className="text-sm text-blue-200/60 mb-6">
-              全自动执行延迟测速、优选 IP 并直接更新 Cloudflare DNS 记录。
+              全自动执行延迟测速、优选 IP 并直接更新 Cloudflare DNS 记录；"预览变更"只生成变更计划，不修改记录。
             </p>
             <div className="flex gap-3">
               {!running ? (
@@ -111,6 +157,16 @@ export default function Dashboard({ activeConfig }: DashboardProps) {
                   <Play className="w-5 h-5 fill-current" />
                   一键优选
                 </button>
+              ) : null}
+              {!running ? (
+                <button
+                  onClick={() => handleStart("dryrun")}
+                  disabled={!activeConfig}
+                  className="bg-blue-500/10 border border-blue-500/40 hover:bg-blue-500/20 disabled:opacity-50 disabled:cursor-not-allowed text-blue-100 px-6 py-3 rounded-xl font-medium flex items-center gap-2 transition-all"
+                >
+                  <Eye className="w-5 h-5" />
+                  预览变更
+                </button>
               ) : (
                 <button
                   onClick={handleStop}
@@ -156,6 +212,41 @@ export default function Dashboard({ activeConfig }: DashboardProps) {
         </div>
       </div>
 
+      {/* Plan Section */}
+      {plan && (
+        <div className="bg-slate-950 rounded-xl border border-white/5 mb-6 max-h-64 flex flex-col">
+          <div className="p-4 border-b border-white/5 flex justify-between items-center bg-white/5">
+            <span className="text-sm font-mono text-slate-400">
+              {plan.type} 变更计划
+            </span>
+            <span className="text-xs text-slate-500">
+              {planActions(plan).filter((a) => a.action !== "noop").length} 项变更
+            </span>
+          </div>
+          <div className="overflow-y-auto p-4 font-mono text-xs space-y-1">
+            {planActions(plan).map((action, i) => {
+              const style = actionStyles[action.action];
+              const label = action.line ? `${action.name} [${action.line}]` : action.name;
+              return (
+                <div key={i} className={clsx("break-all", style.color)}>
+                  {style.mark} {action.type} {label}:{" "}
+                  {action.action === "update"
+                    ? `${action.oldContent} -> ${action.newContent}`
+                    : action.action === "create"
+                      ? action.newContent
+                      : action.oldContent}
+                </div>
+              );
+            })}
+            {planNotes(plan).map((note, i) => (
+              <div key={`note-${i}`} className="break-all text-orange-300">
+                ! {note}
+              </div>
+            ))}
+          </div>
+        </div>
+      )}
+
       {/* Progress Section */}
       <div className="bg-slate-950 rounded-xl border border-white/5 flex-1 flex flex-col min-h-0">
         <div className="p-4 border-b border-white/5 flex justify-between items-center bg-white/5">
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log"
 	"net"
 	"net/http"
 	"sort"
@@ -80,8 +81,9 @@ func TestDownloadSpeed(ipSet utils.PingDelaySet) (speedSet utils.DownloadSpeedSe
 		}
 
 		fmt.Printf("\r[测试进度 %d/%d] 正在测速 IP: %s ... ", i+1, testNum, ipSet[i].IP.String())
-		speed := downloadHandler(ipSet[i].IP)
+		speed, colo := downloadHandler(ipSet[i].IP)
 		ipSet[i].DownloadSpeed = speed
+		ipSet[i].Colo = colo
 
 		speedMB := speed / 1024 / 1024
 
@@ -99,6 +101,7 @@ func TestDownloadSpeed(ipSet utils.PingDelaySet) (speedSet utils.DownloadSpeedSe
 	}
 	bar.Done()
 	if len(speedSet) == 0 { // 没有符合速度限制的数据，返回所有测试数据
+		log.Printf("没有 IP 达到下载速度下限 %.2f MB/s，返回全部测速结果 (可配置 guard.min_speed 阻止发布)", MinSpeed)
 		speedSet = utils.DownloadSpeedSet(ipSet)
 	}
 	// 按速度排序
@@ -118,8 +121,16 @@ func getDialContext(ip *net.IPAddr) func(ctx context.Context, network, address s
 	}
 }
 
-// return download Speed
-func downloadHandler(ip *net.IPAddr) float64 {
+// responseColo 从响应头中取出数据中心三字码，Cloudflare 为 CF-RAY，AWS CloudFront 为 X-Amz-Cf-Pop
+func responseColo(resp *http.Response) string {
+	if resp.Header.Get("Server") == "cloudflare" {
+		return OutRegexp.FindString(resp.Header.Get("CF-RAY")) // 示例 cf-ray: 7bd32409eda7b020-SJC
+	}
+	return OutRegexp.FindString(resp.Header.Get("x-amz-cf-pop")) // 示例 X-Amz-Cf-Pop: SIN52-P1
+}
+
+// return download Speed and colo
+func downloadHandler(ip *net.IPAddr) (float64, string) {
 	client := &http.Client{
 		Transport: &http.Transport{DialContext: getDialContext(ip)},
 		Timeout:   Timeout,
@@ -135,19 +146,20 @@ func downloadHandler(ip *net.IPAddr) float64 {
 	}
 	req, err := http.NewRequest("GET", URL, nil)
 	if err != nil {
-		return 0.0
+		return 0.0, ""
 	}
 
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/98.0.4758.80 Safari/537.36")
 
 	response, err := client.Do(req)
 	if err != nil {
-		return 0.0
+		return 0.0, ""
 	}
 	defer response.Body.Close()
 	if response.StatusCode != 200 {
-		return 0.0
+		return 0.0, ""
 	}
+	colo := responseColo(response)
 	timeStart := time.Now()           // 开始时间（当前）
 	timeEnd := timeStart.Add(Timeout) // 加上下载测速时间得到的结束时间
 
@@ -191,5 +203,5 @@ func downloadHandler(ip *net.IPAddr) float64 {
 		}
 		contentRead += int64(bufferRead)
 	}
-	return e.Value() / (Timeout.Seconds() / 120)
+	return e.Value() / (Timeout.Seconds() / 120), colo
 }
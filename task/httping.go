@@ -63,14 +63,8 @@ func (p *Ping) httping(ip *net.IPAddr) (int, time.Duration) {
 
 		// 只有指定了地区才匹配机场三字码
 		if HttpingCFColo != "" {
-			// 通过头部 Server 值判断是 Cloudflare 还是 AWS CloudFront 并设置 cfRay 为各自的机场三字码完整内容
-			cfRay := func() string {
-				if resp.Header.Get("Server") == "cloudflare" {
-					return resp.Header.Get("CF-RAY") // 示例 cf-ray: 7bd32409eda7b020-SJC
-				}
-				return resp.Header.Get("x-amz-cf-pop") // 示例 X-Amz-Cf-Pop: SIN52-P1
-			}()
-			colo := p.getColo(cfRay)
+			// 通过头部 Server 值判断是 Cloudflare 还是 AWS CloudFront 并取出各自的机场三字码
+			colo := p.getColo(responseColo(resp))
 			if colo == "" { // 没有匹配到三字码或不符合指定地区则直接结束该 IP 测试
 				return 0, 0
 			}
@@ -46,6 +46,7 @@ type CloudflareIPData struct {
 	*PingData
 	lossRate      float32
 	DownloadSpeed float64
+	Colo          string // 下载测速时响应的数据中心三字码，未知时为空
 }
 
 // 计算丢包率